@@ -0,0 +1,85 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"os"
+
+	"github.com/andoniaf/telegram-pr-notify/pkg/diffstat"
+	"github.com/andoniaf/telegram-pr-notify/pkg/events"
+	"github.com/andoniaf/telegram-pr-notify/pkg/github"
+	"github.com/andoniaf/telegram-pr-notify/pkg/notify"
+	"github.com/andoniaf/telegram-pr-notify/pkg/telegram"
+)
+
+const (
+	defaultDiffMaxSize = 100_000
+	diffstatWidth      = 30
+	diffstatMaxFiles   = 100
+)
+
+// diffActions are the pull_request actions whose notification gets the diff.
+var diffActions = map[string]bool{
+	"opened":           true,
+	"reopened":         true,
+	"synchronize":      true,
+	"ready_for_review": true,
+}
+
+// sendDiff follows the notification msg, sent as replyTo, with the PR's
+// diff as a document when it is at most maxSize bytes, or with a diffstat
+// message otherwise, and returns the ID of the message it sent. The diff is
+// read from diffFile when set, or fetched with gh.
+func sendDiff(tg *telegram.Client, gh *github.Client, data *events.TemplateData, msg notify.Message, diffFile string, maxSize int64, replyTo string) (string, error) {
+	var diff []byte
+	small := false
+
+	switch {
+	case diffFile != "":
+		b, err := os.ReadFile(diffFile)
+		if err != nil {
+			return "", fmt.Errorf("reading diff_file: %w", err)
+		}
+		diff, small = b, int64(len(b)) <= maxSize
+	case gh != nil:
+		b, ok, err := gh.PullRequestDiff(data.Repo.FullName, data.PR.Number, maxSize)
+		if err != nil {
+			return "", fmt.Errorf("fetching diff: %w", err)
+		}
+		diff, small = b, ok
+	default:
+		return "", errors.New("attach_diff needs github_token or diff_file")
+	}
+
+	link := fmt.Sprintf(`<a href="%s">#%d</a>`, html.EscapeString(data.PR.HTMLURL), data.PR.Number)
+
+	if small {
+		if len(diff) == 0 {
+			return "", nil
+		}
+		caption := fmt.Sprintf("📄 Diff for %s: %s", link, diffstat.Summary(diffstat.Parse(diff)))
+		return tg.SendDocument(telegram.Document{
+			Filename:  fmt.Sprintf("pr-%d.diff", data.PR.Number),
+			Content:   diff,
+			Caption:   caption,
+			ThreadID:  msg.ThreadID,
+			ReplyTo:   replyTo,
+			Protected: msg.Protected,
+		})
+	}
+
+	// Too large to attach: the whole diff is only at hand for diff_file,
+	// otherwise ask the API for the per-file counts.
+	files := diffstat.Parse(diff)
+	if files == nil && gh != nil {
+		var err error
+		if files, err = gh.PullRequestFiles(data.Repo.FullName, data.PR.Number, diffstatMaxFiles); err != nil {
+			return "", fmt.Errorf("fetching changed files: %w", err)
+		}
+	}
+
+	text := fmt.Sprintf("📊 <b>Diffstat</b> for %s (diff too large to attach)\n<pre>%s</pre>",
+		link, html.EscapeString(diffstat.Format(files, diffstatWidth)))
+	return tg.Send(notify.Message{Blocks: []string{text}, ThreadID: msg.ThreadID, Silent: true, Protected: msg.Protected})
+}
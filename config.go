@@ -0,0 +1,267 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/andoniaf/telegram-pr-notify/pkg/config"
+)
+
+// defaultConfigFile is the repository config file read when the
+// config_file input is empty. It is optional.
+const defaultConfigFile = ".github/telegram-pr-notify.yml"
+
+// configKind is the shape of value a config key accepts.
+type configKind int
+
+const (
+	// configText takes a single value or block scalar.
+	configText configKind = iota
+	// configList also takes a list, joined like the input's separator.
+	configList
+	// configRules also takes a mapping of selector: value, as key=value
+	// lines.
+	configRules
+)
+
+// configKey describes how a config key's value is written as its input
+// and checked.
+type configKey struct {
+	kind configKind
+
+	// sep joins a list's items.
+	sep string
+
+	// valid, when set, checks each value: the whole text, each list item or
+	// each mapping value.
+	valid func(string) error
+}
+
+// configKeys are the inputs the config file may set.
+var configKeys = map[string]configKey{
+	"notifier": {valid: oneOf("telegram", "slack", "discord", "matrix", "teams")},
+	"topic_id": {},
+
+	"custom_template":  {},
+	"ci_notify":        {valid: oneOf("all", "failure", "failure_and_recovery")},
+	"max_commits":      {valid: validPositive},
+	"category_topics":  {kind: configRules},
+	"buttons":          {kind: configList, sep: "\n"},
+	"button_layout":    {valid: validLayout},
+	"ticket_patterns":  {kind: configList, sep: "\n", valid: validTicketPattern},
+	"max_files":        {valid: validPositive},
+	"attach_diff":      {valid: validBool},
+	"diff_max_size":    {valid: validPositive},
+	"silent":           {kind: configRules, valid: validBool},
+	"protect_content":  {kind: configRules, valid: validBool},
+	"link_preview":     {kind: configRules, valid: validPreview},
+	"send_images":      {valid: validBool},
+	"reactions":        {kind: configRules},
+	"reactions_only":   {valid: validBool},
+	"pin_labels":       {kind: configList, sep: ","},
+	"pin_template":     {},
+	"fail_on":          {valid: oneOf(failOnAny, failOnAll, failOnNone)},
+	"queue_workers":    {valid: validPositive},
+	"debug":            {valid: validBool},
+	"log_format":       {valid: oneOf(logText, logJSON)},
+	"auto_topic":       {valid: oneOf(autoTopicRepo, autoTopicPR, "off")},
+	"auto_topic_name":  {},
+	"auto_topic_color": {},
+
+	"auto_topic_min_lines": {valid: validCount},
+}
+
+// inputOnly are inputs the config file may not set, with the reason. A pull
+// request can change the file, so it may not hold secrets, choose where
+// messages are sent, or name paths the action reads or writes.
+var inputOnly = map[string]string{
+	"bot_token":           "is a secret; pass it as an input from secrets",
+	"slack_webhook_url":   "is a secret; pass it as an input from secrets",
+	"slack_token":         "is a secret; pass it as an input from secrets",
+	"discord_webhook_url": "is a secret; pass it as an input from secrets",
+	"matrix_access_token": "is a secret; pass it as an input from secrets",
+	"teams_webhook_url":   "is a secret; pass it as an input from secrets",
+	"github_token":        "is a secret; pass it as an input from secrets",
+	"chat_id":             "chooses where messages are sent; set it as an input",
+	"slack_channel":       "chooses where messages are sent; set it as an input",
+	"matrix_homeserver":   "chooses where messages are sent; set it as an input",
+	"matrix_room_id":      "chooses where messages are sent; set it as an input",
+	"diff_file":           "is a path; set it as an input",
+	"state_file":          "is a path; set it as an input",
+	"queue_file":          "is a path; set it as an input",
+	"metrics_file":        "is a path; set it as an input",
+	"mode":                "can only be set as an input",
+	"config_file":         "can only be set as an input",
+	"event_payload":       "can only be set as an input",
+}
+
+// loadConfig reads the config file named by the config_file input, or the
+// default one if it exists, and sets every input the workflow left empty
+// from it: workflow inputs take precedence over the file, and the file
+// over built-in defaults. It returns the keys it set.
+func loadConfig() ([]string, error) {
+	path := strings.TrimSpace(os.Getenv("INPUT_CONFIG_FILE"))
+	explicit := path != ""
+	if !explicit {
+		path = defaultConfigFile
+	}
+	if ws := os.Getenv("GITHUB_WORKSPACE"); ws != "" && !filepath.IsAbs(path) {
+		path = filepath.Join(ws, path)
+	}
+
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) && !explicit {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading config file: %w", err)
+	}
+	values, err := parseConfig(b)
+	if err != nil {
+		return nil, fmt.Errorf("config file %s: %w", path, err)
+	}
+	return applyConfig(values), nil
+}
+
+// configValue is a config key's value as the input's text.
+type configValue struct {
+	key, value string
+}
+
+// parseConfig parses and validates a config file into input values, in
+// file order.
+func parseConfig(b []byte) ([]configValue, error) {
+	entries, err := config.Parse(b)
+	if err != nil {
+		return nil, err
+	}
+	var values []configValue
+	for _, e := range entries {
+		v, err := configInput(e)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, configValue{e.Key, v})
+	}
+	return values, nil
+}
+
+// configInput checks an entry against its key's schema and returns it as
+// the input's text.
+func configInput(e config.Entry) (string, error) {
+	if reason, ok := inputOnly[e.Key]; ok {
+		return "", config.Errorf(e.Line, "%s %s", e.Key, reason)
+	}
+	k, ok := configKeys[e.Key]
+	if !ok {
+		return "", config.Errorf(e.Line, "unknown key %q", e.Key)
+	}
+
+	check := func(line int, v string) error {
+		if k.valid == nil {
+			return nil
+		}
+		if err := k.valid(strings.TrimSpace(v)); err != nil {
+			return config.Errorf(line, "%s: %v", e.Key, err)
+		}
+		return nil
+	}
+
+	switch {
+	case e.Kind == config.List && k.kind == configList:
+		items := make([]string, len(e.Items))
+		for i, item := range e.Items {
+			if err := check(item.Line, item.Value); err != nil {
+				return "", err
+			}
+			items[i] = item.Value
+		}
+		return strings.Join(items, k.sep), nil
+
+	case e.Kind == config.Map && k.kind == configRules:
+		lines := make([]string, len(e.Pairs))
+		for i, p := range e.Pairs {
+			if err := check(p.Line, p.Value); err != nil {
+				return "", err
+			}
+			lines[i] = p.Key + "=" + p.Value
+		}
+		return strings.Join(lines, "\n"), nil
+
+	case e.Kind != config.Scalar:
+		return "", config.Errorf(e.Line, "%s must be a single value, not %s", e.Key, e.Kind)
+	}
+
+	switch {
+	case e.Value == "":
+	case k.kind == configRules:
+		// A single value, or key=value rules written inline.
+		if _, err := parseEventRules(e.Value, func(v string) error { return check(e.Line, v) }); err != nil {
+			var ce *config.Error
+			if errors.As(err, &ce) {
+				return "", ce
+			}
+			return "", config.Errorf(e.Line, "%s: %v", e.Key, err)
+		}
+	default:
+		if err := check(e.Line, e.Value); err != nil {
+			return "", err
+		}
+	}
+	return e.Value, nil
+}
+
+// applyConfig sets the INPUT_* variable of each value whose input is
+// empty, and returns the keys it set.
+func applyConfig(values []configValue) []string {
+	var set []string
+	for _, v := range values {
+		env := "INPUT_" + strings.ToUpper(v.key)
+		if os.Getenv(env) != "" || v.value == "" {
+			continue
+		}
+		os.Setenv(env, v.value)
+		set = append(set, v.key)
+	}
+	return set
+}
+
+func oneOf(values ...string) func(string) error {
+	return func(v string) error {
+		for _, want := range values {
+			if strings.EqualFold(v, want) {
+				return nil
+			}
+		}
+		return fmt.Errorf("invalid value %q (want %s)", v, strings.Join(values, ", "))
+	}
+}
+
+func validPositive(v string) error {
+	if n, err := strconv.Atoi(v); err != nil || n < 1 {
+		return fmt.Errorf("invalid value %q (want a positive integer)", v)
+	}
+	return nil
+}
+
+func validCount(v string) error {
+	if n, err := strconv.Atoi(v); err != nil || n < 0 {
+		return fmt.Errorf("invalid value %q (want a non-negative integer)", v)
+	}
+	return nil
+}
+
+func validLayout(v string) error {
+	_, err := parseLayout(v)
+	return err
+}
+
+func validTicketPattern(v string) error {
+	_, err := parseTicketPatterns(v)
+	return err
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		want    []configValue
+		wantErr string
+	}{
+		{
+			name: "scalars",
+			src:  "notifier: slack\ntopic_id: 7\nmax_files: 20\nauto_topic: PR",
+			want: []configValue{{"notifier", "slack"}, {"topic_id", "7"}, {"max_files", "20"}, {"auto_topic", "PR"}},
+		},
+		{
+			name: "lists",
+			src:  "buttons:\n  - 'Docs | https://example.com'\n  - Run | {{.Repo.HTMLURL}}/actions\npin_labels:\n  - release\n  - hotfix",
+			want: []configValue{{"buttons", "Docs | https://example.com\nRun | {{.Repo.HTMLURL}}/actions"}, {"pin_labels", "release,hotfix"}},
+		},
+		{
+			name: "rules",
+			src:  "link_preview:\n  push: none\n  '*': small\nreactions: merged=🎉, failure=🔥",
+			want: []configValue{{"link_preview", "push=none\n*=small"}, {"reactions", "merged=🎉, failure=🔥"}},
+		},
+		{
+			name: "empty value",
+			src:  "topic_id:",
+			want: []configValue{{"topic_id", ""}},
+		},
+		{
+			name:    "syntax error",
+			src:     "notifier: telegram\nchat_id: [1]",
+			wantErr: "line 2: flow collections are not supported; use an indented list or mapping",
+		},
+		{
+			name:    "unknown key",
+			src:     "\nquiet_hours: 22-7",
+			wantErr: `line 2: unknown key "quiet_hours"`,
+		},
+		{
+			name:    "secret",
+			src:     "bot_token: 123:abc",
+			wantErr: "line 1: bot_token is a secret; pass it as an input from secrets",
+		},
+		{
+			name:    "input only",
+			src:     "mode: doctor",
+			wantErr: "line 1: mode can only be set as an input",
+		},
+		{
+			name:    "destination",
+			src:     "chat_id:\n  - -100123",
+			wantErr: "line 1: chat_id chooses where messages are sent; set it as an input",
+		},
+		{
+			name:    "homeserver",
+			src:     "matrix_homeserver: https://matrix.example.com",
+			wantErr: "line 1: matrix_homeserver chooses where messages are sent; set it as an input",
+		},
+		{
+			name:    "path",
+			src:     "state_file: /tmp/state.json",
+			wantErr: "line 1: state_file is a path; set it as an input",
+		},
+		{
+			name:    "metrics path",
+			src:     "metrics_file: metrics.prom",
+			wantErr: "line 1: metrics_file is a path; set it as an input",
+		},
+		{
+			name:    "invalid enum",
+			src:     "fail_on: some",
+			wantErr: `line 1: fail_on: invalid value "some" (want any, all, none)`,
+		},
+		{
+			name:    "invalid integer",
+			src:     "max_commits: 0",
+			wantErr: `line 1: max_commits: invalid value "0" (want a positive integer)`,
+		},
+		{
+			name:    "invalid list item",
+			src:     "ticket_patterns:\n  - Jira=[A-Z]+-\\d+ https://x/$0\n  - Jira",
+			wantErr: `line 3: ticket_patterns: invalid entry "Jira" (want [Name=]REGEX URL)`,
+		},
+		{
+			name:    "invalid rule",
+			src:     "silent:\n  push: true\n  bot: maybe",
+			wantErr: `line 3: silent: invalid value "maybe" (want true or false)`,
+		},
+		{
+			name:    "invalid inline rule",
+			src:     "protect_content: push=yes",
+			wantErr: `line 1: protect_content: invalid value "yes" (want true or false)`,
+		},
+		{
+			name:    "list for a single value",
+			src:     "notifier:\n  - telegram",
+			wantErr: "line 1: notifier must be a single value, not a list",
+		},
+		{
+			name:    "mapping for a list",
+			src:     "buttons:\n  docs: https://example.com",
+			wantErr: "line 1: buttons must be a single value, not a mapping",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseConfig([]byte(tt.src))
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("parseConfig() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseConfig() error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseConfig() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	t.Setenv("GITHUB_WORKSPACE", "testdata")
+	t.Setenv("INPUT_CONFIG_FILE", "config/telegram-pr-notify.yml")
+	// An explicit input wins over the file; an empty one does not.
+	t.Setenv("INPUT_MAX_COMMITS", "3")
+	t.Setenv("INPUT_CI_NOTIFY", "")
+	for _, env := range []string{"INPUT_SILENT", "INPUT_BUTTONS", "INPUT_CUSTOM_TEMPLATE", "INPUT_PIN_LABELS"} {
+		t.Setenv(env, "")
+	}
+
+	set, err := loadConfig()
+	if err != nil {
+		t.Fatalf("loadConfig() error: %v", err)
+	}
+	if want := []string{"ci_notify", "silent", "buttons", "custom_template", "pin_labels"}; !reflect.DeepEqual(set, want) {
+		t.Errorf("loadConfig() set %v, want %v", set, want)
+	}
+
+	want := map[string]string{
+		"INPUT_MAX_COMMITS":     "3",
+		"INPUT_CI_NOTIFY":       "failure_and_recovery",
+		"INPUT_SILENT":          "pull_request.synchronize=true\nbot=true",
+		"INPUT_BUTTONS":         "View PR | {{.PR.HTMLURL}}\nActions | {{.Repo.HTMLURL}}/actions",
+		"INPUT_CUSTOM_TEMPLATE": "<b>{{.Repo.FullName}}</b>: {{.PR.Title}}\n",
+		"INPUT_PIN_LABELS":      "release,hotfix",
+	}
+	for env, v := range want {
+		if got := os.Getenv(env); got != v {
+			t.Errorf("%s = %q, want %q", env, got, v)
+		}
+	}
+}
+
+func TestLoadConfigMissing(t *testing.T) {
+	t.Setenv("GITHUB_WORKSPACE", t.TempDir())
+
+	// The default file is optional.
+	t.Setenv("INPUT_CONFIG_FILE", "")
+	if set, err := loadConfig(); err != nil || set != nil {
+		t.Errorf("loadConfig() = %v, %v, want nothing", set, err)
+	}
+
+	// A file named by the input is not.
+	t.Setenv("INPUT_CONFIG_FILE", "notify.yml")
+	if _, err := loadConfig(); err == nil {
+		t.Error("loadConfig() expected error for a missing config_file")
+	}
+}
+
+func TestLoadConfigError(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, defaultConfigFile)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("notifier: telegram\ndebug: yes\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GITHUB_WORKSPACE", dir)
+	t.Setenv("INPUT_CONFIG_FILE", "")
+
+	_, err := loadConfig()
+	want := "config file " + path + `: line 2: debug: invalid value "yes" (want true or false)`
+	if err == nil || err.Error() != want {
+		t.Errorf("loadConfig() error = %v, want %q", err, want)
+	}
+}
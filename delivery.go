@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/andoniaf/telegram-pr-notify/pkg/events"
+	"github.com/andoniaf/telegram-pr-notify/pkg/notify"
+	"github.com/andoniaf/telegram-pr-notify/pkg/queue"
+	"github.com/andoniaf/telegram-pr-notify/pkg/telegram"
+)
+
+// fail_on policies.
+const (
+	failOnAny  = "any"
+	failOnAll  = "all"
+	failOnNone = "none"
+)
+
+func parseFailOn(input string) (string, error) {
+	switch v := strings.ToLower(strings.TrimSpace(input)); v {
+	case "":
+		return failOnAny, nil
+	case failOnAny, failOnAll, failOnNone:
+		return v, nil
+	}
+	return "", fmt.Errorf("invalid fail_on %q (want any, all or none)", input)
+}
+
+// notifyTargets lists the destinations notifier sends to, each named for
+// reports: a fanout's targets, or the notifier's single destination.
+func notifyTargets(notifier notify.Notifier, backend, topicID string) []notify.Target {
+	if f, ok := notifier.(*notify.Fanout); ok {
+		return f.Targets()
+	}
+	return []notify.Target{{Name: destination(backend, topicID), Notifier: notifier}}
+}
+
+// destination names the single chat, channel or room a notifier sends to,
+// for reports.
+func destination(backend, topicID string) string {
+	switch backend {
+	case "", "telegram":
+		name := "chat " + os.Getenv("INPUT_CHAT_ID")
+		if topicID != "" {
+			name += ":" + topicID
+		}
+		return name
+	case "slack":
+		if channel := os.Getenv("INPUT_SLACK_CHANNEL"); channel != "" {
+			return "slack " + channel
+		}
+	case "matrix":
+		return "matrix " + os.Getenv("INPUT_MATRIX_ROOM_ID")
+	}
+	return backend
+}
+
+// checkDeliveries applies the fail_on policy to the results of a send: it
+// returns an error naming every failed destination when the policy is not
+// met, and prints a warning for tolerated failures.
+func checkDeliveries(failOn string, results []notify.Result) error {
+	_, err := notify.JoinResults(results)
+	if err == nil {
+		return nil
+	}
+	failed := 0
+	for _, r := range results {
+		if r.Err != nil {
+			failed++
+		}
+	}
+	switch {
+	case failOn == failOnAny:
+		return err
+	case failOn == failOnAll && failed == len(results):
+		return err
+	}
+	slog.Warn("Some notifications failed", "failed", failed, "total", len(results), "error", strings.ReplaceAll(err.Error(), "\n", "; "))
+	return nil
+}
+
+// delivered reports whether any of results succeeded.
+func delivered(results []notify.Result) bool {
+	for _, r := range results {
+		if r.Err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// writeStepSummary appends a table of delivery results to the job summary
+// file at path, as set in $GITHUB_STEP_SUMMARY, with secrets removed. It does
+// nothing when path is empty or there are no results.
+func writeStepSummary(path, heading string, results []notify.Result) error {
+	if path == "" || len(results) == 0 {
+		return nil
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "### %s\n\n", heading)
+	b.WriteString("| Destination | Status | Message ID | Error |\n")
+	b.WriteString("|-------------|--------|------------|-------|\n")
+	for _, r := range results {
+		status, errText := "✅ Sent", ""
+		if r.Err != nil {
+			status, errText = "❌ Failed", r.Err.Error()
+		}
+		fmt.Fprintf(&b, "| %s | %s | %s | %s |\n", tableCell(r.Target), status, tableCell(r.MessageID), tableCell(errText))
+	}
+	b.WriteString("\n")
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("opening step summary: %w", err)
+	}
+	if _, err := f.WriteString(notify.Redact(b.String(), secrets...)); err != nil {
+		f.Close()
+		return fmt.Errorf("writing step summary: %w", err)
+	}
+	return f.Close()
+}
+
+// tableCell escapes s for a Markdown table cell.
+func tableCell(s string) string {
+	s = strings.ReplaceAll(s, "|", `\|`)
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// queueTimeout bounds how long a run waits on rate limits. Jobs still
+// queued then stay in the journal for the next run.
+const queueTimeout = 2 * time.Minute
+
+// newQueue returns the delivery queue, with workers concurrent sends and a
+// journal at journalPath when set, so that notifications that could not be
+// sent are retried on the next run. The journal is nil without a path.
+func newQueue(journalPath, workers string) (*queue.Queue, *queue.Journal, error) {
+	n := maxParallelSends
+	if workers != "" {
+		var err error
+		if n, err = strconv.Atoi(workers); err != nil || n < 1 {
+			return nil, nil, fmt.Errorf("queue_workers must be a positive integer")
+		}
+	}
+	var journal *queue.Journal
+	if journalPath != "" {
+		var err error
+		if journal, err = queue.OpenJournal(journalPath, queue.DefaultMaxAttempts, secrets...); err != nil {
+			return nil, nil, err
+		}
+	}
+	q := queue.New(queue.Options{
+		Workers:   n,
+		ChatRate:  queue.ChatRate,
+		GroupRate: queue.GroupRate,
+		Journal:   journal,
+		OnDepth:   func(depth int) { queueDepth.Set(float64(depth)) },
+	})
+	return q, journal, nil
+}
+
+// deliverQueued queues msg for every target, behind any jobs left by
+// earlier runs, and delivers them all with send. It returns the results for
+// msg in target order, and those of the earlier jobs.
+func deliverQueued(q *queue.Queue, targets []notify.Target, key string, msg notify.Message,
+	send func(target string, n notify.Notifier, msg notify.Message) (string, error)) (current, backlog []notify.Result, err error) {
+	stamp := time.Now().UnixNano()
+	ids := make(map[string]int, len(targets))
+	jobs := make([]queue.Job, len(targets))
+	for i, t := range targets {
+		id := fmt.Sprintf("%d-%d", stamp, i)
+		ids[id] = i
+		jobs[i] = queue.Job{ID: id, Target: t.Name, Chat: chatOf(t), Key: t.Name + " " + key, Message: msg}
+	}
+	if err := q.Add(jobs...); err != nil {
+		return nil, nil, err
+	}
+
+	byName := make(map[string]notify.Notifier, len(targets))
+	for _, t := range targets {
+		byName[t.Name] = t.Notifier
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), queueTimeout)
+	defer cancel()
+	results := q.Run(ctx, func(job queue.Job) (string, error) {
+		n, ok := byName[job.Target]
+		if !ok {
+			return "", fmt.Errorf("destination is no longer configured")
+		}
+		return send(job.Target, n, job.Message)
+	})
+
+	current = make([]notify.Result, len(targets))
+	for _, r := range results {
+		res := notify.Result{Target: r.Job.Target, MessageID: r.MessageID, Err: r.Err}
+		if i, ok := ids[r.Job.ID]; ok {
+			current[i] = res
+			continue
+		}
+		res.Target += " (queued)"
+		backlog = append(backlog, res)
+	}
+	return current, backlog, nil
+}
+
+// chatOf returns the chat that rate limits apply to for t: the Telegram
+// chat, or the target itself for other backends.
+func chatOf(t notify.Target) string {
+	if c, ok := t.Notifier.(*telegram.Client); ok {
+		return c.ChatID()
+	}
+	return t.Name
+}
+
+// orderKey groups the notifications that must arrive in order: a PR's, or
+// otherwise the repository's.
+func orderKey(data *events.TemplateData) string {
+	if data.PR.Number != 0 {
+		return fmt.Sprintf("%s#%d", data.Repo.FullName, data.PR.Number)
+	}
+	return data.Repo.FullName
+}
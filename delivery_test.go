@@ -0,0 +1,175 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/andoniaf/telegram-pr-notify/pkg/events"
+	"github.com/andoniaf/telegram-pr-notify/pkg/notify"
+)
+
+func TestParseFailOn(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{"", failOnAny, false},
+		{"any", failOnAny, false},
+		{" ALL ", failOnAll, false},
+		{"none", failOnNone, false},
+		{"some", "", true},
+	}
+	for _, tt := range tests {
+		got, err := parseFailOn(tt.input)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseFailOn(%q) = %q, %v, want %q (error %v)", tt.input, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestCheckDeliveries(t *testing.T) {
+	ok := notify.Result{Target: "chat -100123", MessageID: "42"}
+	failed := notify.Result{Target: "chat @team", Err: errors.New("chat not found")}
+
+	tests := []struct {
+		name    string
+		failOn  string
+		results []notify.Result
+		wantErr bool
+	}{
+		{"any, all sent", failOnAny, []notify.Result{ok, ok}, false},
+		{"any, one failed", failOnAny, []notify.Result{ok, failed}, true},
+		{"all, one failed", failOnAll, []notify.Result{ok, failed}, false},
+		{"all, every one failed", failOnAll, []notify.Result{failed, failed}, true},
+		{"none, every one failed", failOnNone, []notify.Result{failed}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkDeliveries(tt.failOn, tt.results)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("checkDeliveries() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !strings.Contains(err.Error(), "chat @team: chat not found") {
+				t.Errorf("error = %q, want the failed destination named", err)
+			}
+		})
+	}
+}
+
+func TestWriteStepSummary(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "summary.md")
+	os.WriteFile(path, []byte("previous step\n"), 0o644)
+
+	results := []notify.Result{
+		{Target: "chat -100123:5", MessageID: "42"},
+		{Target: "chat @team", Err: errors.New("Bad Request: a | b\nmore")},
+	}
+	if err := writeStepSummary(path, "Notification for push", results); err != nil {
+		t.Fatalf("writeStepSummary() error: %v", err)
+	}
+
+	b, _ := os.ReadFile(path)
+	got := string(b)
+	for _, want := range []string{
+		"previous step\n### Notification for push\n",
+		"| chat -100123:5 | ✅ Sent | 42 |  |\n",
+		`| chat @team | ❌ Failed |  | Bad Request: a \| b more |` + "\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("summary missing %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestWriteStepSummaryRedactsSecrets(t *testing.T) {
+	defer func(old []string) { secrets = old }(secrets)
+	secrets = []string{"123456789:AAHdqTcvCH1vGWJxfSeofSAs0K5PALDsaw"}
+
+	path := filepath.Join(t.TempDir(), "summary.md")
+	results := []notify.Result{{Target: "chat 1", Err: errors.New(`Post "https://api.telegram.org/bot123456789%3AAAHdqTcv…": timeout`)}}
+	if err := writeStepSummary(path, "x", results); err != nil {
+		t.Fatalf("writeStepSummary() error: %v", err)
+	}
+	b, _ := os.ReadFile(path)
+	if strings.Contains(string(b), "AAHdqTcv") {
+		t.Errorf("summary leaks the token:\n%s", b)
+	}
+}
+
+func TestWriteStepSummaryUnset(t *testing.T) {
+	if err := writeStepSummary("", "x", []notify.Result{{Target: "chat 1"}}); err != nil {
+		t.Errorf("writeStepSummary() error = %v, want nil without a summary file", err)
+	}
+}
+
+type fakeSender struct {
+	id  string
+	err error
+}
+
+func (f fakeSender) Send(notify.Message) (string, error) { return f.id, f.err }
+
+func (f fakeSender) Edit(string, notify.Message) error { return nil }
+
+func TestDeliverQueued(t *testing.T) {
+	journalPath := filepath.Join(t.TempDir(), "queue.jsonl")
+	send := func(_ string, n notify.Notifier, msg notify.Message) (string, error) { return n.Send(msg) }
+
+	// A first run fails to reach one chat; the journal keeps it.
+	q, journal, err := newQueue(journalPath, "2")
+	if err != nil {
+		t.Fatalf("newQueue() error: %v", err)
+	}
+	targets := []notify.Target{
+		{Name: "chat -100123", Notifier: fakeSender{id: "1"}},
+		{Name: "chat @team", Notifier: fakeSender{err: &notify.StatusError{Code: 429, Err: errors.New("Too Many Requests")}}},
+	}
+	current, backlog, err := deliverQueued(q, targets, "octo/repo#7", notify.Message{Blocks: []string{"opened"}}, send)
+	if err != nil {
+		t.Fatalf("deliverQueued() error: %v", err)
+	}
+	journal.Close()
+	if len(backlog) != 0 || current[0].MessageID != "1" || current[1].Err == nil {
+		t.Fatalf("first run = %+v, %+v", current, backlog)
+	}
+
+	// The next run delivers the queued job before its own message.
+	q, journal, err = newQueue(journalPath, "")
+	if err != nil {
+		t.Fatalf("newQueue() error: %v", err)
+	}
+	defer journal.Close()
+	targets = []notify.Target{
+		{Name: "chat -100123", Notifier: fakeSender{id: "2"}},
+		{Name: "chat @team", Notifier: fakeSender{id: "3"}},
+	}
+	current, backlog, err = deliverQueued(q, targets, "octo/repo#7", notify.Message{Blocks: []string{"merged"}}, send)
+	if err != nil {
+		t.Fatalf("deliverQueued() error: %v", err)
+	}
+	if len(backlog) != 1 || backlog[0].Target != "chat @team (queued)" || backlog[0].Err != nil {
+		t.Errorf("backlog = %+v, want the queued job delivered", backlog)
+	}
+	if current[0].MessageID != "2" || current[1].MessageID != "3" {
+		t.Errorf("current = %+v, want results in target order", current)
+	}
+}
+
+func TestNewQueueInvalidWorkers(t *testing.T) {
+	if _, _, err := newQueue("", "0"); err == nil {
+		t.Error("newQueue() expected error for queue_workers 0")
+	}
+}
+
+func TestOrderKey(t *testing.T) {
+	if got := orderKey(prEvent("opened")); got != "octocat/Hello-World#7" {
+		t.Errorf("orderKey() = %q", got)
+	}
+	if got := orderKey(&events.TemplateData{Repo: events.Repository{FullName: "octo/repo"}}); got != "octo/repo" {
+		t.Errorf("orderKey() = %q", got)
+	}
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"embed"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/andoniaf/telegram-pr-notify/pkg/events"
+	"github.com/andoniaf/telegram-pr-notify/pkg/notify"
+	"github.com/andoniaf/telegram-pr-notify/pkg/telegram"
+	"github.com/andoniaf/telegram-pr-notify/pkg/templates"
+)
+
+// sampleEvents are the event payloads that templates are checked against:
+// a copy of the test fixtures, which are not built in. Run go generate after
+// changing testdata; TestSampleEventsMatchTestdata fails until then.
+//
+//go:generate sh -c "rm -f samples/*.json && cp testdata/*.json samples/"
+//go:embed samples/*.json
+var sampleEvents embed.FS
+
+// doctorConfig is the configuration checked by the doctor mode.
+type doctorConfig struct {
+	customTemplate  string
+	buttonsTemplate string
+	pins            pinRule
+	autoTopic       bool
+}
+
+// report prints doctor check results and counts failures.
+type report struct {
+	w      io.Writer
+	failed int
+}
+
+func (r *report) ok(format string, args ...any) {
+	fmt.Fprintf(r.w, "  [ok]   %s\n", fmt.Sprintf(format, args...))
+}
+
+func (r *report) warn(format string, args ...any) {
+	fmt.Fprintf(r.w, "  [warn] %s\n", fmt.Sprintf(format, args...))
+}
+
+func (r *report) fail(format string, args ...any) {
+	r.failed++
+	fmt.Fprintf(r.w, "  [fail] %s\n", fmt.Sprintf(format, args...))
+}
+
+func (r *report) section(title string) {
+	fmt.Fprintf(r.w, "\n%s\n", title)
+}
+
+// doctor checks every target and the templates without sending a
+// notification, printing a report to w. It fails when any check fails.
+func doctor(w io.Writer, targets []notify.Target, cfg doctorConfig) error {
+	r := &report{w: w}
+	fmt.Fprintln(w, "telegram-pr-notify doctor")
+
+	for _, t := range targets {
+		if len(targets) > 1 {
+			r.section("Notifier: " + t.Name)
+		} else {
+			r.section("Notifier")
+		}
+		if tg, ok := t.Notifier.(*telegram.Client); ok {
+			checkTelegram(r, tg, cfg)
+		} else {
+			r.warn("connectivity is only checked for telegram; skipped for %s", t.Name)
+		}
+	}
+
+	r.section("Templates")
+	checkTemplates(r, cfg)
+
+	if r.failed > 0 {
+		return fmt.Errorf("doctor found %d problem(s)", r.failed)
+	}
+	fmt.Fprintln(w, "\nAll checks passed")
+	return nil
+}
+
+// checkTelegram checks the bot token, the chat, the bot's rights and the
+// topic.
+func checkTelegram(r *report, tg *telegram.Client, cfg doctorConfig) {
+	me, err := tg.GetMe()
+	if err != nil {
+		r.fail("getMe: %v (check bot_token)", err)
+		return
+	}
+	r.ok("bot token is valid for @%s", me.Username)
+
+	chat, err := tg.GetChat()
+	if err != nil {
+		r.fail("getChat: %v (check chat_id, and that the bot was added to the chat)", err)
+		return
+	}
+	name := chat.Title
+	if name == "" {
+		name = fmt.Sprint(chat.ID)
+	}
+	r.ok("chat %q found (%s)", name, chat.Type)
+
+	member, err := tg.GetChatMember(me.ID)
+	if err != nil {
+		r.fail("getChatMember: %v", err)
+		return
+	}
+	admin := member.Status == "administrator" || member.Status == "creator"
+	switch {
+	case member.Status == "left" || member.Status == "kicked":
+		r.fail("the bot is not a member of the chat (status %q)", member.Status)
+		return
+	case member.Status == "restricted" && !member.CanSendMessages:
+		r.fail("the bot is restricted and cannot send messages")
+		return
+	case chat.Type == "channel" && !(admin && member.CanPostMessages):
+		r.fail("the bot needs to be a channel administrator with the right to post messages")
+		return
+	}
+	r.ok("the bot is a chat %s", member.Status)
+
+	if cfg.pins.enabled() && !(member.Status == "creator" || admin && member.CanPinMessages) {
+		r.warn("pin_labels/pin_template are set but the bot cannot pin messages")
+	}
+	if cfg.autoTopic {
+		if !chat.IsForum {
+			r.fail("auto_topic is set but the chat does not have topics enabled")
+		} else if !(member.Status == "creator" || admin && member.CanManageTopics) {
+			r.fail("auto_topic is set but the bot cannot manage topics")
+		}
+	}
+
+	topicID := tg.TopicID()
+	if topicID != "" && !chat.IsForum {
+		r.fail("a topic is set but the chat does not have topics enabled")
+		return
+	}
+	if err := tg.SendChatAction("typing", ""); err != nil {
+		if topicID != "" {
+			r.fail("cannot post in topic %s: %v", topicID, err)
+		} else {
+			r.fail("cannot post in the chat: %v", err)
+		}
+		return
+	}
+	if topicID != "" {
+		r.ok("the bot can post in topic %s", topicID)
+	} else {
+		r.ok("the bot can post in the chat")
+	}
+}
+
+// checkTemplates renders every default template, the custom template and
+// the buttons template against every sample event.
+func checkTemplates(r *report, cfg doctorConfig) {
+	var samples []*events.TemplateData
+	var names []string
+	entries, _ := sampleEvents.ReadDir("samples")
+	for _, e := range entries {
+		raw, err := sampleEvents.ReadFile(path.Join("samples", e.Name()))
+		if err != nil {
+			r.fail("reading sample %s: %v", e.Name(), err)
+			continue
+		}
+		data, err := events.Parse(raw)
+		if err != nil {
+			r.fail("parsing sample %s: %v", e.Name(), err)
+			continue
+		}
+		samples = append(samples, data)
+		names = append(names, strings.TrimSuffix(e.Name(), ".json"))
+	}
+
+	defaults := templates.Defaults()
+	keys := make([]string, 0, len(defaults))
+	for k := range defaults {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	failures := 0
+	for _, k := range keys {
+		failures += renderAll(r, "default template "+k, samples, names, func(data *events.TemplateData) error {
+			_, err := templates.Build(data, defaults[k])
+			return err
+		})
+	}
+	if failures == 0 {
+		r.ok("%d default templates render against %d sample events", len(keys), len(samples))
+	}
+
+	if cfg.customTemplate != "" {
+		if renderAll(r, "custom_template", samples, names, func(data *events.TemplateData) error {
+			_, err := templates.Build(data, cfg.customTemplate)
+			return err
+		}) == 0 {
+			r.ok("custom_template renders against %d sample events", len(samples))
+		}
+	}
+	if cfg.buttonsTemplate != "" {
+		if renderAll(r, "buttons", samples, names, func(data *events.TemplateData) error {
+			_, err := templates.Buttons(data, cfg.buttonsTemplate)
+			return err
+		}) == 0 {
+			r.ok("buttons renders against %d sample events", len(samples))
+		}
+	}
+	if cfg.pins.template != "" {
+		if renderAll(r, "pin_template", samples, names, func(data *events.TemplateData) error {
+			_, err := cfg.pins.match(data)
+			return err
+		}) == 0 {
+			r.ok("pin_template renders against %d sample events", len(samples))
+		}
+	}
+}
+
+// renderAll runs render for every sample and reports the first failure.
+// It returns the number of failing samples.
+func renderAll(r *report, what string, samples []*events.TemplateData, names []string, render func(*events.TemplateData) error) int {
+	failed := 0
+	var first string
+	for i, data := range samples {
+		if err := render(data); err != nil {
+			if failed == 0 {
+				first = fmt.Sprintf("%s with %s: %v", what, names[i], err)
+			}
+			failed++
+		}
+	}
+	switch {
+	case failed == 1:
+		r.fail("%s", first)
+	case failed > 1:
+		r.fail("%s (and %d more sample events)", first, failed-1)
+	}
+	return failed
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bytes"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/andoniaf/telegram-pr-notify/pkg/notify"
+	"github.com/andoniaf/telegram-pr-notify/pkg/slack"
+	"github.com/andoniaf/telegram-pr-notify/pkg/telegram"
+)
+
+func TestDoctorTemplates(t *testing.T) {
+	var out bytes.Buffer
+	err := doctor(&out, []notify.Target{{Name: "slack", Notifier: slack.NewWebhookClient("http://localhost")}}, doctorConfig{
+		customTemplate:  "<b>{{.EventName}}</b> {{.PR.Title}}",
+		buttonsTemplate: "Repo | {{.Repo.HTMLURL}}",
+	})
+	if err != nil {
+		t.Fatalf("doctor() error: %v\n%s", err, out.String())
+	}
+	for _, want := range []string{"default templates render against", "custom_template renders", "buttons renders"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("report missing %q:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestSampleEventsMatchTestdata(t *testing.T) {
+	fixtures, err := filepath.Glob("testdata/*.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	samples, err := fs.Glob(sampleEvents, "samples/*.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(samples) != len(fixtures) {
+		t.Errorf("samples has %d files, testdata %d; run go generate", len(samples), len(fixtures))
+	}
+	for _, f := range fixtures {
+		want, err := os.ReadFile(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := sampleEvents.ReadFile(path.Join("samples", filepath.Base(f)))
+		if err != nil || !bytes.Equal(got, want) {
+			t.Errorf("samples/%s differs from %s; run go generate", filepath.Base(f), f)
+		}
+	}
+}
+
+func TestDoctorFanoutNames(t *testing.T) {
+	fanout := notify.NewFanout([]notify.Target{
+		{Name: "slack #dev", Notifier: slack.NewWebhookClient("http://localhost")},
+		{Name: "slack #ops", Notifier: slack.NewWebhookClient("http://localhost")},
+	}, 1)
+
+	var out bytes.Buffer
+	if err := doctor(&out, notifyTargets(fanout, "slack", ""), doctorConfig{}); err != nil {
+		t.Fatalf("doctor() error: %v\n%s", err, out.String())
+	}
+	for _, want := range []string{"skipped for slack #dev", "skipped for slack #ops"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("report missing %q:\n%s", want, out.String())
+		}
+	}
+}
+
+// rewriteTransport sends every request to a test server.
+type rewriteTransport struct {
+	target string
+}
+
+func (t rewriteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme, req.URL.Host = "http", strings.TrimPrefix(t.target, "http://")
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestDoctorTelegram(t *testing.T) {
+	tests := []struct {
+		name      string
+		responses map[string]string
+		topicID   string
+		wantErr   bool
+		want      string
+	}{
+		{
+			name: "healthy forum topic",
+			responses: map[string]string{
+				"getMe":          `{"ok": true, "result": {"id": 1, "username": "pr_bot"}}`,
+				"getChat":        `{"ok": true, "result": {"id": -100123, "type": "supergroup", "title": "Dev", "is_forum": true}}`,
+				"getChatMember":  `{"ok": true, "result": {"status": "administrator", "can_manage_topics": true}}`,
+				"sendChatAction": `{"ok": true, "result": true}`,
+			},
+			topicID: "5",
+			want:    "the bot can post in topic 5",
+		},
+		{
+			name: "chat not found",
+			responses: map[string]string{
+				"getMe":   `{"ok": true, "result": {"id": 1, "username": "pr_bot"}}`,
+				"getChat": `{"ok": false, "description": "Bad Request: chat not found"}`,
+			},
+			wantErr: true,
+			want:    "check chat_id",
+		},
+		{
+			name: "topic without forum",
+			responses: map[string]string{
+				"getMe":         `{"ok": true, "result": {"id": 1, "username": "pr_bot"}}`,
+				"getChat":       `{"ok": true, "result": {"id": -100123, "type": "group", "title": "Dev"}}`,
+				"getChatMember": `{"ok": true, "result": {"status": "member"}}`,
+			},
+			topicID: "5",
+			wantErr: true,
+			want:    "does not have topics enabled",
+		},
+		{
+			name: "bot removed",
+			responses: map[string]string{
+				"getMe":         `{"ok": true, "result": {"id": 1, "username": "pr_bot"}}`,
+				"getChat":       `{"ok": true, "result": {"id": -100123, "type": "group", "title": "Dev"}}`,
+				"getChatMember": `{"ok": true, "result": {"status": "kicked"}}`,
+			},
+			wantErr: true,
+			want:    "not a member",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				method := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+				resp, ok := tt.responses[method]
+				if !ok {
+					t.Errorf("unexpected call to %s", method)
+					resp = `{"ok": false, "description": "unexpected"}`
+				}
+				w.Write([]byte(resp))
+			}))
+			defer server.Close()
+
+			tg := telegram.NewClient("token", "-100123", tt.topicID).
+				WithHTTPClient(&http.Client{Transport: rewriteTransport{server.URL}})
+
+			var out bytes.Buffer
+			err := doctor(&out, []notify.Target{{Name: "chat -100123", Notifier: tg}}, doctorConfig{})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("doctor() error = %v, wantErr %v\n%s", err, tt.wantErr, out.String())
+			}
+			if !strings.Contains(out.String(), tt.want) {
+				t.Errorf("report missing %q:\n%s", tt.want, out.String())
+			}
+		})
+	}
+}
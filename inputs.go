@@ -0,0 +1,207 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/andoniaf/telegram-pr-notify/pkg/events"
+	"github.com/andoniaf/telegram-pr-notify/pkg/notify"
+)
+
+// parseKeyValues parses a comma- or newline-separated list of key=value
+// pairs, as used by mapping inputs such as category_topics.
+func parseKeyValues(input string) (map[string]string, error) {
+	pairs := make(map[string]string)
+	for _, item := range strings.FieldsFunc(input, func(r rune) bool { return r == ',' || r == '\n' }) {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(item, "=")
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid entry %q (want key=value)", item)
+		}
+		pairs[key] = value
+	}
+	return pairs, nil
+}
+
+// lookupFold returns the value for key using case-insensitive matching.
+func lookupFold(pairs map[string]string, key string) (string, bool) {
+	if v, ok := pairs[key]; ok {
+		return v, true
+	}
+	for k, v := range pairs {
+		if strings.EqualFold(k, key) {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// maxButtonsPerRow is the most buttons Telegram accepts in one keyboard row.
+const maxButtonsPerRow = 8
+
+// parseLayout parses a button_layout input: comma-separated row sizes, where
+// the last size repeats for the remaining buttons. A single number is the
+// maximum number of buttons per row.
+func parseLayout(input string) ([]int, error) {
+	if strings.TrimSpace(input) == "" {
+		return []int{maxButtonsPerRow}, nil
+	}
+	var rows []int
+	for _, item := range strings.Split(input, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(item))
+		if err != nil || n < 1 || n > maxButtonsPerRow {
+			return nil, fmt.Errorf("invalid row size %q (want 1-%d)", strings.TrimSpace(item), maxButtonsPerRow)
+		}
+		rows = append(rows, n)
+	}
+	return rows, nil
+}
+
+// parseTicketPatterns parses the ticket_patterns input: one pattern per line
+// as "[Name=]REGEX URL", where the URL is the last whitespace-separated field.
+func parseTicketPatterns(input string) ([]events.TicketPattern, error) {
+	var patterns []events.TicketPattern
+	for _, line := range strings.Split(input, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		sep := strings.LastIndexAny(line, " \t")
+		if sep < 0 {
+			return nil, fmt.Errorf("invalid entry %q (want [Name=]REGEX URL)", line)
+		}
+		expr, url := strings.TrimSpace(line[:sep]), line[sep+1:]
+
+		var name string
+		if n, rest, ok := strings.Cut(expr, "="); ok && isTrackerName(n) {
+			name, expr = n, rest
+		}
+
+		p, err := events.NewTicketPattern(name, expr, url)
+		if err != nil {
+			return nil, err
+		}
+		patterns = append(patterns, p)
+	}
+	return patterns, nil
+}
+
+// isTrackerName reports whether s looks like a tracker label rather than
+// the start of a regex.
+func isTrackerName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '-' || r == ' ') {
+			return false
+		}
+	}
+	return true
+}
+
+// eventRules maps event selectors to a setting, as used by the silent,
+// protect_content, link_preview and reactions inputs. Selectors are "*", an
+// event name ("push"), an event and action ("pull_request.synchronize"),
+// "bot", "merged" and "failure".
+type eventRules map[string]string
+
+// parseEventRules parses a rules input: either a single value for every
+// event, or comma- or newline-separated selector=value pairs. valid checks
+// each value.
+func parseEventRules(input string, valid func(string) error) (eventRules, error) {
+	input = strings.TrimSpace(input)
+	if input == "" {
+		return nil, nil
+	}
+	rules := make(eventRules)
+	if !strings.Contains(input, "=") {
+		rules["*"] = input
+	} else {
+		pairs, err := parseKeyValues(input)
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range pairs {
+			rules[strings.ToLower(k)] = v
+		}
+	}
+	for k, v := range rules {
+		if err := valid(v); err != nil {
+			return nil, fmt.Errorf("rule %q: %w", k, err)
+		}
+	}
+	return rules, nil
+}
+
+// lookup returns the value of the most specific rule matching data:
+// failure, merged, event.action, bot, event, then "*".
+func (r eventRules) lookup(data *events.TemplateData) (string, bool) {
+	var keys []string
+	if data.IsFailure() {
+		keys = append(keys, "failure")
+	}
+	if data.IsMerged() {
+		keys = append(keys, "merged")
+	}
+	if data.Action != "" {
+		keys = append(keys, data.EventName+"."+data.Action)
+	}
+	if data.IsBot() {
+		keys = append(keys, "bot")
+	}
+	keys = append(keys, data.EventName, "*")
+
+	for _, k := range keys {
+		if v, ok := r[strings.ToLower(k)]; ok {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// enabled reports whether the rule matching data is true.
+func (r eventRules) enabled(data *events.TemplateData) bool {
+	v, _ := r.lookup(data)
+	b, _ := strconv.ParseBool(v)
+	return b
+}
+
+func validBool(v string) error {
+	if _, err := strconv.ParseBool(v); err != nil {
+		return fmt.Errorf("invalid value %q (want true or false)", v)
+	}
+	return nil
+}
+
+// Link preview modes accepted by the link_preview input.
+const (
+	previewNone = "none"
+	previewAuto = "auto"
+)
+
+func validPreview(v string) error {
+	switch v {
+	case previewNone, previewAuto, notify.PreviewSmall, notify.PreviewLarge:
+		return nil
+	}
+	return fmt.Errorf("invalid value %q (want none, auto, small or large)", v)
+}
+
+// linkPreview returns the preview of the event's main link selected by the
+// link_preview rules. Previews are off unless a rule enables them.
+func linkPreview(r eventRules, data *events.TemplateData) notify.LinkPreview {
+	mode, _ := r.lookup(data)
+	switch mode {
+	case "", previewNone:
+		return notify.LinkPreview{}
+	case previewAuto:
+		return notify.LinkPreview{URL: data.RelevantURL()}
+	}
+	return notify.LinkPreview{URL: data.RelevantURL(), Size: mode}
+}
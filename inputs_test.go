@@ -0,0 +1,177 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/andoniaf/telegram-pr-notify/pkg/events"
+	"github.com/andoniaf/telegram-pr-notify/pkg/notify"
+)
+
+func TestParseKeyValues(t *testing.T) {
+	got, err := parseKeyValues("Q&A=12, Ideas = 34\nAnnouncements=56\n")
+	if err != nil {
+		t.Fatalf("parseKeyValues() error: %v", err)
+	}
+	want := map[string]string{"Q&A": "12", "Ideas": "34", "Announcements": "56"}
+	if len(got) != len(want) {
+		t.Fatalf("parseKeyValues() = %v, want %v", got, want)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("got[%q] = %q, want %q", k, got[k], v)
+		}
+	}
+}
+
+func TestParseKeyValuesEmpty(t *testing.T) {
+	got, err := parseKeyValues("")
+	if err != nil {
+		t.Fatalf("parseKeyValues() error: %v", err)
+	}
+	if len(got) != 0 {
+		t.Errorf("parseKeyValues(\"\") = %v, want empty", got)
+	}
+}
+
+func TestParseKeyValuesInvalid(t *testing.T) {
+	for _, input := range []string{"novalue", "=12"} {
+		if _, err := parseKeyValues(input); err == nil {
+			t.Errorf("parseKeyValues(%q) expected error", input)
+		}
+	}
+}
+
+func TestLookupFold(t *testing.T) {
+	pairs := map[string]string{"Q&A": "12"}
+	if v, ok := lookupFold(pairs, "q&a"); !ok || v != "12" {
+		t.Errorf("lookupFold() = %q, %v, want %q, true", v, ok, "12")
+	}
+	if _, ok := lookupFold(pairs, "Ideas"); ok {
+		t.Error("lookupFold() found a missing key")
+	}
+}
+
+func TestParseLayout(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    []int
+		wantErr bool
+	}{
+		{"", []int{8}, false},
+		{"3", []int{3}, false},
+		{"1, 2", []int{1, 2}, false},
+		{"0", nil, true},
+		{"9", nil, true},
+		{"two", nil, true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseLayout(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseLayout(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			continue
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("parseLayout(%q) = %v, want %v", tt.input, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("parseLayout(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		}
+	}
+}
+
+func TestParseTicketPatterns(t *testing.T) {
+	input := `
+Jira=[A-Z][A-Z0-9]+-\d+ https://example.atlassian.net/browse/$0
+(?i)yt-(\d+)	https://youtrack.example.com/issue/YT-$1
+`
+	patterns, err := parseTicketPatterns(input)
+	if err != nil {
+		t.Fatalf("parseTicketPatterns() error: %v", err)
+	}
+	if len(patterns) != 2 {
+		t.Fatalf("parseTicketPatterns() = %d patterns, want 2", len(patterns))
+	}
+	if patterns[0].Name != "Jira" || patterns[0].Regexp.String() != `[A-Z][A-Z0-9]+-\d+` {
+		t.Errorf("patterns[0] = %+v", patterns[0])
+	}
+	if patterns[1].Name != "" || patterns[1].URL != "https://youtrack.example.com/issue/YT-$1" {
+		t.Errorf("patterns[1] = %+v", patterns[1])
+	}
+
+	for _, bad := range []string{"nourl", "Jira=[A-Z https://x"} {
+		if _, err := parseTicketPatterns(bad); err == nil {
+			t.Errorf("parseTicketPatterns(%q) expected error", bad)
+		}
+	}
+}
+
+func TestEventRulesEnabled(t *testing.T) {
+	rules, err := parseEventRules("pull_request.synchronize=true, bot=true\npull_request.review_requested=false, failure=false, push=true\npull_request.closed=false, merged=true", validBool)
+	if err != nil {
+		t.Fatalf("parseEventRules() error: %v", err)
+	}
+	dependabot := events.User{Login: "dependabot[bot]"}
+
+	tests := []struct {
+		name string
+		data events.TemplateData
+		want bool
+	}{
+		{"event and action", events.TemplateData{EventName: "pull_request", Action: "synchronize"}, true},
+		{"no match", events.TemplateData{EventName: "pull_request", Action: "opened"}, false},
+		{"event", events.TemplateData{EventName: "push"}, true},
+		{"bot author", events.TemplateData{EventName: "pull_request", Action: "opened", PR: events.PullRequest{User: dependabot}}, true},
+		{"action beats bot", events.TemplateData{EventName: "pull_request", Action: "review_requested", Actor: dependabot}, false},
+		{"failure beats event", events.TemplateData{EventName: "push", CI: events.CIRun{Conclusion: "failure"}}, false},
+		{"merged beats event and action", events.TemplateData{EventName: "pull_request", Action: "closed", PR: events.PullRequest{Merged: true}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rules.enabled(&tt.data); got != tt.want {
+				t.Errorf("enabled() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseEventRules(t *testing.T) {
+	rules, err := parseEventRules("true", validBool)
+	if err != nil || rules["*"] != "true" {
+		t.Errorf("parseEventRules(\"true\") = %v, %v, want a catch-all rule", rules, err)
+	}
+	if rules, err := parseEventRules("", validBool); err != nil || rules != nil {
+		t.Errorf("parseEventRules(\"\") = %v, %v, want nil", rules, err)
+	}
+	for _, input := range []string{"maybe", "push=yes please", "push"} {
+		if _, err := parseEventRules(input, validBool); err == nil {
+			t.Errorf("parseEventRules(%q) expected error", input)
+		}
+	}
+	if _, err := parseEventRules("push=huge", validPreview); err == nil {
+		t.Error("parseEventRules() accepted an invalid preview size")
+	}
+}
+
+func TestLinkPreview(t *testing.T) {
+	rules, err := parseEventRules("*=none, pull_request.opened=large, release=auto", validPreview)
+	if err != nil {
+		t.Fatalf("parseEventRules() error: %v", err)
+	}
+	pr := events.PullRequest{HTMLURL: "https://github.com/o/r/pull/1"}
+
+	got := linkPreview(rules, &events.TemplateData{EventName: "pull_request", Action: "opened", PR: pr})
+	if want := (notify.LinkPreview{URL: pr.HTMLURL, Size: notify.PreviewLarge}); got != want {
+		t.Errorf("linkPreview() = %+v, want %+v", got, want)
+	}
+	if got := linkPreview(rules, &events.TemplateData{EventName: "pull_request", Action: "closed", PR: pr}); got.URL != "" {
+		t.Errorf("linkPreview() = %+v, want disabled", got)
+	}
+	if got := linkPreview(nil, &events.TemplateData{EventName: "pull_request", PR: pr}); got.URL != "" {
+		t.Errorf("linkPreview() without rules = %+v, want disabled", got)
+	}
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/andoniaf/telegram-pr-notify/pkg/notify"
+)
+
+// LevelNotice sits between info and warning: things worth an annotation on
+// the run, such as a skipped notification.
+const LevelNotice = slog.Level(2)
+
+// Log formats accepted by the log_format input.
+const (
+	logText = "text"
+	logJSON = "json"
+)
+
+// logHandler is a slog.Handler that writes one line per record for the
+// Actions runner. Debug, notice, warning and error records become workflow
+// commands (::debug::, ::notice::, ::warning::, ::error::), so the runner
+// shows them as annotations; info records are plain lines.
+type logHandler struct {
+	out, errOut io.Writer
+	level       slog.Leveler
+	json        bool
+	// debugCommand writes debug records as ::debug:: commands, which the
+	// runner only shows with step debug logging on. Otherwise they are plain
+	// lines, visible whenever the debug input enables them.
+	debugCommand bool
+
+	// inner formats the record's attributes into buf.
+	inner slog.Handler
+	buf   *bytes.Buffer
+	mu    *sync.Mutex
+}
+
+// newLogHandler returns a handler writing records at level or above in the
+// given format to out, and errors to errOut.
+func newLogHandler(out, errOut io.Writer, level slog.Leveler, format string, debugCommand bool) (*logHandler, error) {
+	h := &logHandler{
+		out:          out,
+		errOut:       errOut,
+		level:        level,
+		debugCommand: debugCommand,
+		buf:          new(bytes.Buffer),
+		mu:           new(sync.Mutex),
+	}
+	opts := &slog.HandlerOptions{Level: slog.LevelDebug, ReplaceAttr: h.replaceAttr}
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case "", logText:
+		h.inner = slog.NewTextHandler(h.buf, opts)
+	case logJSON:
+		h.json = true
+		h.inner = slog.NewJSONHandler(h.buf, opts)
+	default:
+		return nil, fmt.Errorf("invalid log_format %q (want text or json)", format)
+	}
+	return h, nil
+}
+
+// replaceAttr drops the time, which the runner adds itself, and names the
+// notice level. In text, the level and message are written by Handle.
+func (h *logHandler) replaceAttr(groups []string, a slog.Attr) slog.Attr {
+	if len(groups) > 0 {
+		return a
+	}
+	switch a.Key {
+	case slog.TimeKey:
+		return slog.Attr{}
+	case slog.LevelKey:
+		if !h.json {
+			return slog.Attr{}
+		}
+		if l, ok := a.Value.Any().(slog.Level); ok && l == LevelNotice {
+			a.Value = slog.StringValue("NOTICE")
+		}
+	case slog.MessageKey:
+		if !h.json {
+			return slog.Attr{}
+		}
+	}
+	return a
+}
+
+func (h *logHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+func (h *logHandler) Handle(ctx context.Context, r slog.Record) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.buf.Reset()
+	if err := h.inner.Handle(ctx, r); err != nil {
+		return err
+	}
+	line := strings.TrimSuffix(h.buf.String(), "\n")
+	if !h.json {
+		line = strings.TrimSpace(r.Message + " " + line)
+	}
+
+	w := h.out
+	switch {
+	case r.Level >= slog.LevelError:
+		w, line = h.errOut, "::error::"+escapeData(line)
+	case r.Level >= slog.LevelWarn:
+		line = "::warning::" + escapeData(line)
+	case r.Level >= LevelNotice:
+		line = "::notice::" + escapeData(line)
+	case r.Level < slog.LevelInfo && h.debugCommand:
+		line = "::debug::" + escapeData(line)
+	case r.Level < slog.LevelInfo && !h.json:
+		line = "[debug] " + line
+	}
+	_, err := io.WriteString(w, line+"\n")
+	return err
+}
+
+func (h *logHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	c := *h
+	c.inner = h.inner.WithAttrs(attrs)
+	return &c
+}
+
+func (h *logHandler) WithGroup(name string) slog.Handler {
+	c := *h
+	c.inner = h.inner.WithGroup(name)
+	return &c
+}
+
+// escapeData escapes a workflow command's message, which must be one line.
+func escapeData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+// apiTransport is an http.RoundTripper that logs every API request at
+// debug level (method, path, request size, response code and duration) and
+// records its latency and rate limiting in the metrics. Secret inputs are
+// removed from the path, which holds the Telegram bot token.
+type apiTransport struct {
+	base http.RoundTripper
+}
+
+func (t apiTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	apiDuration.Observe(time.Since(start).Seconds(), req.URL.Host, apiMethod(req), statusCode(resp))
+	if resp != nil && resp.StatusCode == http.StatusTooManyRequests {
+		rateLimitHits.Inc(req.URL.Host)
+	}
+	attrs := []any{
+		"method", req.Method,
+		"host", req.URL.Host,
+		"path", notify.Redact(req.URL.Path, secrets...),
+		"bytes", req.ContentLength,
+		"duration", time.Since(start).Round(time.Millisecond),
+	}
+	if err != nil {
+		slog.Debug("API request failed", append(attrs, "error", notify.RedactError(err, secrets...))...)
+		return nil, err
+	}
+	slog.Debug("API request", append(attrs, "status", resp.StatusCode)...)
+	return resp, nil
+}
+
+// setupLogger installs the action's logger as slog's default, with debug
+// records when debug is true or the runner has debug logging on.
+func setupLogger(debug, format string) error {
+	runnerDebug := os.Getenv("RUNNER_DEBUG") == "1"
+	level := slog.LevelInfo
+	if runnerDebug {
+		level = slog.LevelDebug
+	}
+	if debug != "" {
+		on, err := strconv.ParseBool(debug)
+		if err != nil {
+			return fmt.Errorf("debug must be true or false")
+		}
+		if on {
+			level = slog.LevelDebug
+		}
+	}
+	h, err := newLogHandler(stdout, stderr, level, format, runnerDebug)
+	if err != nil {
+		return err
+	}
+	slog.SetDefault(slog.New(h))
+	return nil
+}
+
+// apiClient returns an http.Client for the notifier and GitHub APIs whose
+// requests are logged and measured by apiTransport.
+func apiClient(timeout time.Duration) *http.Client {
+	return &http.Client{Timeout: timeout, Transport: apiTransport{base: http.DefaultTransport}}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLogHandlerText(t *testing.T) {
+	var out, errOut bytes.Buffer
+	h, err := newLogHandler(&out, &errOut, slog.LevelInfo, "", false)
+	if err != nil {
+		t.Fatalf("newLogHandler() error: %v", err)
+	}
+	log := slog.New(h)
+
+	log.Debug("hidden")
+	log.Info("Notification sent successfully", "message_id", "42")
+	log.Log(context.Background(), LevelNotice, "Notification skipped", "conclusion", "success")
+	log.With("chat", "-100123").Warn("Could not attach diff", "error", "line one\nline two 100%")
+	log.Error("sending message: chat not found")
+
+	want := "Notification sent successfully message_id=42\n" +
+		"::notice::Notification skipped conclusion=success\n" +
+		"::warning::Could not attach diff chat=-100123 error=\"line one\\nline two 100%25\"\n"
+	if out.String() != want {
+		t.Errorf("stdout =\n%s\nwant\n%s", out.String(), want)
+	}
+	if got := errOut.String(); got != "::error::sending message: chat not found\n" {
+		t.Errorf("stderr = %q", got)
+	}
+}
+
+func TestLogHandlerMultilineMessage(t *testing.T) {
+	var out bytes.Buffer
+	h, _ := newLogHandler(&out, &out, slog.LevelInfo, logText, false)
+	slog.New(h).Warn("first\nsecond")
+	if got := out.String(); got != "::warning::first%0Asecond\n" {
+		t.Errorf("output = %q, want the newline escaped", got)
+	}
+}
+
+func TestLogHandlerDebug(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		command bool
+		want    string
+	}{
+		{"plain", logText, false, "[debug] Parsed event event=push\n"},
+		{"runner debug", logText, true, "::debug::Parsed event event=push\n"},
+		{"json", logJSON, false, `{"level":"DEBUG","msg":"Parsed event","event":"push"}` + "\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			h, err := newLogHandler(&out, &out, slog.LevelDebug, tt.format, tt.command)
+			if err != nil {
+				t.Fatalf("newLogHandler() error: %v", err)
+			}
+			slog.New(h).Debug("Parsed event", "event", "push")
+			if out.String() != tt.want {
+				t.Errorf("output = %q, want %q", out.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestLogHandlerJSONAnnotations(t *testing.T) {
+	var out bytes.Buffer
+	h, _ := newLogHandler(&out, &out, slog.LevelInfo, "JSON", false)
+	slog.New(h).Log(context.Background(), LevelNotice, "skipped")
+	if got := out.String(); got != `::notice::{"level":"NOTICE","msg":"skipped"}`+"\n" {
+		t.Errorf("output = %q", got)
+	}
+}
+
+func TestLogHandlerInvalidFormat(t *testing.T) {
+	if _, err := newLogHandler(nil, nil, slog.LevelInfo, "xml", false); err == nil {
+		t.Error("newLogHandler() expected error for an unknown format")
+	}
+}
+
+func TestAPITransport(t *testing.T) {
+	defer func(old []string) { secrets = old }(secrets)
+	defer slog.SetDefault(slog.Default())
+	secrets = []string{"123456789:AAHdqTcvCH1vGWJxfSeofSAs0K5PALDsaw"}
+
+	var out bytes.Buffer
+	h, _ := newLogHandler(&out, &out, slog.LevelDebug, logText, false)
+	slog.SetDefault(slog.New(h))
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: apiTransport{base: http.DefaultTransport}}
+	resp, err := client.Post(server.URL+"/bot"+secrets[0]+"/sendMessage", "application/json", strings.NewReader(`{"text":"hi"}`))
+	if err != nil {
+		t.Fatalf("Post() error: %v", err)
+	}
+	resp.Body.Close()
+
+	got := out.String()
+	for _, want := range []string{"[debug] API request ", "method=POST", "path=/bot[REDACTED]/sendMessage", "bytes=13", "status=429"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("log = %q, missing %q", got, want)
+		}
+	}
+	if strings.Contains(got, "AAHdqTcv") {
+		t.Errorf("log leaks the token: %q", got)
+	}
+
+	var metrics bytes.Buffer
+	registry.WriteText(&metrics)
+	host := strings.TrimPrefix(server.URL, "http://")
+	for _, want := range []string{
+		`telegram_pr_notify_rate_limit_hits_total{host="` + host + `"} 1`,
+		`telegram_pr_notify_api_request_duration_seconds_count{host="` + host + `",method="sendMessage",code="429"} 1`,
+	} {
+		if !strings.Contains(metrics.String(), want) {
+			t.Errorf("metrics missing %q", want)
+		}
+	}
+}
@@ -1,45 +1,129 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"log/slog"
 	"os"
-	"regexp"
+	"strconv"
+	"strings"
 
 	"github.com/andoniaf/telegram-pr-notify/pkg/events"
+	"github.com/andoniaf/telegram-pr-notify/pkg/github"
+	"github.com/andoniaf/telegram-pr-notify/pkg/notify"
+	"github.com/andoniaf/telegram-pr-notify/pkg/state"
 	"github.com/andoniaf/telegram-pr-notify/pkg/telegram"
 	"github.com/andoniaf/telegram-pr-notify/pkg/templates"
+	"github.com/andoniaf/telegram-pr-notify/pkg/trace"
 )
 
-var chatIDPattern = regexp.MustCompile(`^-?\d+$`)
-
 func main() {
+	// Until run reads the debug and log_format inputs.
+	h, _ := newLogHandler(stdout, stderr, slog.LevelInfo, logText, false)
+	slog.SetDefault(slog.New(h))
+
 	if err := run(); err != nil {
-		fmt.Fprintf(os.Stderr, "::error::%v\n", err)
+		slog.Error(err.Error())
 		os.Exit(1)
 	}
-	fmt.Println("Notification sent successfully")
 }
 
 func run() error {
-	botToken := os.Getenv("INPUT_BOT_TOKEN")
-	chatID := os.Getenv("INPUT_CHAT_ID")
+	// Before reading any input, so the config file fills those left empty.
+	fromConfig, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	mode := os.Getenv("INPUT_MODE")
+	if len(os.Args) > 1 {
+		mode = os.Args[1]
+	}
+	backend := os.Getenv("INPUT_NOTIFIER")
 	topicID := os.Getenv("INPUT_TOPIC_ID")
 	customTemplate := os.Getenv("INPUT_CUSTOM_TEMPLATE")
 	eventPayload := os.Getenv("INPUT_EVENT_PAYLOAD")
+	ciNotify := os.Getenv("INPUT_CI_NOTIFY")
+	stateFile := os.Getenv("INPUT_STATE_FILE")
+	maxCommits := os.Getenv("INPUT_MAX_COMMITS")
+	categoryTopics := os.Getenv("INPUT_CATEGORY_TOPICS")
+	buttonsTemplate := os.Getenv("INPUT_BUTTONS")
+	buttonLayout := os.Getenv("INPUT_BUTTON_LAYOUT")
+	ticketPatterns := os.Getenv("INPUT_TICKET_PATTERNS")
+	githubToken := secretInput("INPUT_GITHUB_TOKEN")
+	maxFiles := os.Getenv("INPUT_MAX_FILES")
+	attachDiff := os.Getenv("INPUT_ATTACH_DIFF")
+	diffFile := os.Getenv("INPUT_DIFF_FILE")
+	diffMaxSize := os.Getenv("INPUT_DIFF_MAX_SIZE")
+	sendImages := os.Getenv("INPUT_SEND_IMAGES")
+	silent := os.Getenv("INPUT_SILENT")
+	protectContent := os.Getenv("INPUT_PROTECT_CONTENT")
+	linkPreviews := os.Getenv("INPUT_LINK_PREVIEW")
+	reactions := os.Getenv("INPUT_REACTIONS")
+	reactionsOnly := os.Getenv("INPUT_REACTIONS_ONLY")
+	autoTopicMode := os.Getenv("INPUT_AUTO_TOPIC")
+	autoTopicMinLines := os.Getenv("INPUT_AUTO_TOPIC_MIN_LINES")
+	autoTopicName := os.Getenv("INPUT_AUTO_TOPIC_NAME")
+	autoTopicColor := os.Getenv("INPUT_AUTO_TOPIC_COLOR")
+	failOnInput := os.Getenv("INPUT_FAIL_ON")
+	queueFile := os.Getenv("INPUT_QUEUE_FILE")
+	queueWorkers := os.Getenv("INPUT_QUEUE_WORKERS")
+	debug := os.Getenv("INPUT_DEBUG")
+	logFormat := os.Getenv("INPUT_LOG_FORMAT")
+	metricsFile := os.Getenv("INPUT_METRICS_FILE")
+	pins := parsePinRule(os.Getenv("INPUT_PIN_LABELS"), os.Getenv("INPUT_PIN_TEMPLATE"))
 
-	if botToken != "" {
-		fmt.Fprintf(os.Stdout, "::add-mask::%s\n", botToken)
+	if err := setupLogger(debug, logFormat); err != nil {
+		return err
 	}
-
-	if botToken == "" {
-		return fmt.Errorf("bot_token is required")
+	if metricsFile != "" {
+		defer func() {
+			if err := writeMetrics(metricsFile); err != nil {
+				slog.Warn("Could not write metrics", "error", err)
+			}
+		}()
+	}
+	if len(fromConfig) > 0 {
+		slog.Debug("Loaded settings from config file", "inputs", strings.Join(fromConfig, ","))
+	}
+	notifier, err := newNotifier(backend, topicID)
+	if err != nil {
+		return err
 	}
-	if chatID == "" {
-		return fmt.Errorf("chat_id is required")
+	failOn, err := parseFailOn(failOnInput)
+	if err != nil {
+		return err
+	}
+	layout, err := parseLayout(buttonLayout)
+	if err != nil {
+		return fmt.Errorf("parsing button_layout: %w", err)
+	}
+	silentRules, err := parseEventRules(silent, validBool)
+	if err != nil {
+		return fmt.Errorf("parsing silent: %w", err)
 	}
-	if !chatIDPattern.MatchString(chatID) {
-		return fmt.Errorf("chat_id must be a numeric value (e.g., -100123456789)")
+	protectRules, err := parseEventRules(protectContent, validBool)
+	if err != nil {
+		return fmt.Errorf("parsing protect_content: %w", err)
+	}
+	previewRules, err := parseEventRules(linkPreviews, validPreview)
+	if err != nil {
+		return fmt.Errorf("parsing link_preview: %w", err)
+	}
+
+	switch mode {
+	case "", "notify":
+	case "doctor":
+		return doctor(stdout, notifyTargets(notifier, backend, topicID), doctorConfig{
+			customTemplate:  customTemplate,
+			buttonsTemplate: buttonsTemplate,
+			pins:            pins,
+			autoTopic:       autoTopicMode != "",
+		})
+	default:
+		return fmt.Errorf("invalid mode %q (want notify or doctor)", mode)
 	}
+
 	if eventPayload == "" {
 		return fmt.Errorf("event_payload is required")
 	}
@@ -48,21 +132,289 @@ func run() error {
 	if err != nil {
 		return fmt.Errorf("parsing event: %w", err)
 	}
+	slog.Debug("Parsed event", "event", data.EventName, "action", data.Action, "repo", data.Repo.FullName, "actor", data.Actor.Login)
+	eventsReceived.Inc(data.EventName)
+
+	tracer := trace.FromEnv(serviceName)
+	span := tracer.Start("notify", nil)
+	span.SetAttr("event", data.EventName)
+	span.SetAttr("action", data.Action)
+	span.SetAttr("repository", data.Repo.FullName)
+	defer func() {
+		span.End(nil)
+		if err := tracer.Flush(); err != nil {
+			slog.Warn("Could not export trace", "error", notify.RedactError(err, secrets...))
+		}
+	}()
+
+	if ticketPatterns != "" {
+		patterns, err := parseTicketPatterns(ticketPatterns)
+		if err != nil {
+			return fmt.Errorf("parsing ticket_patterns: %w", err)
+		}
+		data.ExtractTickets(patterns)
+	}
+
+	if maxCommits != "" {
+		n, err := strconv.Atoi(maxCommits)
+		if err != nil || n < 1 {
+			return fmt.Errorf("max_commits must be a positive integer")
+		}
+		data.Push.Limit = n
+	}
+
+	fileLimit := github.DefaultMaxFiles
+	if maxFiles != "" {
+		n, err := strconv.Atoi(maxFiles)
+		if err != nil || n < 1 {
+			return fmt.Errorf("max_files must be a positive integer")
+		}
+		fileLimit = n
+	}
 
-	message, err := templates.Render(data, customTemplate)
+	withDiff := false
+	if attachDiff != "" {
+		if withDiff, err = strconv.ParseBool(attachDiff); err != nil {
+			return fmt.Errorf("attach_diff must be true or false")
+		}
+	}
+	withImages := false
+	if sendImages != "" {
+		if withImages, err = strconv.ParseBool(sendImages); err != nil {
+			return fmt.Errorf("send_images must be true or false")
+		}
+	}
+	reactionRules, err := parseReactions(reactions)
+	if err != nil {
+		return fmt.Errorf("parsing reactions: %w", err)
+	}
+	if reactionRules != nil && stateFile == "" {
+		return fmt.Errorf("reactions requires state_file")
+	}
+	topics, err := parseAutoTopic(autoTopicMode, autoTopicMinLines, autoTopicName, autoTopicColor)
+	if err != nil {
+		return err
+	}
+	if topics.enabled() && stateFile == "" {
+		return fmt.Errorf("auto_topic requires state_file")
+	}
+	onlyReact := false
+	if reactionsOnly != "" {
+		if onlyReact, err = strconv.ParseBool(reactionsOnly); err != nil {
+			return fmt.Errorf("reactions_only must be true or false")
+		}
+	}
+	diffLimit := int64(defaultDiffMaxSize)
+	if diffMaxSize != "" {
+		n, err := strconv.ParseInt(diffMaxSize, 10, 64)
+		if err != nil || n < 1 {
+			return fmt.Errorf("diff_max_size must be a positive integer")
+		}
+		diffLimit = n
+	}
+
+	if data.CI.Kind != "" && stateFile != "" {
+		store, err := state.Open(stateFile)
+		if err != nil {
+			return err
+		}
+		key := data.CI.Key(data.Repo.FullName)
+		data.CI.PreviousConclusion = store.LastConclusion(key)
+		store.SetConclusion(key, data.CI.Conclusion)
+		if err := store.Save(); err != nil {
+			return err
+		}
+	}
+
+	ok, err := data.ShouldNotify(ciNotify)
+	if err != nil {
+		return err
+	}
+	if data.CI.Kind != "" {
+		slog.Debug("CI filter", "ci_notify", ciNotify, "conclusion", data.CI.Conclusion, "previous", data.CI.PreviousConclusion, "notify", ok)
+	}
+	if !ok {
+		eventsFiltered.Inc(data.EventName, "ci_notify")
+		slog.Log(context.Background(), LevelNotice, "Notification skipped: CI conclusion does not match ci_notify",
+			"event", data.EventName, "conclusion", data.CI.Conclusion, "ci_notify", ciNotify)
+		return nil
+	}
+
+	_, multi := notifier.(*notify.Fanout)
+	if multi && (categoryTopics != "" || topics.enabled()) {
+		slog.Warn("category_topics and auto_topic are ignored when chat_id lists several chats")
+	}
+
+	if topics.enabled() && !multi {
+		tid, err := routeAutoTopic(notifier, stateFile, topics, data)
+		if err != nil {
+			slog.Warn("Could not use an automatic topic, posting to topic_id", "error", err)
+		} else if tid != "" {
+			slog.Debug("Routed to automatic topic", "mode", autoTopicMode, "topic", tid)
+			topicID = tid
+		}
+	}
+
+	if data.Discussion.Category.Name != "" && categoryTopics != "" {
+		routes, err := parseKeyValues(categoryTopics)
+		if err != nil {
+			return fmt.Errorf("parsing category_topics: %w", err)
+		}
+		if tid, ok := lookupFold(routes, data.Discussion.Category.Name); ok {
+			slog.Debug("Routed by discussion category", "category", data.Discussion.Category.Name, "topic", tid)
+			topicID = tid
+		}
+	}
+
+	if reactionRules != nil {
+		reacted, err := applyReaction(notifier, stateFile, reactionRules, data)
+		if err != nil {
+			slog.Warn("Could not set reaction", "error", err)
+		} else if reacted && onlyReact {
+			eventsFiltered.Inc(data.EventName, "reactions_only")
+			slog.Log(context.Background(), LevelNotice, "Reaction set, notification skipped", "reactions_only", true)
+			return nil
+		}
+	}
+
+	if customTemplate == "" && !templates.HasDefault(data) {
+		eventsFiltered.Inc(data.EventName, "no_template")
+		slog.Log(context.Background(), LevelNotice, "Notification skipped: no default template for this action",
+			"event", data.EventName, "action", data.Action)
+		return nil
+	}
+
+	var gh *github.Client
+	if githubToken != "" {
+		gh = github.NewClient(githubToken).WithHTTPClient(apiClient(githubTimeout))
+		if runID, err := strconv.ParseInt(os.Getenv("GITHUB_RUN_ID"), 10, 64); err == nil {
+			gh.WithRunID(runID)
+		}
+		if apiURL := os.Getenv("GITHUB_API_URL"); apiURL != "" {
+			gh.WithBaseURL(apiURL)
+		}
+		// Enrichment is best effort: the notification is still sent.
+		if err := github.Enrich(gh, data, fileLimit); err != nil {
+			slog.Warn("GitHub API enrichment incomplete", "error", err)
+		}
+	}
+
+	msg, err := templates.Build(data, customTemplate)
 	if err != nil {
 		return fmt.Errorf("rendering template: %w", err)
 	}
+	slog.Debug("Rendered template", "template", templates.Key(data, customTemplate))
+	if !multi {
+		// Multiple chats keep their own topics: topic IDs are per chat.
+		msg.ThreadID = topicID
+	}
+	msg.Silent = silentRules.enabled(data)
+	msg.Protected = protectRules.enabled(data)
+	msg.Preview = linkPreview(previewRules, data)
+	slog.Debug("Delivery options", "silent", msg.Silent, "protected", msg.Protected, "preview", msg.Preview.Size, "preview_url", msg.Preview.URL)
 
-	buttons := []telegram.Button{{Text: data.ButtonText(), URL: data.RelevantURL()}}
-	for _, issue := range data.LinkedIssues() {
-		buttons = append(buttons, telegram.Button{Text: issue.Text, URL: issue.URL})
+	buttons := msg.FlatButtons()
+	if buttonsTemplate != "" {
+		extra, err := templates.Buttons(data, buttonsTemplate)
+		if err != nil {
+			return err
+		}
+		buttons = append(buttons, extra...)
+	}
+	msg.Buttons = notify.Layout(buttons, layout)
+	if withImages {
+		msg.Images = data.Images()
 	}
 
-	client := telegram.NewClient(botToken, chatID, topicID)
-	if err := client.SendMessage(message, buttons); err != nil {
+	q, journal, err := newQueue(queueFile, queueWorkers)
+	if err != nil {
+		return err
+	}
+	if journal != nil {
+		defer journal.Close()
+		if n := q.Len(); n > 0 {
+			slog.Info("Retrying notifications queued by earlier runs", "jobs", n)
+		}
+	}
+	targets := notifyTargets(notifier, backend, topicID)
+	results, backlog, err := deliverQueued(q, targets, orderKey(data), msg, func(target string, n notify.Notifier, msg notify.Message) (string, error) {
+		return deliver(tracer, span, target, n, msg)
+	})
+	if err != nil {
+		return err
+	}
+	countDeliveries(data.EventName, results)
+	countDeliveries("backlog", backlog)
+	if _, err := notify.JoinResults(backlog); err != nil {
+		slog.Warn("Queued notifications failed again", "error", strings.ReplaceAll(err.Error(), "\n", "; "))
+	}
+	heading := fmt.Sprintf("Notification for %s %s", data.EventName, data.Action)
+	defer func() {
+		if err := writeStepSummary(os.Getenv("GITHUB_STEP_SUMMARY"), heading, append(backlog, results...)); err != nil {
+			slog.Warn(err.Error())
+		}
+	}()
+
+	if err := checkDeliveries(failOn, results); err != nil {
 		return fmt.Errorf("sending message: %w", err)
 	}
+	// With fail_on tolerating it, there is nothing to follow up on when
+	// every delivery failed.
+	messageID, _ := notify.JoinResults(results)
+	if !delivered(results) {
+		return nil
+	}
 
+	slog.Info("Notification sent successfully", "message_id", messageID)
+
+	if reactionRules != nil && data.EventName == "pull_request" && announceActions[data.Action] && messageID != "" {
+		if err := rememberAnnouncement(stateFile, data, messageID); err != nil {
+			slog.Warn("Could not record the PR announcement", "error", err)
+		}
+	}
+
+	if topics.enabled() && !multi {
+		if err := closeAutoTopic(notifier, stateFile, topics, data); err != nil {
+			slog.Warn("Could not close the PR topic", "error", err)
+		}
+	}
+
+	if pins.enabled() {
+		// Pinning is best effort: the notification is already out.
+		if err := syncPins(notifier, stateFile, pins, data, messageID, msg.Silent); err != nil {
+			slog.Warn("Could not update pinned messages", "error", err)
+		}
+	}
+
+	if withDiff && data.EventName == "pull_request" && diffActions[data.Action] {
+		tg, ok := notifier.(*telegram.Client)
+		if !ok {
+			slog.Warn("attach_diff is only supported by the telegram notifier with a single chat")
+			return nil
+		}
+		// The notification is already out, so a missing diff is not fatal.
+		id, err := sendDiff(tg, gh, data, msg, diffFile, diffLimit, messageID)
+		if err != nil {
+			slog.Warn("Could not attach diff", "error", err)
+		}
+		if id != "" || err != nil {
+			results = append(results, notify.Result{Target: destination(backend, topicID) + " (diff)", MessageID: id, Err: err})
+		}
+	}
 	return nil
 }
+
+// send delivers msg with n. Telegram rejects images it cannot fetch, so a
+// message with images that fails is retried as text: the text alone still
+// helps.
+func send(n notify.Notifier, msg notify.Message) (string, error) {
+	id, err := n.Send(msg)
+	if err != nil && len(msg.Images) > 0 {
+		slog.Warn("Could not send images, sending text only", "error", err)
+		msg.Images = nil
+		sendRetries.Inc("images")
+		slog.Debug("Retrying send", "attempt", 2, "images", false)
+		id, err = n.Send(msg)
+	}
+	return id, err
+}
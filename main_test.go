@@ -0,0 +1,34 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestRunSkipsActionsWithoutTemplate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request to %s", r.URL.Path)
+	}))
+	defer server.Close()
+
+	payload, err := os.ReadFile("testdata/deployment_status_in_progress.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	args := os.Args
+	os.Args = os.Args[:1]
+	defer func() { os.Args = args }()
+	t.Setenv("GITHUB_WORKSPACE", t.TempDir())
+	t.Setenv("INPUT_CONFIG_FILE", "")
+	t.Setenv("INPUT_MODE", "")
+	t.Setenv("INPUT_CUSTOM_TEMPLATE", "")
+	t.Setenv("INPUT_NOTIFIER", "slack")
+	t.Setenv("INPUT_SLACK_WEBHOOK_URL", server.URL)
+	t.Setenv("INPUT_EVENT_PAYLOAD", string(payload))
+
+	if err := run(); err != nil {
+		t.Errorf("run() error: %v", err)
+	}
+}
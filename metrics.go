@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/andoniaf/telegram-pr-notify/pkg/metrics"
+	"github.com/andoniaf/telegram-pr-notify/pkg/notify"
+	"github.com/andoniaf/telegram-pr-notify/pkg/trace"
+)
+
+// serviceName names the action in metrics and traces.
+const serviceName = "telegram-pr-notify"
+
+// The action's metrics, recorded as it runs and written to the
+// metrics_file input at the end of the run.
+var (
+	registry = metrics.NewRegistry()
+
+	eventsReceived = registry.Counter("telegram_pr_notify_events_received_total",
+		"Events received, by event type.", "event")
+	eventsFiltered = registry.Counter("telegram_pr_notify_events_filtered_total",
+		"Events not notified, by event type and the filter that skipped them.", "event", "reason")
+	deliveriesSent = registry.Counter("telegram_pr_notify_deliveries_sent_total",
+		"Notifications delivered, by event type and destination.", "event", "destination")
+	deliveriesFailed = registry.Counter("telegram_pr_notify_deliveries_failed_total",
+		"Notifications that failed, by event type and destination.", "event", "destination")
+	apiDuration = registry.Histogram("telegram_pr_notify_api_request_duration_seconds",
+		"API request latency, by host, API method and status code.", metrics.DefBuckets, "host", "method", "code")
+	sendRetries = registry.Counter("telegram_pr_notify_retries_total",
+		"Notifications sent again after a failure, by reason.", "reason")
+	rateLimitHits = registry.Counter("telegram_pr_notify_rate_limit_hits_total",
+		"API responses with status 429, by host.", "host")
+	queueDepth = registry.Gauge("telegram_pr_notify_queue_depth",
+		"Notifications waiting in the delivery queue.")
+)
+
+// writeMetrics writes the registry to path in the Prometheus text format.
+// The file is replaced in one rename, so a textfile collector never reads a
+// partial file.
+func writeMetrics(path string) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("creating metrics directory: %w", err)
+	}
+	f, err := os.CreateTemp(dir, ".metrics-*")
+	if err != nil {
+		return fmt.Errorf("writing metrics: %w", err)
+	}
+	defer os.Remove(f.Name())
+	if err := registry.WriteText(f); err != nil {
+		f.Close()
+		return fmt.Errorf("writing metrics: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("writing metrics: %w", err)
+	}
+	if err := os.Chmod(f.Name(), 0o644); err != nil {
+		return fmt.Errorf("writing metrics: %w", err)
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		return fmt.Errorf("writing metrics: %w", err)
+	}
+	return nil
+}
+
+// countDeliveries counts the results of sending a notification for event.
+func countDeliveries(event string, results []notify.Result) {
+	for _, r := range results {
+		if r.Err != nil {
+			deliveriesFailed.Inc(event, r.Target)
+		} else {
+			deliveriesSent.Inc(event, r.Target)
+		}
+	}
+}
+
+// deliver sends msg to the destination named target with n, recording a
+// trace span under parent.
+func deliver(tracer *trace.Tracer, parent *trace.Span, target string, n notify.Notifier, msg notify.Message) (string, error) {
+	span := tracer.Start("deliver", parent)
+	span.SetAttr("destination", target)
+	id, err := send(n, msg)
+	span.SetAttr("message_id", id)
+	span.End(notify.RedactError(err, secrets...))
+	return id, err
+}
+
+// apiMethod names the API call of req for metrics: the Bot API method for
+// Telegram, whose paths end with it, or the HTTP method otherwise, since
+// other APIs put IDs in their paths.
+func apiMethod(req *http.Request) string {
+	if strings.HasPrefix(req.URL.Path, "/bot") {
+		return path.Base(req.URL.Path)
+	}
+	return req.Method
+}
+
+func statusCode(resp *http.Response) string {
+	if resp == nil {
+		return "error"
+	}
+	return strconv.Itoa(resp.StatusCode)
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/andoniaf/telegram-pr-notify/pkg/notify"
+)
+
+func TestCountDeliveries(t *testing.T) {
+	countDeliveries("release", []notify.Result{
+		{Target: "chat -100123", MessageID: "42"},
+		{Target: "chat @team", Err: errors.New("chat not found")},
+	})
+
+	var buf bytes.Buffer
+	registry.WriteText(&buf)
+	for _, want := range []string{
+		`telegram_pr_notify_deliveries_sent_total{event="release",destination="chat -100123"} 1`,
+		`telegram_pr_notify_deliveries_failed_total{event="release",destination="chat @team"} 1`,
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("metrics missing %q", want)
+		}
+	}
+}
+
+func TestWriteMetrics(t *testing.T) {
+	eventsReceived.Inc("push")
+	path := filepath.Join(t.TempDir(), "metrics", "notify.prom")
+
+	if err := writeMetrics(path); err != nil {
+		t.Fatalf("writeMetrics() error: %v", err)
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `telegram_pr_notify_events_received_total{event="push"}`; !strings.Contains(string(b), want) {
+		t.Errorf("metrics file missing %q:\n%s", want, b)
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(path)); len(entries) != 1 {
+		t.Errorf("metrics directory has %d files, want only the metrics file", len(entries))
+	}
+}
+
+func TestAPIMethod(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"https://api.telegram.org/bot123:abc/sendMessage", "sendMessage"},
+		{"https://matrix.example.org/_matrix/client/v3/rooms/!r/send/m.room.message/1", "PUT"},
+	}
+	for _, tt := range tests {
+		if got := apiMethod(httptest.NewRequest("PUT", tt.url, nil)); got != tt.want {
+			t.Errorf("apiMethod(%s) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
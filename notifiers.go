@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/andoniaf/telegram-pr-notify/pkg/discord"
+	"github.com/andoniaf/telegram-pr-notify/pkg/matrix"
+	"github.com/andoniaf/telegram-pr-notify/pkg/notify"
+	"github.com/andoniaf/telegram-pr-notify/pkg/slack"
+	"github.com/andoniaf/telegram-pr-notify/pkg/teams"
+	"github.com/andoniaf/telegram-pr-notify/pkg/telegram"
+)
+
+// chatIDPattern matches numeric chat IDs and public @usernames.
+var chatIDPattern = regexp.MustCompile(`^(-?\d+|@[A-Za-z][A-Za-z0-9_]{3,31})$`)
+
+// maxParallelSends bounds concurrent sends to multiple chats.
+const maxParallelSends = 4
+
+// Timeouts for each request to a notifier's API and to the GitHub API.
+const (
+	notifierTimeout = 30 * time.Second
+	githubTimeout   = 10 * time.Second
+)
+
+// chatTarget is a chat_id entry, with its optional topic.
+type chatTarget struct {
+	chatID  string
+	topicID string
+}
+
+// parseChatTargets parses the chat_id input: a comma- or newline-separated
+// list of chat_id[:topic_id] entries. Entries without a topic use topicID.
+func parseChatTargets(input, topicID string) ([]chatTarget, error) {
+	var targets []chatTarget
+	for _, item := range strings.FieldsFunc(input, func(r rune) bool { return r == ',' || r == '\n' }) {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		chat, topic, ok := strings.Cut(item, ":")
+		chat, topic = strings.TrimSpace(chat), strings.TrimSpace(topic)
+		if !chatIDPattern.MatchString(chat) {
+			return nil, fmt.Errorf("invalid chat_id %q: must be a numeric value (e.g., -100123456789) or a public @username", chat)
+		}
+		if !ok {
+			topic = topicID
+		} else if _, err := strconv.Atoi(topic); err != nil {
+			return nil, fmt.Errorf("invalid topic %q for chat %s", topic, chat)
+		}
+		targets = append(targets, chatTarget{chatID: chat, topicID: topic})
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("chat_id is required")
+	}
+	return targets, nil
+}
+
+// newNotifier builds the backend selected by the notifier input from its
+// backend-specific inputs. Secrets are masked in the workflow log.
+func newNotifier(backend, topicID string) (notify.Notifier, error) {
+	switch backend {
+	case "", "telegram":
+		botToken := secretInput("INPUT_BOT_TOKEN")
+		chatID := os.Getenv("INPUT_CHAT_ID")
+		if botToken == "" {
+			return nil, fmt.Errorf("bot_token is required")
+		}
+		targets, err := parseChatTargets(chatID, topicID)
+		if err != nil {
+			return nil, err
+		}
+		if len(targets) == 1 {
+			return telegram.NewClient(botToken, targets[0].chatID, targets[0].topicID).WithHTTPClient(apiClient(notifierTimeout)), nil
+		}
+		clients := make([]notify.Target, len(targets))
+		for i, t := range targets {
+			name := t.chatID
+			if t.topicID != "" {
+				name += ":" + t.topicID
+			}
+			clients[i] = notify.Target{Name: "chat " + name, Notifier: telegram.NewClient(botToken, t.chatID, t.topicID).WithHTTPClient(apiClient(notifierTimeout))}
+		}
+		return notify.NewFanout(clients, maxParallelSends), nil
+
+	case "slack":
+		if webhookURL := secretInput("INPUT_SLACK_WEBHOOK_URL"); webhookURL != "" {
+			return slack.NewWebhookClient(webhookURL).WithHTTPClient(apiClient(notifierTimeout)), nil
+		}
+		token := secretInput("INPUT_SLACK_TOKEN")
+		channel := os.Getenv("INPUT_SLACK_CHANNEL")
+		if token == "" || channel == "" {
+			return nil, fmt.Errorf("slack requires slack_webhook_url, or slack_token and slack_channel")
+		}
+		return slack.NewClient(token, channel).WithHTTPClient(apiClient(notifierTimeout)), nil
+
+	case "discord":
+		webhookURL := secretInput("INPUT_DISCORD_WEBHOOK_URL")
+		if webhookURL == "" {
+			return nil, fmt.Errorf("discord requires discord_webhook_url")
+		}
+		return discord.NewClient(webhookURL, "").WithHTTPClient(apiClient(notifierTimeout)), nil
+
+	case "matrix":
+		homeserver := os.Getenv("INPUT_MATRIX_HOMESERVER")
+		token := secretInput("INPUT_MATRIX_ACCESS_TOKEN")
+		roomID := os.Getenv("INPUT_MATRIX_ROOM_ID")
+		if homeserver == "" || token == "" || roomID == "" {
+			return nil, fmt.Errorf("matrix requires matrix_homeserver, matrix_access_token and matrix_room_id")
+		}
+		return matrix.NewClient(homeserver, token, roomID).WithHTTPClient(apiClient(notifierTimeout)), nil
+
+	case "teams":
+		webhookURL := secretInput("INPUT_TEAMS_WEBHOOK_URL")
+		if webhookURL == "" {
+			return nil, fmt.Errorf("teams requires teams_webhook_url")
+		}
+		return teams.NewClient(webhookURL).WithHTTPClient(apiClient(notifierTimeout)), nil
+
+	default:
+		return nil, fmt.Errorf("unsupported notifier %q (want telegram, slack, discord, matrix or teams)", backend)
+	}
+}
+
+// secrets holds the values read with secretInput. stdout and stderr, which
+// the action prints through, remove them, including URL-escaped and
+// truncated forms that the runner's own masking misses.
+var (
+	secrets []string
+	stdout  = notify.NewRedactWriter(os.Stdout)
+	stderr  = notify.NewRedactWriter(os.Stderr)
+)
+
+// secretInput reads an input and masks its value in the workflow log.
+func secretInput(name string) string {
+	v := os.Getenv(name)
+	if v != "" {
+		fmt.Fprintf(os.Stdout, "::add-mask::%s\n", v)
+		secrets = append(secrets, v)
+		stdout.Add(v)
+		stderr.Add(v)
+	}
+	return v
+}
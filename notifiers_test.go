@@ -0,0 +1,27 @@
+package main
+
+import "testing"
+
+func TestParseChatTargets(t *testing.T) {
+	got, err := parseChatTargets("-100123, @my_channel\n-100456:7", "5")
+	if err != nil {
+		t.Fatalf("parseChatTargets() error: %v", err)
+	}
+	want := []chatTarget{{"-100123", "5"}, {"@my_channel", "5"}, {"-100456", "7"}}
+	if len(got) != len(want) {
+		t.Fatalf("parseChatTargets() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("target %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestParseChatTargetsInvalid(t *testing.T) {
+	for _, input := range []string{"", " , ", "my_channel", "@ab", "-100123:general", "https://t.me/x"} {
+		if _, err := parseChatTargets(input, ""); err == nil {
+			t.Errorf("parseChatTargets(%q) expected error", input)
+		}
+	}
+}
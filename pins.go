@@ -0,0 +1,125 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/andoniaf/telegram-pr-notify/pkg/events"
+	"github.com/andoniaf/telegram-pr-notify/pkg/notify"
+	"github.com/andoniaf/telegram-pr-notify/pkg/state"
+	"github.com/andoniaf/telegram-pr-notify/pkg/templates"
+)
+
+// pinner is implemented by notifiers that can pin messages.
+type pinner interface {
+	PinChatMessage(id string, silent bool) error
+	UnpinChatMessage(id string) error
+}
+
+// pinRule decides which PR announcements are pinned: PRs carrying one of
+// labels, or for which template renders "true".
+type pinRule struct {
+	labels   []string
+	template string
+}
+
+func parsePinRule(labels, template string) pinRule {
+	r := pinRule{template: strings.TrimSpace(template)}
+	for _, l := range strings.Split(labels, ",") {
+		if l = strings.TrimSpace(l); l != "" {
+			r.labels = append(r.labels, l)
+		}
+	}
+	return r
+}
+
+func (r pinRule) enabled() bool {
+	return len(r.labels) > 0 || r.template != ""
+}
+
+func (r pinRule) match(data *events.TemplateData) (bool, error) {
+	for _, l := range r.labels {
+		if data.PR.HasLabel(l) {
+			return true, nil
+		}
+	}
+	if r.template == "" {
+		return false, nil
+	}
+	return templates.Condition(data, r.template)
+}
+
+// pinKey identifies a PR's pinned announcement in the state file.
+func pinKey(data *events.TemplateData) string {
+	return fmt.Sprintf("pin:%s#%d", data.Repo.FullName, data.PR.Number)
+}
+
+// syncPins pins or unpins the notification messageID, remembering pinned
+// messages in stateFile when set.
+func syncPins(notifier notify.Notifier, stateFile string, rule pinRule, data *events.TemplateData, messageID string, silent bool) error {
+	p, ok := notifier.(pinner)
+	if !ok {
+		return errors.New("the notifier does not support pinning")
+	}
+	var store *state.Store
+	if stateFile != "" {
+		var err error
+		if store, err = state.Open(stateFile); err != nil {
+			return err
+		}
+	}
+	if err := updatePins(p, store, rule, data, messageID, silent); err != nil {
+		return err
+	}
+	if store != nil {
+		return store.Save()
+	}
+	return nil
+}
+
+// updatePins pins the announcement messageID of a PR matching rule, and
+// unpins the PR's announcement once it is closed. Pinned messages are
+// remembered in store; without one they are never unpinned.
+func updatePins(p pinner, store *state.Store, rule pinRule, data *events.TemplateData, messageID string, silent bool) error {
+	if data.EventName != "pull_request" {
+		return nil
+	}
+	key := pinKey(data)
+
+	switch {
+	case data.Action == "closed":
+		if store == nil {
+			return nil
+		}
+		id := store.Message(key)
+		if id == "" {
+			return nil
+		}
+		store.DeleteMessage(key)
+		if err := p.UnpinChatMessage(id); err != nil {
+			return fmt.Errorf("unpinning message %s: %w", id, err)
+		}
+	case announceActions[data.Action] && messageID != "":
+		ok, err := rule.match(data)
+		if err != nil || !ok {
+			return err
+		}
+		if err := p.PinChatMessage(messageID, silent); err != nil {
+			return fmt.Errorf("pinning message %s: %w", messageID, err)
+		}
+		if store == nil {
+			return nil
+		}
+		// A PR keeps one pinned announcement, e.g. when a draft that was
+		// pinned on open becomes ready for review.
+		if old := store.Message(key); old != "" && old != messageID {
+			if err := p.UnpinChatMessage(old); err != nil {
+				slog.Warn("Could not unpin previous message", "message_id", old, "error", err)
+			}
+		}
+		store.SetMessage(key, messageID)
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/andoniaf/telegram-pr-notify/pkg/events"
+	"github.com/andoniaf/telegram-pr-notify/pkg/state"
+)
+
+type fakePinner struct {
+	calls []string
+}
+
+func (f *fakePinner) PinChatMessage(id string, silent bool) error {
+	f.calls = append(f.calls, "pin:"+id)
+	return nil
+}
+
+func (f *fakePinner) UnpinChatMessage(id string) error {
+	f.calls = append(f.calls, "unpin:"+id)
+	return nil
+}
+
+func prEvent(action string, labels ...string) *events.TemplateData {
+	data := &events.TemplateData{
+		EventName: "pull_request",
+		Action:    action,
+		Repo:      events.Repository{FullName: "octocat/Hello-World"},
+		PR:        events.PullRequest{Number: 7},
+	}
+	for _, l := range labels {
+		data.PR.Labels = append(data.PR.Labels, events.Label{Name: l})
+	}
+	return data
+}
+
+func TestUpdatePins(t *testing.T) {
+	store, err := state.Open(filepath.Join(t.TempDir(), "state.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rule := parsePinRule("urgent, release", "")
+	p := &fakePinner{}
+
+	steps := []struct {
+		data      *events.TemplateData
+		messageID string
+	}{
+		{prEvent("opened"), "10"},
+		{prEvent("opened", "Urgent"), "11"},
+		{prEvent("ready_for_review", "urgent"), "12"},
+		{prEvent("closed", "urgent"), "13"},
+		{prEvent("closed", "urgent"), "14"},
+	}
+	for _, s := range steps {
+		if err := updatePins(p, store, rule, s.data, s.messageID, false); err != nil {
+			t.Fatalf("updatePins(%s) error: %v", s.data.Action, err)
+		}
+	}
+
+	if got := strings.Join(p.calls, ","); got != "pin:11,pin:12,unpin:11,unpin:12" {
+		t.Errorf("calls = %s", got)
+	}
+	if id := store.Message(pinKey(prEvent("closed"))); id != "" {
+		t.Errorf("stored message = %q, want none after close", id)
+	}
+}
+
+func TestPinRuleTemplate(t *testing.T) {
+	rule := parsePinRule("", `{{eq .PR.Base.Ref "release"}}`)
+	if !rule.enabled() {
+		t.Fatal("enabled() = false with a template")
+	}
+
+	data := prEvent("opened")
+	data.PR.Base.Ref = "release"
+	if ok, err := rule.match(data); err != nil || !ok {
+		t.Errorf("match() = %v, %v, want true", ok, err)
+	}
+	data.PR.Base.Ref = "main"
+	if ok, err := rule.match(data); err != nil || ok {
+		t.Errorf("match() = %v, %v, want false", ok, err)
+	}
+}
+
+func TestPinRuleDisabled(t *testing.T) {
+	if parsePinRule(" , ", "").enabled() {
+		t.Error("enabled() = true without labels or template")
+	}
+}
@@ -0,0 +1,372 @@
+// Package config parses the repository configuration file. It reads the
+// subset of YAML that the file needs: top-level keys holding a scalar, a
+// block scalar (| or >), a list of scalars, or a mapping of scalars.
+// Anchors, tags, flow collections and deeper nesting are rejected.
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Kind is the shape of an entry's value.
+type Kind int
+
+const (
+	Scalar Kind = iota
+	List
+	Map
+)
+
+func (k Kind) String() string {
+	switch k {
+	case List:
+		return "a list"
+	case Map:
+		return "a mapping"
+	}
+	return "a single value"
+}
+
+// Entry is one top-level key of the file.
+type Entry struct {
+	Key  string
+	Line int
+	Kind Kind
+
+	// Value is a scalar's value, or a block scalar's text.
+	Value string
+
+	// Items are a list's values.
+	Items []Item
+
+	// Pairs are a mapping's key-value pairs, in file order.
+	Pairs []Pair
+}
+
+// Item is a list value.
+type Item struct {
+	Value string
+	Line  int
+}
+
+// Pair is a mapping's key and value.
+type Pair struct {
+	Key, Value string
+	Line       int
+}
+
+// Error is a problem at a line of the file.
+type Error struct {
+	Line int
+	Msg  string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Msg)
+}
+
+// Errorf returns an Error at line.
+func Errorf(line int, format string, args ...any) *Error {
+	return &Error{Line: line, Msg: fmt.Sprintf(format, args...)}
+}
+
+type line struct {
+	num    int
+	indent int
+	text   string // without indentation
+	raw    string // with indentation, for block scalars
+}
+
+// Parse parses data into its top-level entries, in file order.
+func Parse(data []byte) ([]Entry, error) {
+	var lines []line
+	for i, raw := range strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n") {
+		text := strings.TrimLeft(raw, " ")
+		if strings.HasPrefix(text, "\t") {
+			return nil, Errorf(i+1, "tabs are not allowed for indentation")
+		}
+		lines = append(lines, line{num: i + 1, indent: len(raw) - len(text), text: strings.TrimRight(text, " \t"), raw: raw})
+	}
+
+	var entries []Entry
+	seen := make(map[string]int)
+	for i := 0; i < len(lines); i++ {
+		l := lines[i]
+		text := stripComment(l.text)
+		if text == "" || (len(entries) == 0 && text == "---") {
+			continue
+		}
+		if l.indent > 0 {
+			return nil, Errorf(l.num, "unexpected indentation")
+		}
+		key, rest, err := splitKey(text, l.num)
+		if err != nil {
+			return nil, err
+		}
+		if first, ok := seen[key]; ok {
+			return nil, Errorf(l.num, "duplicate key %q (first set on line %d)", key, first)
+		}
+		seen[key] = l.num
+
+		e := Entry{Key: key, Line: l.num}
+		var next int
+		switch {
+		case rest == "":
+			next, err = parseNested(lines, i+1, &e)
+		case strings.HasPrefix(rest, "|") || strings.HasPrefix(rest, ">"):
+			next, err = parseBlock(lines, i+1, rest, &e)
+		default:
+			e.Value, err = parseScalar(rest, l.num)
+			next = i + 1
+		}
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+		i = next - 1
+	}
+	return entries, nil
+}
+
+// splitKey splits "key: rest" into the key, which may be quoted, and its
+// trimmed value.
+func splitKey(text string, num int) (string, string, error) {
+	key, rest, ok := strings.Cut(text, ":")
+	if !ok || (rest != "" && rest[0] != ' ') {
+		return "", "", Errorf(num, "expected \"key: value\"")
+	}
+	key = strings.TrimSpace(key)
+	if strings.HasPrefix(key, "'") || strings.HasPrefix(key, "\"") {
+		// Quoted, such as the '*' selector of a rules mapping.
+		k, err := parseScalar(key, num)
+		if err != nil {
+			return "", "", err
+		}
+		return k, strings.TrimSpace(rest), nil
+	}
+	if key == "" || strings.ContainsAny(key, " \"'") {
+		return "", "", Errorf(num, "invalid key %q", key)
+	}
+	return key, strings.TrimSpace(rest), nil
+}
+
+// parseNested parses the indented list or mapping after a key with no
+// value, starting at lines[start], and returns the index after it. A key
+// with nothing indented under it is an empty scalar.
+func parseNested(lines []line, start int, e *Entry) (int, error) {
+	indent := -1
+	i := start
+	for ; i < len(lines); i++ {
+		l := lines[i]
+		text := stripComment(l.text)
+		if text == "" {
+			continue
+		}
+		if l.indent == 0 {
+			break
+		}
+		if indent < 0 {
+			indent = l.indent
+			if text == "-" || strings.HasPrefix(text, "- ") {
+				e.Kind = List
+			} else {
+				e.Kind = Map
+			}
+		}
+		if l.indent != indent {
+			return 0, Errorf(l.num, "nested values are not supported")
+		}
+
+		if e.Kind == List {
+			if text != "-" && !strings.HasPrefix(text, "- ") {
+				return 0, Errorf(l.num, "expected a list item (\"- value\")")
+			}
+			v, err := parseScalar(strings.TrimSpace(strings.TrimPrefix(text, "-")), l.num)
+			if err != nil {
+				return 0, err
+			}
+			e.Items = append(e.Items, Item{Value: v, Line: l.num})
+			continue
+		}
+
+		if strings.HasPrefix(text, "- ") {
+			return 0, Errorf(l.num, "expected \"key: value\", not a list item")
+		}
+		k, rest, err := splitKey(text, l.num)
+		if err != nil {
+			return 0, err
+		}
+		if rest == "" && i+1 < len(lines) && lines[i+1].indent > indent && stripComment(lines[i+1].text) != "" {
+			return 0, Errorf(lines[i+1].num, "nested values are not supported")
+		}
+		for _, p := range e.Pairs {
+			if p.Key == k {
+				return 0, Errorf(l.num, "duplicate key %q (first set on line %d)", k, p.Line)
+			}
+		}
+		v, err := parseScalar(rest, l.num)
+		if err != nil {
+			return 0, err
+		}
+		e.Pairs = append(e.Pairs, Pair{Key: k, Value: v, Line: l.num})
+	}
+	return i, nil
+}
+
+// parseBlock parses a literal (|) or folded (>) block scalar whose header
+// is header, starting at lines[start], and returns the index after it.
+// Trailing newlines are clipped to one, or stripped with "-".
+func parseBlock(lines []line, start int, header string, e *Entry) (int, error) {
+	num := lines[start-1].num
+	style, chomp := header[0], strings.TrimSpace(stripComment(header[1:]))
+	if chomp != "" && chomp != "-" {
+		return 0, Errorf(num, "unsupported block scalar header %q", header)
+	}
+
+	indent := -1
+	var body []string
+	i := start
+	for ; i < len(lines); i++ {
+		l := lines[i]
+		if l.text == "" {
+			body = append(body, "")
+			continue
+		}
+		if indent < 0 {
+			if l.indent == 0 {
+				break
+			}
+			indent = l.indent
+		}
+		if l.indent < indent {
+			break
+		}
+		body = append(body, l.raw[indent:])
+	}
+	// Blank lines after the block belong to what follows.
+	for len(body) > 0 && body[len(body)-1] == "" {
+		body = body[:len(body)-1]
+		i--
+	}
+
+	var text string
+	if style == '|' {
+		text = strings.Join(body, "\n")
+	} else {
+		text = fold(body)
+	}
+	if chomp != "-" && text != "" {
+		text += "\n"
+	}
+	e.Value = text
+	return i, nil
+}
+
+// fold joins a folded block's lines with spaces, keeping blank lines as
+// line breaks.
+func fold(body []string) string {
+	var b strings.Builder
+	for i, l := range body {
+		switch {
+		case l == "":
+			b.WriteString("\n")
+		case i > 0 && body[i-1] != "":
+			b.WriteString(" " + l)
+		default:
+			b.WriteString(l)
+		}
+	}
+	return b.String()
+}
+
+// parseScalar parses a plain, single-quoted or double-quoted scalar.
+func parseScalar(s string, num int) (string, error) {
+	s = stripComment(s)
+	if s == "" {
+		return "", nil
+	}
+	switch s[0] {
+	case '"':
+		return parseDoubleQuoted(s, num)
+	case '\'':
+		return parseSingleQuoted(s, num)
+	case '[', '{':
+		return "", Errorf(num, "flow collections are not supported; use an indented list or mapping")
+	case '&', '*', '!':
+		return "", Errorf(num, "anchors, aliases and tags are not supported")
+	case '|', '>':
+		return "", Errorf(num, "block scalars are only supported as top-level values")
+	}
+	return s, nil
+}
+
+func parseSingleQuoted(s string, num int) (string, error) {
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch {
+		case s[i] == '\'' && i+1 < len(s) && s[i+1] == '\'':
+			b.WriteByte('\'')
+			i++
+		case s[i] == '\'':
+			if i != len(s)-1 {
+				return "", Errorf(num, "unexpected text after quoted string")
+			}
+			return b.String(), nil
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return "", Errorf(num, "unterminated quoted string")
+}
+
+func parseDoubleQuoted(s string, num int) (string, error) {
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '"':
+			if i != len(s)-1 {
+				return "", Errorf(num, "unexpected text after quoted string")
+			}
+			return b.String(), nil
+		case c == '\\' && i+1 < len(s):
+			i++
+			switch s[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case '"', '\\', '/':
+				b.WriteByte(s[i])
+			default:
+				return "", Errorf(num, "unsupported escape \\%c", s[i])
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", Errorf(num, "unterminated quoted string")
+}
+
+// stripComment removes a # comment, which starts a line or follows a
+// space, outside of quotes.
+func stripComment(s string) string {
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' || c == '\'' && quote == '\'' && i+1 < len(s) && s[i+1] == '\'' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case (c == '"' || c == '\'') && (i == 0 || s[i-1] == ' ' || s[i-1] == '-'):
+			quote = c
+		case c == '#' && (i == 0 || s[i-1] == ' ' || s[i-1] == '\t'):
+			return strings.TrimRight(s[:i], " \t")
+		}
+	}
+	return s
+}
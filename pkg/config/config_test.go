@@ -0,0 +1,95 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	src := `---
+# Notifications for this repository.
+notifier: telegram
+chat_id:
+  - -100123   # main group
+  - "@releases"
+silent:
+  pull_request.synchronize: true
+  bot: 'true'
+custom_template: |
+  <b>{{.Repo.FullName}}</b>
+    #{{.PR.Number}}
+
+pin_template: >-
+  {{if .PR}}
+  pinned{{end}}
+buttons:
+log_format: "json" # comment
+ticket_patterns: 'JIRA=[A-Z]+-\d+ https://x/#{{.ID}}'
+`
+	entries, err := Parse([]byte(src))
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	want := []Entry{
+		{Key: "notifier", Line: 3, Value: "telegram"},
+		{Key: "chat_id", Line: 4, Kind: List, Items: []Item{{"-100123", 5}, {"@releases", 6}}},
+		{Key: "silent", Line: 7, Kind: Map, Pairs: []Pair{{"pull_request.synchronize", "true", 8}, {"bot", "true", 9}}},
+		{Key: "custom_template", Line: 10, Value: "<b>{{.Repo.FullName}}</b>\n  #{{.PR.Number}}\n"},
+		{Key: "pin_template", Line: 14, Value: "{{if .PR}} pinned{{end}}"},
+		{Key: "buttons", Line: 17},
+		{Key: "log_format", Line: 18, Value: "json"},
+		{Key: "ticket_patterns", Line: 19, Value: `JIRA=[A-Z]+-\d+ https://x/#{{.ID}}`},
+	}
+	if !reflect.DeepEqual(entries, want) {
+		t.Errorf("Parse() =\n%+v\nwant\n%+v", entries, want)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"missing colon", "notifier telegram", "line 1: expected \"key: value\""},
+		{"duplicate key", "a: 1\n\nb: 2\na: 3", "line 4: duplicate key \"a\" (first set on line 1)"},
+		{"indented key", "a: 1\n  b: 2", "line 2: unexpected indentation"},
+		{"tab", "a:\n\t- x", "line 2: tabs are not allowed for indentation"},
+		{"flow list", "chat_id: [1, 2]", "line 1: flow collections are not supported; use an indented list or mapping"},
+		{"alias", "a: *ref", "line 1: anchors, aliases and tags are not supported"},
+		{"unterminated", "a: \"x", "line 1: unterminated quoted string"},
+		{"trailing text", "a: 'x' y", "line 1: unexpected text after quoted string"},
+		{"nested list", "a:\n  - x\n    - y", "line 3: nested values are not supported"},
+		{"nested map", "a:\n  b:\n    c: 1", "line 3: nested values are not supported"},
+		{"mixed list", "a:\n  - x\n  b: y", "line 3: expected a list item (\"- value\")"},
+		{"duplicate pair", "a:\n  b: 1\n  b: 2", "line 3: duplicate key \"b\" (first set on line 2)"},
+		{"block header", "a: |2\n  x", "line 1: unsupported block scalar header \"|2\""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.src))
+			if err == nil || err.Error() != tt.want {
+				t.Errorf("Parse() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseScalar(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"plain text", "plain text"},
+		{"a#b # comment", "a#b"},
+		{`"a \"b\" \\ c\n"`, "a \"b\" \\ c\n"},
+		{`'it''s # not a comment'`, "it's # not a comment"},
+		{"@channel", "@channel"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		got, err := parseScalar(tt.in, 1)
+		if err != nil || got != tt.want {
+			t.Errorf("parseScalar(%q) = %q, %v, want %q", tt.in, got, err, tt.want)
+		}
+	}
+}
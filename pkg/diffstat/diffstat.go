@@ -0,0 +1,114 @@
+package diffstat
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/andoniaf/telegram-pr-notify/pkg/events"
+)
+
+const maxNameWidth = 40
+
+// Parse counts added and deleted lines per file in a git unified diff.
+func Parse(diff []byte) []events.ChangedFile {
+	var files []events.ChangedFile
+	var cur *events.ChangedFile
+	inHeader := false
+
+	sc := bufio.NewScanner(bytes.NewReader(diff))
+	sc.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	for sc.Scan() {
+		line := sc.Text()
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			name := line
+			if i := strings.LastIndex(line, " b/"); i >= 0 {
+				name = line[i+3:]
+			}
+			files = append(files, events.ChangedFile{Filename: name, Status: "modified"})
+			cur = &files[len(files)-1]
+			inHeader = true
+		case cur == nil:
+		case inHeader:
+			switch {
+			case strings.HasPrefix(line, "@@"):
+				inHeader = false
+			case strings.HasPrefix(line, "new file mode"):
+				cur.Status = "added"
+			case strings.HasPrefix(line, "deleted file mode"):
+				cur.Status = "removed"
+			case strings.HasPrefix(line, "rename from"):
+				cur.Status = "renamed"
+			}
+		case strings.HasPrefix(line, "+"):
+			cur.Additions++
+		case strings.HasPrefix(line, "-"):
+			cur.Deletions++
+		}
+	}
+	return files
+}
+
+// Summary returns a line such as "3 files changed, 10 insertions(+), 2 deletions(-)".
+func Summary(files []events.ChangedFile) string {
+	var add, del int
+	for _, f := range files {
+		add += f.Additions
+		del += f.Deletions
+	}
+	return fmt.Sprintf("%d file%s changed, %d insertion%s(+), %d deletion%s(-)",
+		len(files), plural(len(files)), add, plural(add), del, plural(del))
+}
+
+// Format renders one line per file with a +/- histogram scaled to width
+// characters, followed by the Summary line.
+func Format(files []events.ChangedFile, width int) string {
+	nameWidth, countWidth, most := 0, 1, 0
+	for _, f := range files {
+		nameWidth = max(nameWidth, min(len([]rune(f.Filename)), maxNameWidth))
+		total := f.Additions + f.Deletions
+		countWidth = max(countWidth, len(fmt.Sprint(total)))
+		most = max(most, total)
+	}
+
+	var b strings.Builder
+	for _, f := range files {
+		total := f.Additions + f.Deletions
+		add, del := f.Additions, f.Deletions
+		if most > width {
+			add = scale(f.Additions, most, width)
+			del = scale(f.Deletions, most, width)
+		}
+		fmt.Fprintf(&b, " %-*s | %*d %s%s\n", nameWidth, shorten(f.Filename, maxNameWidth), countWidth, total,
+			strings.Repeat("+", add), strings.Repeat("-", del))
+	}
+	b.WriteString(" " + Summary(files))
+	return b.String()
+}
+
+// scale maps n out of most onto width characters, keeping non-zero counts
+// visible.
+func scale(n, most, width int) int {
+	if n == 0 {
+		return 0
+	}
+	return max(1, n*width/most)
+}
+
+// shorten keeps the end of long paths, which identifies the file best.
+func shorten(name string, width int) string {
+	r := []rune(name)
+	if len(r) <= width {
+		return name
+	}
+	return "..." + string(r[len(r)-width+3:])
+}
+
+func plural(n int) string {
+	if n == 1 {
+		return ""
+	}
+	return "s"
+}
@@ -0,0 +1,92 @@
+package diffstat
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/andoniaf/telegram-pr-notify/pkg/events"
+)
+
+const sampleDiff = `diff --git a/main.go b/main.go
+index 1111111..2222222 100644
+--- a/main.go
++++ b/main.go
+@@ -1,3 +1,4 @@
+ package main
+-import "fmt"
++import (
++	"fmt"
++)
+--- not a header inside a hunk
+diff --git a/docs/new.md b/docs/new.md
+new file mode 100644
+--- /dev/null
++++ b/docs/new.md
+@@ -0,0 +1 @@
++hello
+diff --git a/old.txt b/old.txt
+deleted file mode 100644
+--- a/old.txt
++++ /dev/null
+@@ -1 +0,0 @@
+-bye
+`
+
+func TestParse(t *testing.T) {
+	got := Parse([]byte(sampleDiff))
+	want := []events.ChangedFile{
+		{Filename: "main.go", Status: "modified", Additions: 3, Deletions: 2},
+		{Filename: "docs/new.md", Status: "added", Additions: 1},
+		{Filename: "old.txt", Status: "removed", Deletions: 1},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Parse() = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Parse()[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+	if Parse(nil) != nil {
+		t.Error("Parse(nil) should return nil")
+	}
+}
+
+func TestSummary(t *testing.T) {
+	got := Summary([]events.ChangedFile{{Additions: 1}, {Additions: 2, Deletions: 3}})
+	if got != "2 files changed, 3 insertions(+), 3 deletions(-)" {
+		t.Errorf("Summary() = %q", got)
+	}
+	if got := Summary([]events.ChangedFile{{Additions: 1, Deletions: 1}}); got != "1 file changed, 1 insertion(+), 1 deletion(-)" {
+		t.Errorf("Summary() singular = %q", got)
+	}
+}
+
+func TestFormat(t *testing.T) {
+	files := []events.ChangedFile{
+		{Filename: "main.go", Additions: 3, Deletions: 1},
+		{Filename: "README.md", Additions: 1},
+	}
+	want := " main.go   | 4 +++-\n" +
+		" README.md | 1 +\n" +
+		" 2 files changed, 4 insertions(+), 1 deletion(-)"
+	if got := Format(files, 30); got != want {
+		t.Errorf("Format() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestFormatScalesAndShortens(t *testing.T) {
+	long := strings.Repeat("dir/", 20) + "file.go"
+	got := Format([]events.ChangedFile{{Filename: long, Additions: 300, Deletions: 100}, {Filename: "a", Additions: 1}}, 20)
+
+	lines := strings.Split(got, "\n")
+	if !strings.HasPrefix(lines[0], " ...") || !strings.Contains(lines[0], "file.go") {
+		t.Errorf("long name not shortened: %q", lines[0])
+	}
+	if bars := strings.Count(lines[0], "+") + strings.Count(lines[0], "-"); bars != 20 {
+		t.Errorf("histogram = %d chars, want 20: %q", bars, lines[0])
+	}
+	if !strings.HasSuffix(lines[1], " +") {
+		t.Errorf("small change should keep one bar: %q", lines[1])
+	}
+}
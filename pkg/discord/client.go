@@ -0,0 +1,215 @@
+package discord
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/andoniaf/telegram-pr-notify/pkg/notify"
+)
+
+const (
+	maxTitle           = 256
+	maxDescription     = 4096
+	maxFields          = 25
+	maxFieldName       = 256
+	maxFieldValue      = 1024
+	maxButtonLabel     = 80
+	maxButtonsPerRow   = 5
+	maxActionRows      = 5
+	embedColor         = 0x2f81f7
+	embedColorHigh     = 0xcf222e
+	embedColorLow      = 0x8c959f
+	componentActionRow = 1
+	componentButton    = 2
+	buttonStyleLink    = 5
+
+	// flagSuppressNotifications sends the message without a push or
+	// desktop notification.
+	flagSuppressNotifications = 1 << 12
+)
+
+var _ notify.Notifier = (*Client)(nil)
+
+// Client posts embeds with link buttons through a Discord webhook.
+type Client struct {
+	webhookURL string
+	threadID   string
+	httpClient *http.Client
+}
+
+type webhookRequest struct {
+	Embeds          []embed         `json:"embeds"`
+	Components      []component     `json:"components,omitempty"`
+	AllowedMentions allowedMentions `json:"allowed_mentions"`
+	Flags           int             `json:"flags,omitempty"`
+}
+
+type embed struct {
+	Title       string       `json:"title,omitempty"`
+	Description string       `json:"description,omitempty"`
+	Color       int          `json:"color"`
+	Fields      []embedField `json:"fields,omitempty"`
+}
+
+type embedField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline"`
+}
+
+type component struct {
+	Type       int         `json:"type"`
+	Style      int         `json:"style,omitempty"`
+	Label      string      `json:"label,omitempty"`
+	URL        string      `json:"url,omitempty"`
+	Components []component `json:"components,omitempty"`
+}
+
+// allowedMentions disables pings from user-generated content such as
+// "@everyone" in a PR title.
+type allowedMentions struct {
+	Parse []string `json:"parse"`
+}
+
+type messageResponse struct {
+	ID      string `json:"id"`
+	Message string `json:"message"`
+}
+
+// NewClient creates a client for the webhook. threadID optionally posts
+// into a thread or forum post of the webhook's channel.
+func NewClient(webhookURL, threadID string) *Client {
+	return &Client{
+		webhookURL: webhookURL,
+		threadID:   threadID,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// WithHTTPClient sets a custom http.Client, useful for testing.
+func (c *Client) WithHTTPClient(hc *http.Client) *Client {
+	c.httpClient = hc
+	return c
+}
+
+// Send posts msg and returns the Discord message ID.
+func (c *Client) Send(msg notify.Message) (string, error) {
+	return c.do(http.MethodPost, "", msg)
+}
+
+// Edit replaces a message previously sent through the same webhook.
+func (c *Client) Edit(id string, msg notify.Message) error {
+	_, err := c.do(http.MethodPatch, "/messages/"+url.PathEscape(id), msg)
+	return err
+}
+
+func (c *Client) do(method, path string, msg notify.Message) (string, error) {
+	body, err := json.Marshal(request(msg))
+	if err != nil {
+		return "", fmt.Errorf("marshaling request: %w", err)
+	}
+
+	query := url.Values{}
+	query.Set("wait", "true")
+	query.Set("with_components", "true")
+	thread := c.threadID
+	if msg.ThreadID != "" {
+		thread = msg.ThreadID
+	}
+	if thread != "" {
+		query.Set("thread_id", thread)
+	}
+
+	req, err := http.NewRequest(method, c.webhookURL+path+"?"+query.Encode(), bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("creating request: %w", notify.RedactError(err, c.webhookURL))
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("sending request to Discord webhook: %w", notify.RedactError(err, c.webhookURL))
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", fmt.Errorf("reading response: %w", err)
+	}
+
+	var msgResp messageResponse
+	_ = json.Unmarshal(respBody, &msgResp)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		detail := msgResp.Message
+		if detail == "" {
+			detail = strings.TrimSpace(string(respBody))
+		}
+		return "", &notify.StatusError{Code: resp.StatusCode, Err: fmt.Errorf("discord API error: %s: %s", resp.Status, detail)}
+	}
+	return msgResp.ID, nil
+}
+
+func request(msg notify.Message) webhookRequest {
+	e := embed{
+		Title:       notify.Truncate(notify.ToPlain(msg.Title), maxTitle, "…"),
+		Description: notify.Truncate(notify.ToMarkdown(msg.BodyHTML()), maxDescription, "…"),
+		Color:       embedColor,
+	}
+	switch msg.Priority {
+	case notify.PriorityHigh:
+		e.Color = embedColorHigh
+	case notify.PriorityLow:
+		e.Color = embedColorLow
+	}
+	for i, f := range msg.Fields {
+		if i == maxFields {
+			break
+		}
+		e.Fields = append(e.Fields, embedField{
+			Name:   notify.Truncate(f.Name, maxFieldName, "…"),
+			Value:  notify.Truncate(notify.ToMarkdown(f.Value), maxFieldValue, "…"),
+			Inline: true,
+		})
+	}
+
+	req := webhookRequest{
+		Embeds:          []embed{e},
+		AllowedMentions: allowedMentions{Parse: []string{}},
+	}
+	if msg.Silent {
+		req.Flags = flagSuppressNotifications
+	}
+
+	// Rows longer than Discord allows wrap onto the next action row.
+	for _, buttons := range msg.Buttons {
+		var row []component
+		for _, b := range buttons {
+			if len(req.Components) == maxActionRows {
+				return req
+			}
+			row = append(row, component{
+				Type:  componentButton,
+				Style: buttonStyleLink,
+				Label: notify.Truncate(b.Text, maxButtonLabel, "…"),
+				URL:   b.URL,
+			})
+			if len(row) == maxButtonsPerRow {
+				req.Components = append(req.Components, component{Type: componentActionRow, Components: row})
+				row = nil
+			}
+		}
+		if len(row) > 0 {
+			if len(req.Components) == maxActionRows {
+				return req
+			}
+			req.Components = append(req.Components, component{Type: componentActionRow, Components: row})
+		}
+	}
+	return req
+}
@@ -0,0 +1,142 @@
+package discord
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andoniaf/telegram-pr-notify/pkg/notify"
+)
+
+func TestSend(t *testing.T) {
+	var received webhookRequest
+	var query string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("method = %s, want POST", r.Method)
+		}
+		query = r.URL.RawQuery
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &received)
+		w.Write([]byte(`{"id": "1100000000000000001"}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL+"/api/webhooks/1/token", "").WithHTTPClient(server.Client())
+
+	id, err := client.Send(notify.Message{
+		Blocks:  []string{"<b>New Pull Request</b>\n<a href=\"https://github.com/pr/1\">#1</a> fix_bug"},
+		Buttons: [][]notify.Button{{{Text: "View PR", URL: "https://github.com/pr/1"}}},
+	})
+	if err != nil {
+		t.Fatalf("Send() error: %v", err)
+	}
+	if id != "1100000000000000001" {
+		t.Errorf("Send() id = %q", id)
+	}
+	if !strings.Contains(query, "wait=true") {
+		t.Errorf("query = %q, want wait=true", query)
+	}
+
+	if len(received.Embeds) != 1 {
+		t.Fatalf("len(Embeds) = %d, want 1", len(received.Embeds))
+	}
+	want := "**New Pull Request**\n[#1](https://github.com/pr/1) fix\\_bug"
+	if received.Embeds[0].Description != want {
+		t.Errorf("Description = %q, want %q", received.Embeds[0].Description, want)
+	}
+	if len(received.Components) != 1 || received.Components[0].Components[0].URL != "https://github.com/pr/1" {
+		t.Errorf("Components = %+v", received.Components)
+	}
+	if received.AllowedMentions.Parse == nil || len(received.AllowedMentions.Parse) != 0 {
+		t.Errorf("AllowedMentions = %+v, want mentions disabled", received.AllowedMentions)
+	}
+}
+
+func TestSendButtonsSplitIntoRows(t *testing.T) {
+	var buttons []notify.Button
+	for i := 0; i < 12; i++ {
+		buttons = append(buttons, notify.Button{Text: fmt.Sprintf("B%d", i), URL: "https://example.com"})
+	}
+	req := request(notify.Message{Blocks: []string{"x"}, Buttons: [][]notify.Button{buttons}})
+	if len(req.Components) != 3 {
+		t.Fatalf("rows = %d, want 3", len(req.Components))
+	}
+	if len(req.Components[2].Components) != 2 {
+		t.Errorf("last row = %d buttons, want 2", len(req.Components[2].Components))
+	}
+}
+
+func TestEditAndThread(t *testing.T) {
+	var method, path, query string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method, path, query = r.Method, r.URL.Path, r.URL.RawQuery
+		w.Write([]byte(`{"id": "42"}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL+"/api/webhooks/1/token", "777").WithHTTPClient(server.Client())
+
+	if err := client.Edit("42", notify.Message{Blocks: []string{"Updated"}}); err != nil {
+		t.Fatalf("Edit() error: %v", err)
+	}
+	if method != http.MethodPatch || path != "/api/webhooks/1/token/messages/42" {
+		t.Errorf("request = %s %s", method, path)
+	}
+	if !strings.Contains(query, "thread_id=777") {
+		t.Errorf("query = %q, want thread_id=777", query)
+	}
+}
+
+func TestSendAPIError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"message": "Unknown Webhook", "code": 10015}`))
+	}))
+	defer server.Close()
+
+	_, err := NewClient(server.URL, "").WithHTTPClient(server.Client()).Send(notify.Message{Blocks: []string{"x"}})
+	if err == nil || !strings.Contains(err.Error(), "Unknown Webhook") {
+		t.Errorf("Send() error = %v, want Unknown Webhook", err)
+	}
+}
+
+func TestRequestStructuredMessage(t *testing.T) {
+	req := request(notify.Message{
+		Title:    "Deploy &amp; verify",
+		Blocks:   []string{"body"},
+		Fields:   []notify.Field{{Name: "Env", Value: "prod"}},
+		Priority: notify.PriorityHigh,
+		Silent:   true,
+	})
+
+	e := req.Embeds[0]
+	if e.Title != "Deploy & verify" {
+		t.Errorf("Title = %q", e.Title)
+	}
+	if len(e.Fields) != 1 || e.Fields[0].Name != "Env" || !e.Fields[0].Inline {
+		t.Errorf("Fields = %+v", e.Fields)
+	}
+	if e.Color != embedColorHigh {
+		t.Errorf("Color = %#x, want %#x", e.Color, embedColorHigh)
+	}
+	if req.Flags != flagSuppressNotifications {
+		t.Errorf("Flags = %d, want suppress notifications", req.Flags)
+	}
+}
+
+func TestRequestKeepsButtonRows(t *testing.T) {
+	req := request(notify.Message{Buttons: [][]notify.Button{
+		{{Text: "A", URL: "https://a"}},
+		{{Text: "B", URL: "https://b"}, {Text: "C", URL: "https://c"}},
+	}})
+	if len(req.Components) != 2 || len(req.Components[1].Components) != 2 {
+		t.Errorf("Components = %+v, want rows [A] [B C]", req.Components)
+	}
+}
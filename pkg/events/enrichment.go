@@ -0,0 +1,119 @@
+package events
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ChangedFile is a file changed by a pull request.
+type ChangedFile struct {
+	Filename  string
+	Status    string
+	Additions int
+	Deletions int
+}
+
+// HiddenFiles returns how many changed files are not listed in Files.
+func (d *TemplateData) HiddenFiles() int {
+	if n := d.PR.ChangedFiles - len(d.Files); n > 0 && len(d.Files) > 0 {
+		return n
+	}
+	return 0
+}
+
+// CheckSummary counts the check runs reported for a commit.
+type CheckSummary struct {
+	Total   int
+	Passed  int
+	Failed  int
+	Pending int
+	Skipped int
+
+	// Uncounted is the number of check runs reported but not fetched, on
+	// commits with more than the API client reads.
+	Uncounted int
+}
+
+// Add counts a check run by its status and conclusion.
+func (c *CheckSummary) Add(status, conclusion string) {
+	c.Total++
+	switch {
+	case status != "completed":
+		c.Pending++
+	case failedConclusions[conclusion] || conclusion == "cancelled":
+		c.Failed++
+	case conclusion == "skipped":
+		c.Skipped++
+	default:
+		c.Passed++
+	}
+}
+
+// State returns "failure", "pending" or "success", or "" when no checks
+// were reported.
+func (c CheckSummary) State() string {
+	switch {
+	case c.Total == 0:
+		return ""
+	case c.Failed > 0:
+		return "failure"
+	case c.Pending > 0:
+		return "pending"
+	}
+	return "success"
+}
+
+// Badge returns a one-line status such as "❌ 1 of 5 checks failed", noting
+// the checks that were not counted.
+func (c CheckSummary) Badge() string {
+	var badge string
+	switch c.State() {
+	case "failure":
+		badge = fmt.Sprintf("❌ %d of %d checks failed", c.Failed, c.Total)
+	case "pending":
+		badge = fmt.Sprintf("⏳ %d of %d checks pending", c.Pending, c.Total)
+	case "success":
+		badge = fmt.Sprintf("✅ %d/%d checks passed", c.Total-c.Skipped, c.Total-c.Skipped)
+	default:
+		return ""
+	}
+	if c.Uncounted > 0 {
+		badge += fmt.Sprintf(" (%d more not counted)", c.Uncounted)
+	}
+	return badge
+}
+
+// ReviewSummary counts each reviewer's latest review state, plus review
+// requests that have not been answered yet.
+type ReviewSummary struct {
+	Approved         int
+	ChangesRequested int
+	Commented        int
+	Pending          int
+}
+
+// Text returns a summary such as "✅ 2 approvals, ⏳ 1 pending", or "" when
+// there are no reviews or requests.
+func (r ReviewSummary) Text() string {
+	var parts []string
+	if r.Approved > 0 {
+		parts = append(parts, fmt.Sprintf("✅ %d approval%s", r.Approved, plural(r.Approved)))
+	}
+	if r.ChangesRequested > 0 {
+		parts = append(parts, fmt.Sprintf("🔴 %d change request%s", r.ChangesRequested, plural(r.ChangesRequested)))
+	}
+	if r.Commented > 0 {
+		parts = append(parts, fmt.Sprintf("💬 %d comment%s", r.Commented, plural(r.Commented)))
+	}
+	if r.Pending > 0 {
+		parts = append(parts, fmt.Sprintf("⏳ %d pending", r.Pending))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func plural(n int) string {
+	if n == 1 {
+		return ""
+	}
+	return "s"
+}
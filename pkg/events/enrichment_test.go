@@ -0,0 +1,53 @@
+package events
+
+import "testing"
+
+func TestCheckSummaryBadge(t *testing.T) {
+	tests := []struct {
+		name  string
+		runs  [][2]string
+		state string
+		badge string
+	}{
+		{"none", nil, "", ""},
+		{"all passed", [][2]string{{"completed", "success"}, {"completed", "neutral"}, {"completed", "skipped"}}, "success", "✅ 2/2 checks passed"},
+		{"pending", [][2]string{{"completed", "success"}, {"queued", ""}}, "pending", "⏳ 1 of 2 checks pending"},
+		{"failed wins over pending", [][2]string{{"completed", "cancelled"}, {"in_progress", ""}}, "failure", "❌ 1 of 2 checks failed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var c CheckSummary
+			for _, r := range tt.runs {
+				c.Add(r[0], r[1])
+			}
+			if got := c.State(); got != tt.state {
+				t.Errorf("State() = %q, want %q", got, tt.state)
+			}
+			if got := c.Badge(); got != tt.badge {
+				t.Errorf("Badge() = %q, want %q", got, tt.badge)
+			}
+		})
+	}
+}
+
+func TestReviewSummaryText(t *testing.T) {
+	if got := (ReviewSummary{}).Text(); got != "" {
+		t.Errorf("Text() = %q, want empty", got)
+	}
+	r := ReviewSummary{Approved: 1, ChangesRequested: 2, Pending: 1}
+	if got := r.Text(); got != "✅ 1 approval, 🔴 2 change requests, ⏳ 1 pending" {
+		t.Errorf("Text() = %q", got)
+	}
+}
+
+func TestHiddenFiles(t *testing.T) {
+	d := TemplateData{PR: PullRequest{ChangedFiles: 12}, Files: make([]ChangedFile, 10)}
+	if got := d.HiddenFiles(); got != 2 {
+		t.Errorf("HiddenFiles() = %d, want 2", got)
+	}
+	d.Files = nil
+	if got := d.HiddenFiles(); got != 0 {
+		t.Errorf("HiddenFiles() without files = %d, want 0", got)
+	}
+}
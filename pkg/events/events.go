@@ -4,7 +4,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"regexp"
+	"sort"
 	"strconv"
+	"strings"
+	"time"
 )
 
 // GitHubContext represents the top-level structure of toJSON(github).
@@ -16,6 +19,12 @@ type GitHubContext struct {
 type User struct {
 	Login   string `json:"login"`
 	HTMLURL string `json:"html_url"`
+	Type    string `json:"type"`
+}
+
+// IsBot returns true for GitHub Apps and bot accounts such as dependabot.
+func (u User) IsBot() bool {
+	return u.Type == "Bot" || strings.HasSuffix(u.Login, "[bot]")
 }
 
 type Repository struct {
@@ -24,19 +33,50 @@ type Repository struct {
 }
 
 type PullRequest struct {
-	Number  int    `json:"number"`
-	Title   string `json:"title"`
+	Number       int     `json:"number"`
+	Title        string  `json:"title"`
+	HTMLURL      string  `json:"html_url"`
+	Body         string  `json:"body"`
+	Draft        bool    `json:"draft"`
+	Merged       bool    `json:"merged"`
+	User         User    `json:"user"`
+	Base         Branch  `json:"base"`
+	Head         Branch  `json:"head"`
+	Additions    int     `json:"additions"`
+	Deletions    int     `json:"deletions"`
+	ChangedFiles int     `json:"changed_files"`
+	Labels       []Label `json:"labels"`
+}
+
+type Label struct {
+	Name string `json:"name"`
+}
+
+// HasLabel returns true if the PR carries a label named name, ignoring case.
+func (p PullRequest) HasLabel(name string) bool {
+	for _, l := range p.Labels {
+		if strings.EqualFold(l.Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// ReviewRequest is the reviewer, or the team, asked to review a PR in a
+// pull_request review_requested event.
+type ReviewRequest struct {
+	Reviewer User `json:"requested_reviewer"`
+	Team     Team `json:"requested_team"`
+}
+
+type Team struct {
+	Name    string `json:"name"`
 	HTMLURL string `json:"html_url"`
-	Body    string `json:"body"`
-	Draft   bool   `json:"draft"`
-	Merged  bool   `json:"merged"`
-	User    User   `json:"user"`
-	Base    Branch `json:"base"`
-	Head    Branch `json:"head"`
 }
 
 type Branch struct {
 	Ref string `json:"ref"`
+	SHA string `json:"sha"`
 }
 
 type Review struct {
@@ -55,9 +95,148 @@ type Comment struct {
 
 type pullRequestEvent struct {
 	Action      string      `json:"action"`
+	Reason      string      `json:"reason"`
 	PullRequest PullRequest `json:"pull_request"`
 	Repository  Repository  `json:"repository"`
 	Sender      User        `json:"sender"`
+	ReviewRequest
+}
+
+type ciPullRequest struct {
+	Number int    `json:"number"`
+	Head   Branch `json:"head"`
+	Base   Branch `json:"base"`
+}
+
+type workflowRunEvent struct {
+	Action      string `json:"action"`
+	WorkflowRun struct {
+		ID           int64           `json:"id"`
+		Name         string          `json:"name"`
+		Status       string          `json:"status"`
+		Conclusion   string          `json:"conclusion"`
+		HTMLURL      string          `json:"html_url"`
+		HeadBranch   string          `json:"head_branch"`
+		HeadSHA      string          `json:"head_sha"`
+		RunNumber    int             `json:"run_number"`
+		RunStartedAt time.Time       `json:"run_started_at"`
+		UpdatedAt    time.Time       `json:"updated_at"`
+		PullRequests []ciPullRequest `json:"pull_requests"`
+	} `json:"workflow_run"`
+	Repository Repository `json:"repository"`
+	Sender     User       `json:"sender"`
+}
+
+type checkSuiteEvent struct {
+	Action     string `json:"action"`
+	CheckSuite struct {
+		ID           int64           `json:"id"`
+		Status       string          `json:"status"`
+		Conclusion   string          `json:"conclusion"`
+		HeadBranch   string          `json:"head_branch"`
+		HeadSHA      string          `json:"head_sha"`
+		CreatedAt    time.Time       `json:"created_at"`
+		UpdatedAt    time.Time       `json:"updated_at"`
+		PullRequests []ciPullRequest `json:"pull_requests"`
+		App          struct {
+			Name string `json:"name"`
+		} `json:"app"`
+	} `json:"check_suite"`
+	Repository Repository `json:"repository"`
+	Sender     User       `json:"sender"`
+}
+
+type checkRunEvent struct {
+	Action   string `json:"action"`
+	CheckRun struct {
+		Name         string          `json:"name"`
+		Status       string          `json:"status"`
+		Conclusion   string          `json:"conclusion"`
+		HTMLURL      string          `json:"html_url"`
+		HeadSHA      string          `json:"head_sha"`
+		StartedAt    time.Time       `json:"started_at"`
+		CompletedAt  time.Time       `json:"completed_at"`
+		PullRequests []ciPullRequest `json:"pull_requests"`
+		CheckSuite   struct {
+			HeadBranch string `json:"head_branch"`
+		} `json:"check_suite"`
+	} `json:"check_run"`
+	Repository Repository `json:"repository"`
+	Sender     User       `json:"sender"`
+}
+
+type pushEvent struct {
+	Ref        string     `json:"ref"`
+	Before     string     `json:"before"`
+	After      string     `json:"after"`
+	Created    bool       `json:"created"`
+	Deleted    bool       `json:"deleted"`
+	Forced     bool       `json:"forced"`
+	Compare    string     `json:"compare"`
+	Commits    []Commit   `json:"commits"`
+	Repository Repository `json:"repository"`
+	Sender     User       `json:"sender"`
+}
+
+type releaseEvent struct {
+	Action     string     `json:"action"`
+	Release    Release    `json:"release"`
+	Repository Repository `json:"repository"`
+	Sender     User       `json:"sender"`
+}
+
+type refEvent struct {
+	Ref        string     `json:"ref"`
+	RefType    string     `json:"ref_type"`
+	Repository Repository `json:"repository"`
+	Sender     User       `json:"sender"`
+}
+
+type deploymentStatusEvent struct {
+	Action           string `json:"action"`
+	DeploymentStatus struct {
+		State          string `json:"state"`
+		Environment    string `json:"environment"`
+		EnvironmentURL string `json:"environment_url"`
+		TargetURL      string `json:"target_url"`
+		LogURL         string `json:"log_url"`
+		Description    string `json:"description"`
+	} `json:"deployment_status"`
+	Deployment struct {
+		Environment string `json:"environment"`
+		Ref         string `json:"ref"`
+		SHA         string `json:"sha"`
+	} `json:"deployment"`
+	Repository Repository `json:"repository"`
+	Sender     User       `json:"sender"`
+}
+
+type discussionEvent struct {
+	Action     string     `json:"action"`
+	Discussion Discussion `json:"discussion"`
+	Answer     *Comment   `json:"answer"`
+	Comment    *Comment   `json:"comment"`
+	Changes    struct {
+		Category struct {
+			From DiscussionCategory `json:"from"`
+		} `json:"category"`
+	} `json:"changes"`
+	Repository Repository `json:"repository"`
+	Sender     User       `json:"sender"`
+}
+
+type mergeGroupEvent struct {
+	Action     string `json:"action"`
+	Reason     string `json:"reason"`
+	MergeGroup struct {
+		HeadSHA    string `json:"head_sha"`
+		HeadRef    string `json:"head_ref"`
+		BaseSHA    string `json:"base_sha"`
+		BaseRef    string `json:"base_ref"`
+		HeadCommit Commit `json:"head_commit"`
+	} `json:"merge_group"`
+	Repository Repository `json:"repository"`
+	Sender     User       `json:"sender"`
 }
 
 type reviewEvent struct {
@@ -85,9 +264,345 @@ type TemplateData struct {
 	PR        PullRequest
 	Review    Review
 	Comment   Comment
+	CI        CIRun
+	Push      Push
+
+	// ReviewRequest is set for pull_request review_requested events.
+	ReviewRequest ReviewRequest
+
+	Release    Release
+	Ref        Ref
+	Deployment Deployment
+	Discussion Discussion
+	MergeGroup MergeGroup
+
+	// ExternalTickets holds tracker references found by ExtractTickets.
+	ExternalTickets []ExternalTicket
+
+	// Files, Checks and ReviewSummary are not part of the payload. They are
+	// fetched from the GitHub API when a token is configured.
+	Files         []ChangedFile
+	Checks        CheckSummary
+	ReviewSummary ReviewSummary
 }
 
-var linkedIssuePattern = regexp.MustCompile(`(?i)\b(?:close[sd]?|fix(?:e[sd])?|resolve[sd]?|refs?):?\s+#(\d+)\b`)
+// MergeGroup describes a merge queue group, or the queue context of a
+// pull_request enqueued/dequeued event.
+type MergeGroup struct {
+	HeadSHA    string
+	HeadRef    string
+	BaseSHA    string
+	BaseRef    string
+	HeadCommit Commit
+
+	// Reason explains why a group was destroyed (merged, invalidated,
+	// dequeued) or why a PR was dequeued (CHECKS_FAILED, MERGE_CONFLICT, ...).
+	Reason string
+}
+
+// BaseBranch returns the queue's target branch name.
+func (g MergeGroup) BaseBranch() string {
+	return strings.TrimPrefix(g.BaseRef, "refs/heads/")
+}
+
+// ReasonText returns Reason in a human-readable form.
+func (g MergeGroup) ReasonText() string {
+	return strings.ReplaceAll(strings.ToLower(g.Reason), "_", " ")
+}
+
+// mergeQueueRefPattern matches merge queue branches such as
+// refs/heads/gh-readonly-queue/main/pr-42-<sha>.
+var mergeQueueRefPattern = regexp.MustCompile(`gh-readonly-queue/.+/pr-(\d+)-[0-9a-f]+$`)
+
+// DiscussionCategory is the category a discussion is filed under.
+type DiscussionCategory struct {
+	Name         string `json:"name"`
+	Emoji        string `json:"emoji"`
+	IsAnswerable bool   `json:"is_answerable"`
+}
+
+// Discussion is a GitHub Discussions thread.
+type Discussion struct {
+	Number        int                `json:"number"`
+	Title         string             `json:"title"`
+	Body          string             `json:"body"`
+	HTMLURL       string             `json:"html_url"`
+	AnswerHTMLURL string             `json:"answer_html_url"`
+	Category      DiscussionCategory `json:"category"`
+	User          User               `json:"user"`
+
+	// PreviousCategory is set for category_changed events.
+	PreviousCategory DiscussionCategory `json:"-"`
+}
+
+// Asset is a file attached to a release.
+type Asset struct {
+	Name               string `json:"name"`
+	Size               int64  `json:"size"`
+	BrowserDownloadURL string `json:"browser_download_url"`
+}
+
+// Release is a GitHub release.
+type Release struct {
+	TagName    string  `json:"tag_name"`
+	Name       string  `json:"name"`
+	Body       string  `json:"body"`
+	HTMLURL    string  `json:"html_url"`
+	Draft      bool    `json:"draft"`
+	Prerelease bool    `json:"prerelease"`
+	ZipballURL string  `json:"zipball_url"`
+	Author     User    `json:"author"`
+	Assets     []Asset `json:"assets"`
+}
+
+// DisplayName returns the release name, falling back to the tag.
+func (r Release) DisplayName() string {
+	if r.Name != "" {
+		return r.Name
+	}
+	return r.TagName
+}
+
+// DownloadURL returns the first asset's download URL, or the source
+// archive when the release has no assets.
+func (r Release) DownloadURL() string {
+	if len(r.Assets) > 0 {
+		return r.Assets[0].BrowserDownloadURL
+	}
+	return r.ZipballURL
+}
+
+// Ref is the branch or tag of a create or delete event.
+type Ref struct {
+	Name string
+	Type string
+}
+
+// Deployment describes a deployment_status event.
+type Deployment struct {
+	Environment    string
+	State          string
+	EnvironmentURL string
+	LogURL         string
+	Description    string
+	Ref            string
+	SHA            string
+}
+
+// ShortSHA returns the abbreviated deployed commit SHA.
+func (d Deployment) ShortSHA() string {
+	return shortSHA(d.SHA)
+}
+
+// CommitAuthor is the git author of a pushed commit.
+type CommitAuthor struct {
+	Name     string `json:"name"`
+	Email    string `json:"email"`
+	Username string `json:"username"`
+}
+
+// Commit is a single commit from a push event.
+type Commit struct {
+	ID      string       `json:"id"`
+	Message string       `json:"message"`
+	URL     string       `json:"url"`
+	Author  CommitAuthor `json:"author"`
+}
+
+// ShortID returns the abbreviated commit SHA.
+func (c Commit) ShortID() string {
+	return shortSHA(c.ID)
+}
+
+// Title returns the first line of the commit message.
+func (c Commit) Title() string {
+	title, _, _ := strings.Cut(c.Message, "\n")
+	return strings.TrimSpace(title)
+}
+
+// DefaultMaxCommits is the number of commits listed when Push.Limit is unset.
+const DefaultMaxCommits = 5
+
+// Push describes a push event.
+type Push struct {
+	Ref        string
+	Before     string
+	After      string
+	Created    bool
+	Deleted    bool
+	Forced     bool
+	CompareURL string
+	Commits    []Commit
+
+	// Limit caps the number of commits returned by ShownCommits.
+	// Zero means DefaultMaxCommits.
+	Limit int
+}
+
+// RefName returns the branch or tag name without the refs/heads/ or
+// refs/tags/ prefix.
+func (p Push) RefName() string {
+	if name, ok := strings.CutPrefix(p.Ref, "refs/heads/"); ok {
+		return name
+	}
+	if name, ok := strings.CutPrefix(p.Ref, "refs/tags/"); ok {
+		return name
+	}
+	return p.Ref
+}
+
+// ShortBefore returns the abbreviated SHA the ref pointed to before the push.
+func (p Push) ShortBefore() string {
+	return shortSHA(p.Before)
+}
+
+// ShortAfter returns the abbreviated SHA the ref points to after the push.
+func (p Push) ShortAfter() string {
+	return shortSHA(p.After)
+}
+
+// IsTag returns true if the push targets a tag.
+func (p Push) IsTag() bool {
+	return strings.HasPrefix(p.Ref, "refs/tags/")
+}
+
+func (p Push) limit() int {
+	if p.Limit <= 0 {
+		return DefaultMaxCommits
+	}
+	return p.Limit
+}
+
+// ShownCommits returns the first Limit commits.
+func (p Push) ShownCommits() []Commit {
+	if len(p.Commits) <= p.limit() {
+		return p.Commits
+	}
+	return p.Commits[:p.limit()]
+}
+
+// HiddenCommits returns how many commits are left out by ShownCommits.
+func (p Push) HiddenCommits() int {
+	if len(p.Commits) <= p.limit() {
+		return 0
+	}
+	return len(p.Commits) - p.limit()
+}
+
+// CIRun is the normalized view of a workflow_run, check_suite or check_run event.
+type CIRun struct {
+	Kind        string
+	ID          int64
+	Name        string
+	Status      string
+	Conclusion  string
+	HTMLURL     string
+	HeadBranch  string
+	HeadSHA     string
+	RunNumber   int
+	StartedAt   time.Time
+	CompletedAt time.Time
+
+	// FailedJobs names the failing jobs. A failed check_run is its own job.
+	// workflow_run payloads do not list jobs, so theirs are only filled in
+	// by GitHub API enrichment, which needs github_token.
+	FailedJobs []string
+
+	// PullRequests lists every PR associated with the run. The first one is
+	// also exposed as TemplateData.PR. Runs from forks have none.
+	PullRequests []PullRequest
+
+	// PreviousConclusion is the conclusion of the previous run of the same
+	// workflow on the same branch, when known. It is not part of the payload
+	// and is filled in from the state file.
+	PreviousConclusion string
+}
+
+var failedConclusions = map[string]bool{
+	"failure":         true,
+	"timed_out":       true,
+	"action_required": true,
+	"startup_failure": true,
+}
+
+// Failed returns true if the run concluded with a failing conclusion.
+func (r CIRun) Failed() bool {
+	return failedConclusions[r.Conclusion]
+}
+
+// Succeeded returns true if the run concluded successfully.
+func (r CIRun) Succeeded() bool {
+	return r.Conclusion == "success"
+}
+
+// Recovered returns true if the run succeeded after a failing previous run.
+func (r CIRun) Recovered() bool {
+	return r.Succeeded() && failedConclusions[r.PreviousConclusion]
+}
+
+// Duration returns the wall-clock time of the run, rounded to seconds.
+func (r CIRun) Duration() time.Duration {
+	if r.StartedAt.IsZero() || r.CompletedAt.Before(r.StartedAt) {
+		return 0
+	}
+	return r.CompletedAt.Sub(r.StartedAt).Round(time.Second)
+}
+
+// ShortSHA returns the abbreviated head commit SHA.
+func (r CIRun) ShortSHA() string {
+	return shortSHA(r.HeadSHA)
+}
+
+func shortSHA(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+	return sha
+}
+
+// Key identifies the run's workflow and branch, used to look up the
+// previous conclusion for recovery detection.
+func (r CIRun) Key(repo string) string {
+	return repo + ":" + r.Kind + ":" + r.Name + ":" + r.HeadBranch
+}
+
+// CI notification modes accepted by ShouldNotify.
+const (
+	CINotifyAll                = "all"
+	CINotifyFailure            = "failure"
+	CINotifyFailureAndRecovery = "failure_and_recovery"
+)
+
+// ShouldNotify reports whether a CI event passes the given notification mode.
+// Non-CI events always pass.
+func (d *TemplateData) ShouldNotify(mode string) (bool, error) {
+	if d.CI.Kind == "" {
+		return true, nil
+	}
+	switch mode {
+	case "", CINotifyAll:
+		return true, nil
+	case CINotifyFailure:
+		return d.CI.Failed(), nil
+	case CINotifyFailureAndRecovery:
+		return d.CI.Failed() || d.CI.Recovered(), nil
+	default:
+		return false, fmt.Errorf("invalid ci_notify %q (want %s, %s or %s)", mode, CINotifyAll, CINotifyFailure, CINotifyFailureAndRecovery)
+	}
+}
+
+// issueRefPattern matches a single issue reference: a full issue or pull
+// request URL, owner/repo#N, or #N.
+const issueRefPattern = `(?:https?://([\w.-]+)/([\w.-]+/[\w.-]+)/(?:issues|pull)/(\d+)|(?:\b([\w.-]+/[\w.-]+))?#(\d+))\b`
+
+var (
+	linkedIssuePattern = regexp.MustCompile(`(?i)\b(close[sd]?|fix(?:e[sd])?|resolve[sd]?|refs?|references)\b:?\s+(` +
+		issueRefPattern + `(?:\s*(?:,|&|\band\b)\s*` + issueRefPattern + `)*)`)
+	issueRefRegexp = regexp.MustCompile(issueRefPattern)
+
+	fencedCodePattern = regexp.MustCompile("(?s)```.*?(?:```|$)|~~~.*?(?:~~~|$)")
+	inlineCodePattern = regexp.MustCompile("`[^`\n]*`")
+)
 
 // IssueLink represents a linked issue parsed from a PR body.
 type IssueLink struct {
@@ -95,39 +610,225 @@ type IssueLink struct {
 	URL  string
 }
 
-// LinkedIssues parses GitHub closing keywords from the PR body and returns
-// deduplicated issue links for the same repository.
+// LinkedIssues returns the issues the PR body closes followed by the ones it
+// only references, deduplicated.
 func (d *TemplateData) LinkedIssues() []IssueLink {
-	if d.PR.Body == "" || d.Repo.HTMLURL == "" {
-		return nil
+	closing, referenced := d.parseIssueLinks()
+	return append(closing, referenced...)
+}
+
+// ClosingIssues returns issues the PR body links with a closing keyword
+// (close, fix, resolve and their variants).
+func (d *TemplateData) ClosingIssues() []IssueLink {
+	closing, _ := d.parseIssueLinks()
+	return closing
+}
+
+// ReferencedIssues returns issues the PR body mentions with ref, refs or
+// references, excluding any that are also closed.
+func (d *TemplateData) ReferencedIssues() []IssueLink {
+	_, referenced := d.parseIssueLinks()
+	return referenced
+}
+
+// parseIssueLinks scans the PR body, outside code blocks and spans, for
+// keyword-prefixed issue references. A keyword may be followed by a list
+// such as "Fixes #1, #2 and owner/repo#3". Same-repository references are
+// labelled "Issue #N" and others "owner/repo#N".
+func (d *TemplateData) parseIssueLinks() (closing, referenced []IssueLink) {
+	if d.PR.Body == "" {
+		return nil, nil
+	}
+
+	body := fencedCodePattern.ReplaceAllString(d.PR.Body, "")
+	body = inlineCodePattern.ReplaceAllString(body, "")
+
+	base, repo := d.issueBase()
+	seen := make(map[string]bool)
+	var refs []IssueLink
+	var refKeys []string
+
+	for _, m := range linkedIssuePattern.FindAllStringSubmatch(body, -1) {
+		isClosing := !strings.HasPrefix(strings.ToLower(m[1]), "ref")
+
+		for _, r := range issueRefRegexp.FindAllStringSubmatch(m[2], -1) {
+			target, num := r[4], r[5]
+			if r[3] != "" {
+				host := r[1]
+				if !strings.EqualFold(host, "github.com") && !strings.EqualFold(host, hostOf(base)) {
+					continue
+				}
+				target, num = r[2], r[3]
+			}
+			if _, err := strconv.Atoi(num); err != nil {
+				continue
+			}
+
+			var link IssueLink
+			if target == "" || (repo != "" && strings.EqualFold(target, repo)) {
+				if d.Repo.HTMLURL == "" {
+					continue
+				}
+				link = IssueLink{Text: "Issue #" + num, URL: d.Repo.HTMLURL + "/issues/" + num}
+				target = repo
+			} else {
+				link = IssueLink{Text: target + "#" + num, URL: base + "/" + target + "/issues/" + num}
+			}
+
+			key := strings.ToLower(target) + "#" + num
+			if seen[key] {
+				continue
+			}
+			if isClosing {
+				seen[key] = true
+				closing = append(closing, link)
+				continue
+			}
+			refs = append(refs, link)
+			refKeys = append(refKeys, key)
+		}
 	}
 
-	matches := linkedIssuePattern.FindAllStringSubmatch(d.PR.Body, -1)
-	if len(matches) == 0 {
+	// References may appear before the closing keyword for the same issue.
+	refSeen := make(map[string]bool)
+	for i, link := range refs {
+		if seen[refKeys[i]] || refSeen[refKeys[i]] {
+			continue
+		}
+		refSeen[refKeys[i]] = true
+		referenced = append(referenced, link)
+	}
+	return closing, referenced
+}
+
+// issueBase returns the web base URL (https://github.com, or the GitHub
+// Enterprise host) and the owner/repo of the event repository.
+func (d *TemplateData) issueBase() (base, repo string) {
+	base = "https://github.com"
+	repo = d.Repo.FullName
+	if d.Repo.HTMLURL == "" {
+		return base, repo
+	}
+	if repo == "" {
+		if i := strings.Index(d.Repo.HTMLURL, "://"); i >= 0 {
+			if _, path, ok := strings.Cut(d.Repo.HTMLURL[i+3:], "/"); ok {
+				repo = path
+			}
+		}
+	}
+	if repo != "" && strings.HasSuffix(d.Repo.HTMLURL, "/"+repo) {
+		base = strings.TrimSuffix(d.Repo.HTMLURL, "/"+repo)
+	}
+	return base, repo
+}
+
+func hostOf(url string) string {
+	if i := strings.Index(url, "://"); i >= 0 {
+		url = url[i+3:]
+	}
+	host, _, _ := strings.Cut(url, "/")
+	return host
+}
+
+var (
+	markdownImagePattern = regexp.MustCompile(`!\[[^\]]*\]\((https?://[^\s)]+)(?:\s+"[^"]*")?\)`)
+	htmlImagePattern     = regexp.MustCompile(`(?i)<img\s[^>]*?src\s*=\s*["'](https?://[^"']+)["']`)
+)
+
+// MaxImages is the most images Telegram accepts in one album.
+const MaxImages = 10
+
+// Images returns the http(s) image URLs embedded in the PR body with
+// Markdown or <img> tags, in order of appearance and deduplicated. Images
+// inside code blocks are ignored.
+func (d *TemplateData) Images() []string {
+	if d.PR.Body == "" {
 		return nil
 	}
+	body := fencedCodePattern.ReplaceAllString(d.PR.Body, "")
 
-	seen := make(map[int]bool)
-	var links []IssueLink
-	for _, m := range matches {
-		num, err := strconv.Atoi(m[1])
-		if err != nil || seen[num] {
+	type match struct {
+		pos int
+		url string
+	}
+	var found []match
+	for _, re := range []*regexp.Regexp{markdownImagePattern, htmlImagePattern} {
+		for _, m := range re.FindAllStringSubmatchIndex(body, -1) {
+			found = append(found, match{m[0], body[m[2]:m[3]]})
+		}
+	}
+	sort.Slice(found, func(i, j int) bool { return found[i].pos < found[j].pos })
+
+	seen := make(map[string]bool)
+	var urls []string
+	for _, m := range found {
+		if seen[m.url] {
 			continue
 		}
-		seen[num] = true
-		links = append(links, IssueLink{
-			Text: fmt.Sprintf("Issue #%d", num),
-			URL:  fmt.Sprintf("%s/issues/%d", d.Repo.HTMLURL, num),
-		})
+		seen[m.url] = true
+		urls = append(urls, m.url)
+		if len(urls) == MaxImages {
+			break
+		}
+	}
+	return urls
+}
+
+// SecondaryLinks returns event-specific links shown as extra buttons next
+// to the RelevantURL button.
+func (d *TemplateData) SecondaryLinks() []IssueLink {
+	var links []IssueLink
+	switch d.EventName {
+	case "workflow_run", "check_suite", "check_run":
+		if d.PR.HTMLURL != "" {
+			links = append(links, IssueLink{Text: "View Pull Request", URL: d.PR.HTMLURL})
+		}
+	case "release":
+		if url := d.Release.DownloadURL(); url != "" {
+			links = append(links, IssueLink{Text: "Download", URL: url})
+		}
+	case "deployment_status":
+		if d.Deployment.EnvironmentURL != "" && d.Deployment.LogURL != "" {
+			links = append(links, IssueLink{Text: "View Logs", URL: d.Deployment.LogURL})
+		}
 	}
 	return links
 }
 
-// IsMerged returns true if the PR was closed via merge.
+// IsMerged returns true if the PR was closed via merge. A merge queue merge
+// also closes the PR, so the closed event is the one source of truth for
+// merges; the queue's own events report it with QueueMerged.
 func (d *TemplateData) IsMerged() bool {
 	return d.EventName == "pull_request" && d.Action == "closed" && d.PR.Merged
 }
 
+// QueueMerged returns true for merge queue events that report a merge: a PR
+// dequeued with reason MERGE, or a merge group destroyed with reason
+// merged.
+func (d *TemplateData) QueueMerged() bool {
+	switch d.EventName {
+	case "pull_request":
+		return d.Action == "dequeued" && d.MergeGroup.Reason == "MERGE"
+	case "merge_group":
+		return d.Action == "destroyed" && d.MergeGroup.Reason == "merged"
+	}
+	return false
+}
+
+// IsFailure returns true for failing CI runs and failed deployments.
+func (d *TemplateData) IsFailure() bool {
+	if d.CI.Failed() {
+		return true
+	}
+	return d.EventName == "deployment_status" && (d.Action == "failure" || d.Action == "error")
+}
+
+// IsBot returns true when the event was triggered by a bot, or concerns a
+// pull request opened by one.
+func (d *TemplateData) IsBot() bool {
+	return d.Actor.IsBot() || d.PR.User.IsBot()
+}
+
 // RelevantURL returns the most relevant URL for the event.
 func (d *TemplateData) RelevantURL() string {
 	switch d.EventName {
@@ -139,6 +840,47 @@ func (d *TemplateData) RelevantURL() string {
 		if d.Comment.HTMLURL != "" {
 			return d.Comment.HTMLURL
 		}
+	case "workflow_run", "check_suite", "check_run":
+		if d.CI.HTMLURL != "" {
+			return d.CI.HTMLURL
+		}
+	case "push":
+		switch {
+		case d.Push.Deleted:
+			return d.Repo.HTMLURL
+		case d.Push.Created && d.Repo.HTMLURL != "":
+			return d.Repo.HTMLURL + "/tree/" + d.Push.RefName()
+		case d.Push.CompareURL != "":
+			return d.Push.CompareURL
+		}
+		return d.Repo.HTMLURL
+	case "release":
+		if d.Release.HTMLURL != "" {
+			return d.Release.HTMLURL
+		}
+	case "create":
+		if d.Repo.HTMLURL != "" {
+			return d.Repo.HTMLURL + "/tree/" + d.Ref.Name
+		}
+	case "delete":
+		return d.Repo.HTMLURL
+	case "merge_group":
+		if d.PR.HTMLURL == "" && d.Repo.HTMLURL != "" {
+			return d.Repo.HTMLURL + "/queue/" + d.MergeGroup.BaseBranch()
+		}
+	case "discussion", "discussion_comment":
+		if d.Comment.HTMLURL != "" {
+			return d.Comment.HTMLURL
+		}
+		return d.Discussion.HTMLURL
+	case "deployment_status":
+		switch {
+		case d.Deployment.EnvironmentURL != "":
+			return d.Deployment.EnvironmentURL
+		case d.Deployment.LogURL != "":
+			return d.Deployment.LogURL
+		}
+		return d.Repo.HTMLURL
 	}
 	return d.PR.HTMLURL
 }
@@ -150,6 +892,44 @@ func (d *TemplateData) ButtonText() string {
 		return "View Review"
 	case "pull_request_review_comment":
 		return "View Comment"
+	case "workflow_run":
+		return "View Run"
+	case "check_suite", "check_run":
+		return "View Checks"
+	case "push":
+		switch {
+		case d.Push.Deleted:
+			return "View Repository"
+		case d.Push.Created:
+			return "View Branch"
+		}
+		return "View Changes"
+	case "release":
+		return "View Release"
+	case "create":
+		if d.Ref.Type == "tag" {
+			return "View Tag"
+		}
+		return "View Branch"
+	case "delete":
+		return "View Repository"
+	case "deployment_status":
+		if d.Deployment.EnvironmentURL != "" {
+			return "Open " + d.Deployment.Environment
+		}
+		return "View Deployment"
+	case "discussion":
+		if d.Action == "answered" {
+			return "View Answer"
+		}
+		return "View Discussion"
+	case "discussion_comment":
+		return "View Comment"
+	case "merge_group":
+		if d.PR.HTMLURL == "" {
+			return "View Merge Queue"
+		}
+		return "View Pull Request"
 	default:
 		return "View Pull Request"
 	}
@@ -173,6 +953,24 @@ func Parse(payload []byte) (*TemplateData, error) {
 		return parseReview(ctx.Event)
 	case "pull_request_review_comment":
 		return parseReviewComment(ctx.Event)
+	case "workflow_run":
+		return parseWorkflowRun(ctx.Event)
+	case "check_suite":
+		return parseCheckSuite(ctx.Event)
+	case "check_run":
+		return parseCheckRun(ctx.Event)
+	case "push":
+		return parsePush(ctx.Event)
+	case "release":
+		return parseRelease(ctx.Event)
+	case "create", "delete":
+		return parseRef(ctx.EventName, ctx.Event)
+	case "deployment_status":
+		return parseDeploymentStatus(ctx.Event)
+	case "discussion", "discussion_comment":
+		return parseDiscussion(ctx.EventName, ctx.Event)
+	case "merge_group":
+		return parseMergeGroup(ctx.Event)
 	default:
 		return nil, fmt.Errorf("unsupported event: %s", ctx.EventName)
 	}
@@ -184,11 +982,13 @@ func parsePullRequest(raw json.RawMessage) (*TemplateData, error) {
 		return nil, fmt.Errorf("parsing pull_request event: %w", err)
 	}
 	return &TemplateData{
-		EventName: "pull_request",
-		Action:    e.Action,
-		Actor:     e.Sender,
-		Repo:      e.Repository,
-		PR:        e.PullRequest,
+		EventName:     "pull_request",
+		Action:        e.Action,
+		Actor:         e.Sender,
+		Repo:          e.Repository,
+		PR:            e.PullRequest,
+		MergeGroup:    MergeGroup{Reason: e.Reason},
+		ReviewRequest: e.ReviewRequest,
 	}, nil
 }
 
@@ -227,3 +1027,271 @@ func parseReviewComment(raw json.RawMessage) (*TemplateData, error) {
 		Comment:   e.Comment,
 	}, nil
 }
+
+func parseWorkflowRun(raw json.RawMessage) (*TemplateData, error) {
+	var e workflowRunEvent
+	if err := json.Unmarshal(raw, &e); err != nil {
+		return nil, fmt.Errorf("parsing workflow_run event: %w", err)
+	}
+	run := e.WorkflowRun
+	data := &TemplateData{
+		EventName: "workflow_run",
+		Action:    e.Action,
+		Actor:     e.Sender,
+		Repo:      e.Repository,
+		CI: CIRun{
+			Kind:        "workflow_run",
+			ID:          run.ID,
+			Name:        run.Name,
+			Status:      run.Status,
+			Conclusion:  run.Conclusion,
+			HTMLURL:     run.HTMLURL,
+			HeadBranch:  run.HeadBranch,
+			HeadSHA:     run.HeadSHA,
+			RunNumber:   run.RunNumber,
+			StartedAt:   run.RunStartedAt,
+			CompletedAt: run.UpdatedAt,
+		},
+	}
+	data.linkCIPullRequests(run.PullRequests)
+	return data, nil
+}
+
+func parseCheckSuite(raw json.RawMessage) (*TemplateData, error) {
+	var e checkSuiteEvent
+	if err := json.Unmarshal(raw, &e); err != nil {
+		return nil, fmt.Errorf("parsing check_suite event: %w", err)
+	}
+	suite := e.CheckSuite
+	data := &TemplateData{
+		EventName: "check_suite",
+		Action:    e.Action,
+		Actor:     e.Sender,
+		Repo:      e.Repository,
+		CI: CIRun{
+			Kind:        "check_suite",
+			Name:        suite.App.Name,
+			Status:      suite.Status,
+			Conclusion:  suite.Conclusion,
+			HeadBranch:  suite.HeadBranch,
+			HeadSHA:     suite.HeadSHA,
+			StartedAt:   suite.CreatedAt,
+			CompletedAt: suite.UpdatedAt,
+		},
+	}
+	if e.Repository.HTMLURL != "" && suite.HeadSHA != "" {
+		data.CI.HTMLURL = fmt.Sprintf("%s/commit/%s/checks?check_suite_id=%d", e.Repository.HTMLURL, suite.HeadSHA, suite.ID)
+	}
+	data.linkCIPullRequests(suite.PullRequests)
+	return data, nil
+}
+
+func parseCheckRun(raw json.RawMessage) (*TemplateData, error) {
+	var e checkRunEvent
+	if err := json.Unmarshal(raw, &e); err != nil {
+		return nil, fmt.Errorf("parsing check_run event: %w", err)
+	}
+	run := e.CheckRun
+	data := &TemplateData{
+		EventName: "check_run",
+		Action:    e.Action,
+		Actor:     e.Sender,
+		Repo:      e.Repository,
+		CI: CIRun{
+			Kind:        "check_run",
+			Name:        run.Name,
+			Status:      run.Status,
+			Conclusion:  run.Conclusion,
+			HTMLURL:     run.HTMLURL,
+			HeadBranch:  run.CheckSuite.HeadBranch,
+			HeadSHA:     run.HeadSHA,
+			StartedAt:   run.StartedAt,
+			CompletedAt: run.CompletedAt,
+		},
+	}
+	if data.CI.Failed() {
+		data.CI.FailedJobs = []string{run.Name}
+	}
+	data.linkCIPullRequests(run.PullRequests)
+	return data, nil
+}
+
+// linkCIPullRequests maps the minimal PR references in CI payloads to
+// PullRequest values and exposes the first one as d.PR.
+func (d *TemplateData) linkCIPullRequests(prs []ciPullRequest) {
+	for _, p := range prs {
+		pr := PullRequest{Number: p.Number, Head: p.Head, Base: p.Base}
+		if d.Repo.HTMLURL != "" {
+			pr.HTMLURL = fmt.Sprintf("%s/pull/%d", d.Repo.HTMLURL, p.Number)
+		}
+		d.CI.PullRequests = append(d.CI.PullRequests, pr)
+	}
+	if len(d.CI.PullRequests) > 0 {
+		d.PR = d.CI.PullRequests[0]
+	}
+}
+
+func parsePush(raw json.RawMessage) (*TemplateData, error) {
+	var e pushEvent
+	if err := json.Unmarshal(raw, &e); err != nil {
+		return nil, fmt.Errorf("parsing push event: %w", err)
+	}
+
+	// Push events carry no action; derive one so each kind of push can have
+	// its own template.
+	action := "pushed"
+	switch {
+	case e.Created:
+		action = "created"
+	case e.Deleted:
+		action = "deleted"
+	case e.Forced:
+		action = "forced"
+	}
+
+	return &TemplateData{
+		EventName: "push",
+		Action:    action,
+		Actor:     e.Sender,
+		Repo:      e.Repository,
+		Push: Push{
+			Ref:        e.Ref,
+			Before:     e.Before,
+			After:      e.After,
+			Created:    e.Created,
+			Deleted:    e.Deleted,
+			Forced:     e.Forced,
+			CompareURL: e.Compare,
+			Commits:    e.Commits,
+		},
+	}, nil
+}
+
+func parseRelease(raw json.RawMessage) (*TemplateData, error) {
+	var e releaseEvent
+	if err := json.Unmarshal(raw, &e); err != nil {
+		return nil, fmt.Errorf("parsing release event: %w", err)
+	}
+	return &TemplateData{
+		EventName: "release",
+		Action:    e.Action,
+		Actor:     e.Sender,
+		Repo:      e.Repository,
+		Release:   e.Release,
+	}, nil
+}
+
+func parseRef(eventName string, raw json.RawMessage) (*TemplateData, error) {
+	var e refEvent
+	if err := json.Unmarshal(raw, &e); err != nil {
+		return nil, fmt.Errorf("parsing %s event: %w", eventName, err)
+	}
+
+	// create and delete events carry no action; the ref type selects the
+	// template instead.
+	return &TemplateData{
+		EventName: eventName,
+		Action:    e.RefType,
+		Actor:     e.Sender,
+		Repo:      e.Repository,
+		Ref:       Ref{Name: e.Ref, Type: e.RefType},
+	}, nil
+}
+
+func parseDeploymentStatus(raw json.RawMessage) (*TemplateData, error) {
+	var e deploymentStatusEvent
+	if err := json.Unmarshal(raw, &e); err != nil {
+		return nil, fmt.Errorf("parsing deployment_status event: %w", err)
+	}
+
+	status := e.DeploymentStatus
+	env := status.Environment
+	if env == "" {
+		env = e.Deployment.Environment
+	}
+	logURL := status.LogURL
+	if logURL == "" {
+		logURL = status.TargetURL
+	}
+
+	// Like reviews, the state is more useful than the "created" action.
+	return &TemplateData{
+		EventName: "deployment_status",
+		Action:    status.State,
+		Actor:     e.Sender,
+		Repo:      e.Repository,
+		Deployment: Deployment{
+			Environment:    env,
+			State:          status.State,
+			EnvironmentURL: status.EnvironmentURL,
+			LogURL:         logURL,
+			Description:    status.Description,
+			Ref:            e.Deployment.Ref,
+			SHA:            e.Deployment.SHA,
+		},
+	}, nil
+}
+
+func parseDiscussion(eventName string, raw json.RawMessage) (*TemplateData, error) {
+	var e discussionEvent
+	if err := json.Unmarshal(raw, &e); err != nil {
+		return nil, fmt.Errorf("parsing %s event: %w", eventName, err)
+	}
+
+	data := &TemplateData{
+		EventName:  eventName,
+		Action:     e.Action,
+		Actor:      e.Sender,
+		Repo:       e.Repository,
+		Discussion: e.Discussion,
+	}
+	data.Discussion.PreviousCategory = e.Changes.Category.From
+
+	// The chosen answer of an answered discussion and the comment of a
+	// discussion_comment event are both exposed as Comment.
+	switch {
+	case e.Comment != nil:
+		data.Comment = *e.Comment
+	case e.Answer != nil:
+		data.Comment = *e.Answer
+	}
+	return data, nil
+}
+
+func parseMergeGroup(raw json.RawMessage) (*TemplateData, error) {
+	var e mergeGroupEvent
+	if err := json.Unmarshal(raw, &e); err != nil {
+		return nil, fmt.Errorf("parsing merge_group event: %w", err)
+	}
+
+	group := e.MergeGroup
+	data := &TemplateData{
+		EventName: "merge_group",
+		Action:    e.Action,
+		Actor:     e.Sender,
+		Repo:      e.Repository,
+		MergeGroup: MergeGroup{
+			HeadSHA:    group.HeadSHA,
+			HeadRef:    group.HeadRef,
+			BaseSHA:    group.BaseSHA,
+			BaseRef:    group.BaseRef,
+			HeadCommit: group.HeadCommit,
+			Reason:     e.Reason,
+		},
+	}
+
+	// The payload does not reference the PR directly; its number is
+	// encoded in the temporary queue branch name.
+	if m := mergeQueueRefPattern.FindStringSubmatch(group.HeadRef); m != nil {
+		num, _ := strconv.Atoi(m[1])
+		data.PR = PullRequest{
+			Number: num,
+			Title:  group.HeadCommit.Title(),
+			Base:   Branch{Ref: data.MergeGroup.BaseBranch()},
+		}
+		if e.Repository.HTMLURL != "" {
+			data.PR.HTMLURL = fmt.Sprintf("%s/pull/%d", e.Repository.HTMLURL, num)
+		}
+	}
+	return data, nil
+}
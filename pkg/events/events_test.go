@@ -1,8 +1,11 @@
 package events
 
 import (
+	"fmt"
 	"os"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestParse(t *testing.T) {
@@ -70,6 +73,18 @@ func TestParse(t *testing.T) {
 			wantEvent:  "pull_request",
 			wantAction: "reopened",
 		},
+		{
+			name:       "pull_request review_requested",
+			fixture:    "../../testdata/pull_request_review_requested.json",
+			wantEvent:  "pull_request",
+			wantAction: "review_requested",
+			check: func(t *testing.T, data *TemplateData) {
+				t.Helper()
+				if data.ReviewRequest.Reviewer.Login != "hubot" {
+					t.Errorf("ReviewRequest.Reviewer.Login = %q, want %q", data.ReviewRequest.Reviewer.Login, "hubot")
+				}
+			},
+		},
 		{
 			name:       "pull_request synchronize",
 			fixture:    "../../testdata/pull_request_synchronize.json",
@@ -151,6 +166,339 @@ func TestParse(t *testing.T) {
 				}
 			},
 		},
+		{
+			name:       "workflow_run failure",
+			fixture:    "../../testdata/workflow_run_failure.json",
+			wantEvent:  "workflow_run",
+			wantAction: "completed",
+			check: func(t *testing.T, data *TemplateData) {
+				t.Helper()
+				if data.CI.Kind != "workflow_run" {
+					t.Errorf("CI.Kind = %q, want %q", data.CI.Kind, "workflow_run")
+				}
+				if !data.CI.Failed() {
+					t.Error("CI.Failed() = false, want true")
+				}
+				if data.CI.Duration() != 3*time.Minute+25*time.Second {
+					t.Errorf("CI.Duration() = %v, want 3m25s", data.CI.Duration())
+				}
+				if len(data.CI.FailedJobs) != 0 {
+					t.Errorf("CI.FailedJobs = %v, want none: the payload does not list jobs", data.CI.FailedJobs)
+				}
+				if data.PR.Number != 42 {
+					t.Errorf("PR.Number = %d, want 42", data.PR.Number)
+				}
+				if data.PR.HTMLURL != "https://github.com/octocat/Hello-World/pull/42" {
+					t.Errorf("PR.HTMLURL = %q", data.PR.HTMLURL)
+				}
+				if data.RelevantURL() != "https://github.com/octocat/Hello-World/actions/runs/30433642" {
+					t.Errorf("RelevantURL() = %q", data.RelevantURL())
+				}
+			},
+		},
+		{
+			name:       "workflow_run success without PR",
+			fixture:    "../../testdata/workflow_run_success.json",
+			wantEvent:  "workflow_run",
+			wantAction: "completed",
+			check: func(t *testing.T, data *TemplateData) {
+				t.Helper()
+				if !data.CI.Succeeded() {
+					t.Error("CI.Succeeded() = false, want true")
+				}
+				if data.PR.Number != 0 {
+					t.Errorf("PR.Number = %d, want 0", data.PR.Number)
+				}
+			},
+		},
+		{
+			name:       "check_suite completed",
+			fixture:    "../../testdata/check_suite_completed.json",
+			wantEvent:  "check_suite",
+			wantAction: "completed",
+			check: func(t *testing.T, data *TemplateData) {
+				t.Helper()
+				if data.CI.Name != "GitHub Actions" {
+					t.Errorf("CI.Name = %q, want %q", data.CI.Name, "GitHub Actions")
+				}
+				want := "https://github.com/octocat/Hello-World/commit/ec26c3e57ca3a959ca5aad62de7213c562f8c821/checks?check_suite_id=118578147"
+				if data.CI.HTMLURL != want {
+					t.Errorf("CI.HTMLURL = %q, want %q", data.CI.HTMLURL, want)
+				}
+			},
+		},
+		{
+			name:       "check_run failure",
+			fixture:    "../../testdata/check_run_failure.json",
+			wantEvent:  "check_run",
+			wantAction: "completed",
+			check: func(t *testing.T, data *TemplateData) {
+				t.Helper()
+				if len(data.CI.FailedJobs) != 1 || data.CI.FailedJobs[0] != "lint" {
+					t.Errorf("CI.FailedJobs = %v, want [lint]", data.CI.FailedJobs)
+				}
+				if data.CI.HeadBranch != "feature-branch" {
+					t.Errorf("CI.HeadBranch = %q, want %q", data.CI.HeadBranch, "feature-branch")
+				}
+			},
+		},
+		{
+			name:       "push",
+			fixture:    "../../testdata/push.json",
+			wantEvent:  "push",
+			wantAction: "pushed",
+			check: func(t *testing.T, data *TemplateData) {
+				t.Helper()
+				if data.Push.RefName() != "main" {
+					t.Errorf("Push.RefName() = %q, want %q", data.Push.RefName(), "main")
+				}
+				if len(data.Push.Commits) != 2 {
+					t.Fatalf("len(Push.Commits) = %d, want 2", len(data.Push.Commits))
+				}
+				if got := data.Push.Commits[0].Title(); got != "Add new feature" {
+					t.Errorf("Commits[0].Title() = %q, want %q", got, "Add new feature")
+				}
+				if got := data.Push.Commits[0].ShortID(); got != "a10867b" {
+					t.Errorf("Commits[0].ShortID() = %q, want %q", got, "a10867b")
+				}
+				if data.RelevantURL() != "https://github.com/octocat/Hello-World/compare/6113728f27ae...0d1a26e67d8f" {
+					t.Errorf("RelevantURL() = %q", data.RelevantURL())
+				}
+			},
+		},
+		{
+			name:       "push forced",
+			fixture:    "../../testdata/push_forced.json",
+			wantEvent:  "push",
+			wantAction: "forced",
+			check: func(t *testing.T, data *TemplateData) {
+				t.Helper()
+				if data.Push.RefName() != "release/1.2" {
+					t.Errorf("Push.RefName() = %q, want %q", data.Push.RefName(), "release/1.2")
+				}
+			},
+		},
+		{
+			name:       "push branch deleted",
+			fixture:    "../../testdata/push_branch_deleted.json",
+			wantEvent:  "push",
+			wantAction: "deleted",
+			check: func(t *testing.T, data *TemplateData) {
+				t.Helper()
+				if data.RelevantURL() != "https://github.com/octocat/Hello-World" {
+					t.Errorf("RelevantURL() = %q", data.RelevantURL())
+				}
+				if data.ButtonText() != "View Repository" {
+					t.Errorf("ButtonText() = %q", data.ButtonText())
+				}
+			},
+		},
+		{
+			name:       "release published",
+			fixture:    "../../testdata/release_published.json",
+			wantEvent:  "release",
+			wantAction: "published",
+			check: func(t *testing.T, data *TemplateData) {
+				t.Helper()
+				if data.Release.TagName != "v1.1.0" {
+					t.Errorf("Release.TagName = %q, want %q", data.Release.TagName, "v1.1.0")
+				}
+				if data.Release.DisplayName() != "Hello World 1.1" {
+					t.Errorf("Release.DisplayName() = %q", data.Release.DisplayName())
+				}
+				want := "https://github.com/octocat/Hello-World/releases/download/v1.1.0/hello-world_linux_amd64.tar.gz"
+				if data.Release.DownloadURL() != want {
+					t.Errorf("Release.DownloadURL() = %q, want %q", data.Release.DownloadURL(), want)
+				}
+				if data.RelevantURL() != "https://github.com/octocat/Hello-World/releases/tag/v1.1.0" {
+					t.Errorf("RelevantURL() = %q", data.RelevantURL())
+				}
+			},
+		},
+		{
+			name:       "create tag",
+			fixture:    "../../testdata/create_tag.json",
+			wantEvent:  "create",
+			wantAction: "tag",
+			check: func(t *testing.T, data *TemplateData) {
+				t.Helper()
+				if data.Ref.Name != "v1.1.0" {
+					t.Errorf("Ref.Name = %q, want %q", data.Ref.Name, "v1.1.0")
+				}
+				if data.RelevantURL() != "https://github.com/octocat/Hello-World/tree/v1.1.0" {
+					t.Errorf("RelevantURL() = %q", data.RelevantURL())
+				}
+				if data.ButtonText() != "View Tag" {
+					t.Errorf("ButtonText() = %q", data.ButtonText())
+				}
+			},
+		},
+		{
+			name:       "delete tag",
+			fixture:    "../../testdata/delete_tag.json",
+			wantEvent:  "delete",
+			wantAction: "tag",
+		},
+		{
+			name:       "deployment_status success",
+			fixture:    "../../testdata/deployment_status_success.json",
+			wantEvent:  "deployment_status",
+			wantAction: "success",
+			check: func(t *testing.T, data *TemplateData) {
+				t.Helper()
+				if data.Deployment.Environment != "production" {
+					t.Errorf("Deployment.Environment = %q, want %q", data.Deployment.Environment, "production")
+				}
+				if data.RelevantURL() != "https://hello-world.example.com" {
+					t.Errorf("RelevantURL() = %q", data.RelevantURL())
+				}
+				if data.ButtonText() != "Open production" {
+					t.Errorf("ButtonText() = %q", data.ButtonText())
+				}
+				links := data.SecondaryLinks()
+				if len(links) != 1 || links[0].URL != "https://github.com/octocat/Hello-World/actions/runs/30433642" {
+					t.Errorf("SecondaryLinks() = %v, want logs link", links)
+				}
+			},
+		},
+		{
+			name:       "deployment_status failure falls back to target_url",
+			fixture:    "../../testdata/deployment_status_failure.json",
+			wantEvent:  "deployment_status",
+			wantAction: "failure",
+			check: func(t *testing.T, data *TemplateData) {
+				t.Helper()
+				if data.RelevantURL() != "https://github.com/octocat/Hello-World/actions/runs/30433644" {
+					t.Errorf("RelevantURL() = %q", data.RelevantURL())
+				}
+				if len(data.SecondaryLinks()) != 0 {
+					t.Errorf("SecondaryLinks() = %v, want none", data.SecondaryLinks())
+				}
+			},
+		},
+		{
+			name:       "discussion created",
+			fixture:    "../../testdata/discussion_created.json",
+			wantEvent:  "discussion",
+			wantAction: "created",
+			check: func(t *testing.T, data *TemplateData) {
+				t.Helper()
+				if data.Discussion.Number != 90 {
+					t.Errorf("Discussion.Number = %d, want 90", data.Discussion.Number)
+				}
+				if data.Discussion.Category.Name != "Q&A" {
+					t.Errorf("Discussion.Category.Name = %q, want %q", data.Discussion.Category.Name, "Q&A")
+				}
+				if data.RelevantURL() != "https://github.com/octocat/Hello-World/discussions/90" {
+					t.Errorf("RelevantURL() = %q", data.RelevantURL())
+				}
+				if data.ButtonText() != "View Discussion" {
+					t.Errorf("ButtonText() = %q", data.ButtonText())
+				}
+			},
+		},
+		{
+			name:       "discussion answered",
+			fixture:    "../../testdata/discussion_answered.json",
+			wantEvent:  "discussion",
+			wantAction: "answered",
+			check: func(t *testing.T, data *TemplateData) {
+				t.Helper()
+				if data.Comment.User.Login != "maintainer" {
+					t.Errorf("Comment.User.Login = %q, want %q", data.Comment.User.Login, "maintainer")
+				}
+				if data.RelevantURL() != "https://github.com/octocat/Hello-World/discussions/90#discussioncomment-1" {
+					t.Errorf("RelevantURL() = %q", data.RelevantURL())
+				}
+				if data.ButtonText() != "View Answer" {
+					t.Errorf("ButtonText() = %q", data.ButtonText())
+				}
+			},
+		},
+		{
+			name:       "discussion comment",
+			fixture:    "../../testdata/discussion_comment_created.json",
+			wantEvent:  "discussion_comment",
+			wantAction: "created",
+			check: func(t *testing.T, data *TemplateData) {
+				t.Helper()
+				if data.Comment.Body != "Use the category_topics input." {
+					t.Errorf("Comment.Body = %q", data.Comment.Body)
+				}
+				if data.Discussion.Title != "How do I configure topics?" {
+					t.Errorf("Discussion.Title = %q", data.Discussion.Title)
+				}
+			},
+		},
+		{
+			name:       "pull_request enqueued",
+			fixture:    "../../testdata/pull_request_enqueued.json",
+			wantEvent:  "pull_request",
+			wantAction: "enqueued",
+		},
+		{
+			name:       "pull_request dequeued",
+			fixture:    "../../testdata/pull_request_dequeued.json",
+			wantEvent:  "pull_request",
+			wantAction: "dequeued",
+			check: func(t *testing.T, data *TemplateData) {
+				t.Helper()
+				if data.MergeGroup.Reason != "CHECKS_FAILED" {
+					t.Errorf("MergeGroup.Reason = %q, want %q", data.MergeGroup.Reason, "CHECKS_FAILED")
+				}
+				if data.MergeGroup.ReasonText() != "checks failed" {
+					t.Errorf("MergeGroup.ReasonText() = %q, want %q", data.MergeGroup.ReasonText(), "checks failed")
+				}
+				if data.IsMerged() {
+					t.Error("IsMerged() = true, want false")
+				}
+			},
+		},
+		{
+			name:       "merge_group checks_requested",
+			fixture:    "../../testdata/merge_group_checks_requested.json",
+			wantEvent:  "merge_group",
+			wantAction: "checks_requested",
+			check: func(t *testing.T, data *TemplateData) {
+				t.Helper()
+				if data.PR.Number != 42 {
+					t.Errorf("PR.Number = %d, want 42", data.PR.Number)
+				}
+				if data.PR.Title != "Add new feature (#42)" {
+					t.Errorf("PR.Title = %q", data.PR.Title)
+				}
+				if data.MergeGroup.BaseBranch() != "main" {
+					t.Errorf("MergeGroup.BaseBranch() = %q, want %q", data.MergeGroup.BaseBranch(), "main")
+				}
+				if data.RelevantURL() != "https://github.com/octocat/Hello-World/pull/42" {
+					t.Errorf("RelevantURL() = %q", data.RelevantURL())
+				}
+			},
+		},
+		{
+			name:       "merge_group destroyed merged",
+			fixture:    "../../testdata/merge_group_destroyed_merged.json",
+			wantEvent:  "merge_group",
+			wantAction: "destroyed",
+			check: func(t *testing.T, data *TemplateData) {
+				t.Helper()
+				if !data.QueueMerged() {
+					t.Error("QueueMerged() = false, want true")
+				}
+			},
+		},
+		{
+			name:       "merge_group destroyed invalidated",
+			fixture:    "../../testdata/merge_group_destroyed_invalidated.json",
+			wantEvent:  "merge_group",
+			wantAction: "destroyed",
+			check: func(t *testing.T, data *TemplateData) {
+				t.Helper()
+				if data.IsMerged() {
+					t.Error("IsMerged() = true, want false")
+				}
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -184,7 +532,7 @@ func TestParseInvalidJSON(t *testing.T) {
 }
 
 func TestParseUnsupportedEvent(t *testing.T) {
-	payload := []byte(`{"event_name": "push", "event": {}}`)
+	payload := []byte(`{"event_name": "watch", "event": {}}`)
 	_, err := Parse(payload)
 	if err == nil {
 		t.Error("Parse() expected error for unsupported event")
@@ -371,6 +719,97 @@ func TestLinkedIssues(t *testing.T) {
 	}
 }
 
+func TestClosingAndReferencedIssues(t *testing.T) {
+	data := TemplateData{
+		Repo: Repository{FullName: "octocat/Hello-World", HTMLURL: "https://github.com/octocat/Hello-World"},
+	}
+
+	tests := []struct {
+		name           string
+		body           string
+		wantClosing    []string
+		wantReferenced []string
+	}{
+		{
+			name:        "comma separated list",
+			body:        "Fixes #1, #2 and #3",
+			wantClosing: []string{"Issue #1", "Issue #2", "Issue #3"},
+		},
+		{
+			name:        "cross repository",
+			body:        "Closes other/lib#7",
+			wantClosing: []string{"other/lib#7 https://github.com/other/lib/issues/7"},
+		},
+		{
+			name:        "full url",
+			body:        "Resolves https://github.com/other/lib/issues/8 & https://github.com/octocat/Hello-World/pull/9",
+			wantClosing: []string{"other/lib#8 https://github.com/other/lib/issues/8", "Issue #9 https://github.com/octocat/Hello-World/issues/9"},
+		},
+		{
+			name:        "same repository spelled out",
+			body:        "Fixes octocat/hello-world#4",
+			wantClosing: []string{"Issue #4"},
+		},
+		{
+			name:           "closing and references are separate",
+			body:           "Refs #5, #6\nFixes #6",
+			wantClosing:    []string{"Issue #6"},
+			wantReferenced: []string{"Issue #5"},
+		},
+		{
+			name:           "references keyword",
+			body:           "References other/lib#10",
+			wantReferenced: []string{"other/lib#10"},
+		},
+		{
+			name:        "code is ignored",
+			body:        "Fixes #1\n```\nfixes #2\n```\nUse `closes #3` to close issues",
+			wantClosing: []string{"Issue #1"},
+		},
+		{
+			name: "other hosts are ignored",
+			body: "Fixes https://gitlab.com/a/b/issues/1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := data
+			d.PR = PullRequest{Body: tt.body}
+			assertLinks(t, "ClosingIssues()", d.ClosingIssues(), tt.wantClosing)
+			assertLinks(t, "ReferencedIssues()", d.ReferencedIssues(), tt.wantReferenced)
+			if got, want := len(d.LinkedIssues()), len(tt.wantClosing)+len(tt.wantReferenced); got != want {
+				t.Errorf("LinkedIssues() = %d links, want %d", got, want)
+			}
+		})
+	}
+}
+
+func TestLinkedIssuesEnterprise(t *testing.T) {
+	d := TemplateData{
+		Repo: Repository{FullName: "team/app", HTMLURL: "https://ghe.example.com/team/app"},
+		PR:   PullRequest{Body: "Fixes team/lib#3 and https://ghe.example.com/team/lib/issues/4"},
+	}
+	assertLinks(t, "ClosingIssues()", d.ClosingIssues(), []string{
+		"team/lib#3 https://ghe.example.com/team/lib/issues/3",
+		"team/lib#4 https://ghe.example.com/team/lib/issues/4",
+	})
+}
+
+// assertLinks compares links against "Text" or "Text URL" expectations.
+func assertLinks(t *testing.T, name string, got []IssueLink, want []string) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("%s = %+v, want %v", name, got, want)
+	}
+	for i, w := range want {
+		text, url, hasURL := strings.Cut(w, " https://")
+		if got[i].Text != text || (hasURL && got[i].URL != "https://"+url) {
+			t.Errorf("%s[%d] = %+v, want %q", name, i, got[i], w)
+		}
+	}
+}
+
 func TestLinkedIssuesFromFixture(t *testing.T) {
 	payload, err := os.ReadFile("../../testdata/pull_request_opened_with_issue.json")
 	if err != nil {
@@ -400,6 +839,9 @@ func TestButtonText(t *testing.T) {
 		{"pull_request", "View Pull Request"},
 		{"pull_request_review", "View Review"},
 		{"pull_request_review_comment", "View Comment"},
+		{"workflow_run", "View Run"},
+		{"check_suite", "View Checks"},
+		{"check_run", "View Checks"},
 	}
 
 	for _, tt := range tests {
@@ -411,3 +853,269 @@ func TestButtonText(t *testing.T) {
 		})
 	}
 }
+
+func TestShouldNotify(t *testing.T) {
+	tests := []struct {
+		name       string
+		mode       string
+		conclusion string
+		previous   string
+		want       bool
+	}{
+		{"all passes success", "all", "success", "", true},
+		{"empty mode passes success", "", "success", "", true},
+		{"failure mode passes failure", "failure", "failure", "", true},
+		{"failure mode passes timed_out", "failure", "timed_out", "", true},
+		{"failure mode drops success", "failure", "success", "failure", false},
+		{"recovery mode passes failure", "failure_and_recovery", "failure", "success", true},
+		{"recovery mode passes recovery", "failure_and_recovery", "success", "failure", true},
+		{"recovery mode drops repeated success", "failure_and_recovery", "success", "success", false},
+		{"recovery mode drops success without history", "failure_and_recovery", "success", "", false},
+		{"recovery mode drops cancelled", "failure_and_recovery", "cancelled", "failure", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := &TemplateData{
+				EventName: "workflow_run",
+				CI:        CIRun{Kind: "workflow_run", Conclusion: tt.conclusion, PreviousConclusion: tt.previous},
+			}
+			got, err := data.ShouldNotify(tt.mode)
+			if err != nil {
+				t.Fatalf("ShouldNotify() error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ShouldNotify(%q) = %v, want %v", tt.mode, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShouldNotifyIgnoresNonCIEvents(t *testing.T) {
+	data := &TemplateData{EventName: "pull_request", Action: "opened"}
+	got, err := data.ShouldNotify("failure")
+	if err != nil {
+		t.Fatalf("ShouldNotify() error: %v", err)
+	}
+	if !got {
+		t.Error("ShouldNotify() = false, want true for pull_request")
+	}
+}
+
+func TestShouldNotifyInvalidMode(t *testing.T) {
+	data := &TemplateData{CI: CIRun{Kind: "check_run"}}
+	if _, err := data.ShouldNotify("sometimes"); err == nil {
+		t.Error("ShouldNotify() expected error for invalid mode")
+	}
+}
+
+func TestPushCommitLimit(t *testing.T) {
+	commits := make([]Commit, 8)
+	tests := []struct {
+		name       string
+		limit      int
+		wantShown  int
+		wantHidden int
+	}{
+		{"default limit", 0, DefaultMaxCommits, 3},
+		{"custom limit", 2, 2, 6},
+		{"limit above count", 10, 8, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := Push{Commits: commits, Limit: tt.limit}
+			if got := len(p.ShownCommits()); got != tt.wantShown {
+				t.Errorf("len(ShownCommits()) = %d, want %d", got, tt.wantShown)
+			}
+			if got := p.HiddenCommits(); got != tt.wantHidden {
+				t.Errorf("HiddenCommits() = %d, want %d", got, tt.wantHidden)
+			}
+		})
+	}
+}
+
+func TestPushRefName(t *testing.T) {
+	tests := []struct {
+		ref     string
+		want    string
+		wantTag bool
+	}{
+		{"refs/heads/main", "main", false},
+		{"refs/heads/release/1.0", "release/1.0", false},
+		{"refs/tags/v1.0.0", "v1.0.0", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			p := Push{Ref: tt.ref}
+			if got := p.RefName(); got != tt.want {
+				t.Errorf("RefName() = %q, want %q", got, tt.want)
+			}
+			if got := p.IsTag(); got != tt.wantTag {
+				t.Errorf("IsTag() = %v, want %v", got, tt.wantTag)
+			}
+		})
+	}
+}
+
+func TestSecondaryLinks(t *testing.T) {
+	tests := []struct {
+		name string
+		data TemplateData
+		want []IssueLink
+	}{
+		{
+			name: "pull_request has none",
+			data: TemplateData{EventName: "pull_request", PR: PullRequest{HTMLURL: "https://github.com/pr/1"}},
+		},
+		{
+			name: "CI links the pull request",
+			data: TemplateData{EventName: "check_run", PR: PullRequest{HTMLURL: "https://github.com/pr/1"}},
+			want: []IssueLink{{Text: "View Pull Request", URL: "https://github.com/pr/1"}},
+		},
+		{
+			name: "release without assets links the source archive",
+			data: TemplateData{EventName: "release", Release: Release{ZipballURL: "https://api.github.com/zipball/v1"}},
+			want: []IssueLink{{Text: "Download", URL: "https://api.github.com/zipball/v1"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.data.SecondaryLinks()
+			if len(got) != len(tt.want) {
+				t.Fatalf("SecondaryLinks() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("SecondaryLinks()[%d] = %v, want %v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestIsBot(t *testing.T) {
+	tests := []struct {
+		user User
+		want bool
+	}{
+		{User{Login: "octocat", Type: "User"}, false},
+		{User{Login: "dependabot[bot]"}, true},
+		{User{Login: "renovate", Type: "Bot"}, true},
+	}
+	for _, tt := range tests {
+		if got := tt.user.IsBot(); got != tt.want {
+			t.Errorf("%+v.IsBot() = %v, want %v", tt.user, got, tt.want)
+		}
+	}
+
+	data := TemplateData{Actor: User{Login: "octocat"}, PR: PullRequest{User: User{Login: "dependabot[bot]"}}}
+	if !data.IsBot() {
+		t.Error("IsBot() = false for a bot-authored PR")
+	}
+}
+
+func TestImages(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []string
+	}{
+		{name: "no body"},
+		{
+			name: "markdown and html in order",
+			body: "Before:\n<img width=\"300\" src=\"https://example.com/before.png\">\n\nAfter:\n![after](https://example.com/after.png \"title\")",
+			want: []string{"https://example.com/before.png", "https://example.com/after.png"},
+		},
+		{
+			name: "duplicates removed",
+			body: "![a](https://example.com/a.png) ![again](https://example.com/a.png)",
+			want: []string{"https://example.com/a.png"},
+		},
+		{
+			name: "code blocks and relative paths ignored",
+			body: "```\n![x](https://example.com/x.png)\n```\n![rel](docs/y.png)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := TemplateData{PR: PullRequest{Body: tt.body}}
+			got := data.Images()
+			if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+				t.Errorf("Images() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	var body strings.Builder
+	for i := 0; i < MaxImages+2; i++ {
+		fmt.Fprintf(&body, "![](https://example.com/%d.png)\n", i)
+	}
+	data := TemplateData{PR: PullRequest{Body: body.String()}}
+	if got := data.Images(); len(got) != MaxImages {
+		t.Errorf("len(Images()) = %d, want %d", len(got), MaxImages)
+	}
+}
+
+func TestParseDiscussionCategoryChanged(t *testing.T) {
+	payload := []byte(`{
+		"event_name": "discussion",
+		"event": {
+			"action": "category_changed",
+			"discussion": {"number": 1, "category": {"name": "Q&A"}},
+			"changes": {"category": {"from": {"name": "General"}}}
+		}
+	}`)
+	data, err := Parse(payload)
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	if data.Discussion.PreviousCategory.Name != "General" {
+		t.Errorf("Discussion.PreviousCategory.Name = %q, want %q", data.Discussion.PreviousCategory.Name, "General")
+	}
+}
+
+func TestIsMergedQueue(t *testing.T) {
+	// A queue merge sends closed (merged), dequeued (MERGE) and
+	// merge_group destroyed (merged); only closed counts as the merge.
+	tests := []struct {
+		name           string
+		data           TemplateData
+		merged, landed bool
+	}{
+		{"closed merged", TemplateData{EventName: "pull_request", Action: "closed", PR: PullRequest{Merged: true}}, true, false},
+		{"dequeued after merge", TemplateData{EventName: "pull_request", Action: "dequeued", MergeGroup: MergeGroup{Reason: "MERGE"}}, false, true},
+		{"dequeued on conflict", TemplateData{EventName: "pull_request", Action: "dequeued", MergeGroup: MergeGroup{Reason: "MERGE_CONFLICT"}}, false, false},
+		{"enqueued", TemplateData{EventName: "pull_request", Action: "enqueued"}, false, false},
+		{"group merged", TemplateData{EventName: "merge_group", Action: "destroyed", MergeGroup: MergeGroup{Reason: "merged"}}, false, true},
+		{"group checks requested", TemplateData{EventName: "merge_group", Action: "checks_requested"}, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.data.IsMerged(); got != tt.merged {
+				t.Errorf("IsMerged() = %v, want %v", got, tt.merged)
+			}
+			if got := tt.data.QueueMerged(); got != tt.landed {
+				t.Errorf("QueueMerged() = %v, want %v", got, tt.landed)
+			}
+		})
+	}
+}
+
+func TestMergeGroupWithoutPRLinksQueue(t *testing.T) {
+	data := TemplateData{
+		EventName:  "merge_group",
+		Repo:       Repository{HTMLURL: "https://github.com/octocat/Hello-World"},
+		MergeGroup: MergeGroup{BaseRef: "refs/heads/main"},
+	}
+	if got := data.RelevantURL(); got != "https://github.com/octocat/Hello-World/queue/main" {
+		t.Errorf("RelevantURL() = %q", got)
+	}
+	if got := data.ButtonText(); got != "View Merge Queue" {
+		t.Errorf("ButtonText() = %q", got)
+	}
+}
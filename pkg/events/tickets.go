@@ -0,0 +1,78 @@
+package events
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// TicketPattern recognizes references to an external issue tracker such as
+// Jira, Linear or YouTrack.
+type TicketPattern struct {
+	// Name labels the tracker, e.g. "Jira". Optional.
+	Name string
+
+	Regexp *regexp.Regexp
+
+	// URL is expanded per match with regexp.Expand syntax: $0 is the whole
+	// match and $1, ${name} are capture groups.
+	URL string
+}
+
+// ExternalTicket is a ticket reference found in a pull request.
+type ExternalTicket struct {
+	Tracker string
+	Key     string
+	URL     string
+}
+
+// NewTicketPattern compiles expr into a TicketPattern.
+func NewTicketPattern(name, expr, url string) (TicketPattern, error) {
+	if expr == "" || url == "" {
+		return TicketPattern{}, fmt.Errorf("ticket pattern needs a regex and a URL")
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return TicketPattern{}, fmt.Errorf("invalid ticket regex %q: %w", expr, err)
+	}
+	return TicketPattern{Name: name, Regexp: re, URL: url}, nil
+}
+
+// ExtractTickets scans the PR title, head branch and body for each pattern
+// and stores the deduplicated matches in ExternalTickets, in order of first
+// appearance. The key is the first capture group when the regex has one,
+// otherwise the whole match.
+func (d *TemplateData) ExtractTickets(patterns []TicketPattern) {
+	d.ExternalTickets = nil
+	sources := []string{d.PR.Title, d.PR.Head.Ref, d.PR.Body}
+
+	seen := make(map[string]bool)
+	for _, p := range patterns {
+		for _, src := range sources {
+			for _, m := range p.Regexp.FindAllStringSubmatchIndex(src, -1) {
+				key := src[m[0]:m[1]]
+				if len(m) > 3 && m[2] >= 0 {
+					key = src[m[2]:m[3]]
+				}
+				id := p.Name + "\x00" + key
+				if key == "" || seen[id] {
+					continue
+				}
+				seen[id] = true
+
+				url := string(p.Regexp.ExpandString(nil, p.URL, src, m))
+				d.ExternalTickets = append(d.ExternalTickets, ExternalTicket{Tracker: p.Name, Key: key, URL: url})
+			}
+		}
+	}
+}
+
+// TicketLinks returns ExternalTickets as button links labelled with the
+// tracker name and key.
+func (d *TemplateData) TicketLinks() []IssueLink {
+	var links []IssueLink
+	for _, t := range d.ExternalTickets {
+		links = append(links, IssueLink{Text: strings.TrimSpace(t.Tracker + " " + t.Key), URL: t.URL})
+	}
+	return links
+}
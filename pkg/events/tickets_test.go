@@ -0,0 +1,58 @@
+package events
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestExtractTickets(t *testing.T) {
+	jira := TicketPattern{Name: "Jira", Regexp: regexp.MustCompile(`\b[A-Z][A-Z0-9]+-\d+\b`), URL: "https://example.atlassian.net/browse/$0"}
+	linear := TicketPattern{Name: "Linear", Regexp: regexp.MustCompile(`(?i)\blin-(\d+)\b`), URL: "https://linear.app/acme/issue/LIN-${1}"}
+
+	data := &TemplateData{PR: PullRequest{
+		Title: "PROJ-123: Add login",
+		Head:  Branch{Ref: "feature/PROJ-123-login"},
+		Body:  "Also touches OPS-7 and lin-42.\nSee PROJ-123 again.",
+	}}
+	data.ExtractTickets([]TicketPattern{jira, linear})
+
+	want := []ExternalTicket{
+		{Tracker: "Jira", Key: "PROJ-123", URL: "https://example.atlassian.net/browse/PROJ-123"},
+		{Tracker: "Jira", Key: "OPS-7", URL: "https://example.atlassian.net/browse/OPS-7"},
+		{Tracker: "Linear", Key: "42", URL: "https://linear.app/acme/issue/LIN-42"},
+	}
+	if len(data.ExternalTickets) != len(want) {
+		t.Fatalf("ExternalTickets = %+v, want %+v", data.ExternalTickets, want)
+	}
+	for i := range want {
+		if data.ExternalTickets[i] != want[i] {
+			t.Errorf("ExternalTickets[%d] = %+v, want %+v", i, data.ExternalTickets[i], want[i])
+		}
+	}
+
+	links := data.TicketLinks()
+	if links[0].Text != "Jira PROJ-123" || links[0].URL != want[0].URL {
+		t.Errorf("TicketLinks()[0] = %+v", links[0])
+	}
+}
+
+func TestExtractTicketsNoMatches(t *testing.T) {
+	p, err := NewTicketPattern("", `YT-\d+`, "https://youtrack.example.com/issue/$0")
+	if err != nil {
+		t.Fatalf("NewTicketPattern() error: %v", err)
+	}
+	data := &TemplateData{PR: PullRequest{Title: "No ticket here"}}
+	data.ExtractTickets([]TicketPattern{p})
+	if data.ExternalTickets != nil {
+		t.Errorf("ExternalTickets = %+v, want nil", data.ExternalTickets)
+	}
+}
+
+func TestNewTicketPatternInvalid(t *testing.T) {
+	if _, err := NewTicketPattern("Jira", `[A-Z`, "https://x/$0"); err == nil {
+		t.Error("NewTicketPattern() with invalid regex expected error")
+	}
+	if _, err := NewTicketPattern("Jira", `A-\d+`, ""); err == nil {
+		t.Error("NewTicketPattern() without URL expected error")
+	}
+}
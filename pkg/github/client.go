@@ -0,0 +1,269 @@
+package github
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/andoniaf/telegram-pr-notify/pkg/events"
+	"github.com/andoniaf/telegram-pr-notify/pkg/notify"
+)
+
+const apiBase = "https://api.github.com"
+
+// maxPerPage is the largest page size the REST API accepts. Only the first
+// page is fetched; notifications show a handful of items at most. Check runs
+// are the exception, as a summary must count them all.
+const maxPerPage = 100
+
+// maxCheckPages caps the check run pages fetched for a summary.
+const maxCheckPages = 10
+
+// Client is a minimal read-only GitHub REST API client.
+type Client struct {
+	token      string
+	apiURL     string
+	httpClient *http.Client
+	runID      int64
+}
+
+// NewClient creates a client authenticated with token, usually the
+// workflow's GITHUB_TOKEN.
+func NewClient(token string) *Client {
+	return &Client{
+		token:      token,
+		apiURL:     apiBase,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// WithBaseURL sets the API base URL, e.g. for GitHub Enterprise Server.
+func (c *Client) WithBaseURL(apiURL string) *Client {
+	c.apiURL = strings.TrimRight(apiURL, "/")
+	return c
+}
+
+// WithRunID sets the ID of the workflow run the action runs in, usually
+// GITHUB_RUN_ID. Its own check runs, which are still in progress while it
+// notifies, are left out of check summaries.
+func (c *Client) WithRunID(id int64) *Client {
+	c.runID = id
+	return c
+}
+
+// WithHTTPClient sets a custom http.Client, useful for testing.
+func (c *Client) WithHTTPClient(hc *http.Client) *Client {
+	c.httpClient = hc
+	return c
+}
+
+type fileResponse struct {
+	Filename  string `json:"filename"`
+	Status    string `json:"status"`
+	Additions int    `json:"additions"`
+	Deletions int    `json:"deletions"`
+}
+
+// PullRequestFiles returns up to limit files changed by a pull request.
+func (c *Client) PullRequestFiles(repo string, number, limit int) ([]events.ChangedFile, error) {
+	var resp []fileResponse
+	path := fmt.Sprintf("/repos/%s/pulls/%d/files?per_page=%d", repo, number, min(max(limit, 1), maxPerPage))
+	if err := c.get(path, &resp); err != nil {
+		return nil, err
+	}
+
+	files := make([]events.ChangedFile, 0, min(len(resp), limit))
+	for _, f := range resp {
+		if len(files) == limit {
+			break
+		}
+		files = append(files, events.ChangedFile{Filename: f.Filename, Status: f.Status, Additions: f.Additions, Deletions: f.Deletions})
+	}
+	return files, nil
+}
+
+type reviewResponse struct {
+	State string `json:"state"`
+	User  struct {
+		Login string `json:"login"`
+	} `json:"user"`
+}
+
+type requestedReviewersResponse struct {
+	Users []struct {
+		Login string `json:"login"`
+	} `json:"users"`
+	Teams []struct {
+		Slug string `json:"slug"`
+	} `json:"teams"`
+}
+
+// ReviewSummary counts the latest review of each reviewer and the pending
+// review requests of a pull request. Comments do not override an earlier
+// approval or change request, matching how GitHub shows review status.
+func (c *Client) ReviewSummary(repo string, number int) (events.ReviewSummary, error) {
+	var reviews []reviewResponse
+	if err := c.get(fmt.Sprintf("/repos/%s/pulls/%d/reviews?per_page=%d", repo, number, maxPerPage), &reviews); err != nil {
+		return events.ReviewSummary{}, err
+	}
+	var requested requestedReviewersResponse
+	if err := c.get(fmt.Sprintf("/repos/%s/pulls/%d/requested_reviewers", repo, number), &requested); err != nil {
+		return events.ReviewSummary{}, err
+	}
+
+	latest := make(map[string]string)
+	var order []string
+	for _, r := range reviews {
+		login := r.User.Login
+		prev, seen := latest[login]
+		if !seen {
+			order = append(order, login)
+		}
+		if r.State == "COMMENTED" && (prev == "APPROVED" || prev == "CHANGES_REQUESTED") {
+			continue
+		}
+		latest[login] = r.State
+	}
+
+	var summary events.ReviewSummary
+	for _, login := range order {
+		switch latest[login] {
+		case "APPROVED":
+			summary.Approved++
+		case "CHANGES_REQUESTED":
+			summary.ChangesRequested++
+		case "COMMENTED":
+			summary.Commented++
+		}
+	}
+	summary.Pending = len(requested.Users) + len(requested.Teams)
+	return summary, nil
+}
+
+type checkRunsResponse struct {
+	TotalCount int `json:"total_count"`
+	CheckRuns  []struct {
+		Status     string `json:"status"`
+		Conclusion string `json:"conclusion"`
+		DetailsURL string `json:"details_url"`
+	} `json:"check_runs"`
+}
+
+// CheckSummary counts the latest check runs reported for a commit, except
+// those of the action's own workflow run (see WithRunID). Runs past
+// maxCheckPages pages are reported as Uncounted.
+func (c *Client) CheckSummary(repo, sha string) (events.CheckSummary, error) {
+	own := ""
+	if c.runID != 0 {
+		own = fmt.Sprintf("/actions/runs/%d/", c.runID)
+	}
+
+	var summary events.CheckSummary
+	fetched, total := 0, 0
+	for page := 1; page <= maxCheckPages; page++ {
+		var resp checkRunsResponse
+		path := fmt.Sprintf("/repos/%s/commits/%s/check-runs?filter=latest&per_page=%d&page=%d", repo, url.PathEscape(sha), maxPerPage, page)
+		if err := c.get(path, &resp); err != nil {
+			return events.CheckSummary{}, err
+		}
+		for _, run := range resp.CheckRuns {
+			if own != "" && strings.Contains(run.DetailsURL, own) {
+				continue
+			}
+			summary.Add(run.Status, run.Conclusion)
+		}
+		fetched, total = fetched+len(resp.CheckRuns), resp.TotalCount
+		if len(resp.CheckRuns) < maxPerPage || fetched >= total {
+			return summary, nil
+		}
+	}
+	summary.Uncounted = total - fetched
+	return summary, nil
+}
+
+type jobsResponse struct {
+	Jobs []struct {
+		Name       string `json:"name"`
+		Conclusion string `json:"conclusion"`
+	} `json:"jobs"`
+}
+
+// FailedJobs returns the names of the failed jobs of a workflow run.
+func (c *Client) FailedJobs(repo string, runID int64) ([]string, error) {
+	var resp jobsResponse
+	if err := c.get(fmt.Sprintf("/repos/%s/actions/runs/%d/jobs?filter=latest&per_page=%d", repo, runID, maxPerPage), &resp); err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, job := range resp.Jobs {
+		if job.Conclusion == "failure" || job.Conclusion == "timed_out" {
+			names = append(names, job.Name)
+		}
+	}
+	return names, nil
+}
+
+type errorResponse struct {
+	Message string `json:"message"`
+}
+
+// PullRequestDiff returns the unified diff of a pull request. When the diff
+// is larger than maxBytes, it returns nil and ok false without reading the
+// rest.
+func (c *Client) PullRequestDiff(repo string, number int, maxBytes int64) (diff []byte, ok bool, err error) {
+	body, err := c.fetch(fmt.Sprintf("/repos/%s/pulls/%d", repo, number), "application/vnd.github.diff", maxBytes+1)
+	if err != nil {
+		return nil, false, err
+	}
+	if int64(len(body)) > maxBytes {
+		return nil, false, nil
+	}
+	return body, true, nil
+}
+
+func (c *Client) get(path string, out any) error {
+	body, err := c.fetch(path, "application/vnd.github+json", 10<<20)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("parsing response: %w", err)
+	}
+	return nil
+}
+
+// fetch performs a GET request and returns at most limit bytes of the body.
+func (c *Client) fetch(path, accept string, limit int64) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, c.apiURL+path, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Accept", accept)
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("sending request to GitHub API: %w", notify.RedactError(err, c.token))
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, limit))
+	if err != nil {
+		return nil, fmt.Errorf("reading response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var e errorResponse
+		_ = json.Unmarshal(body, &e)
+		return nil, fmt.Errorf("github API error: GET %s: %s: %s", strings.SplitN(path, "?", 2)[0], resp.Status, e.Message)
+	}
+	return body, nil
+}
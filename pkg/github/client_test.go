@@ -0,0 +1,206 @@
+package github
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newStubServer serves canned JSON responses keyed by request path.
+func newStubServer(t *testing.T, routes map[string]string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "Bearer test-token" {
+			t.Errorf("Authorization = %q", got)
+		}
+		body, ok := routes[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"message": "Not Found"}`))
+			return
+		}
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newTestClient(server *httptest.Server) *Client {
+	return NewClient("test-token").WithBaseURL(server.URL).WithHTTPClient(server.Client())
+}
+
+func TestPullRequestFiles(t *testing.T) {
+	server := newStubServer(t, map[string]string{
+		"/repos/o/r/pulls/1/files": `[
+			{"filename": "main.go", "status": "modified", "additions": 10, "deletions": 2},
+			{"filename": "README.md", "status": "added", "additions": 5, "deletions": 0},
+			{"filename": "old.go", "status": "removed", "additions": 0, "deletions": 40}
+		]`,
+	})
+
+	files, err := newTestClient(server).PullRequestFiles("o/r", 1, 2)
+	if err != nil {
+		t.Fatalf("PullRequestFiles() error: %v", err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("PullRequestFiles() = %d files, want 2", len(files))
+	}
+	if files[0].Filename != "main.go" || files[0].Additions != 10 || files[0].Deletions != 2 {
+		t.Errorf("files[0] = %+v", files[0])
+	}
+}
+
+func TestReviewSummary(t *testing.T) {
+	server := newStubServer(t, map[string]string{
+		"/repos/o/r/pulls/1/reviews": `[
+			{"state": "CHANGES_REQUESTED", "user": {"login": "alice"}},
+			{"state": "APPROVED", "user": {"login": "alice"}},
+			{"state": "COMMENTED", "user": {"login": "alice"}},
+			{"state": "APPROVED", "user": {"login": "bob"}},
+			{"state": "COMMENTED", "user": {"login": "carol"}},
+			{"state": "DISMISSED", "user": {"login": "dave"}}
+		]`,
+		"/repos/o/r/pulls/1/requested_reviewers": `{"users": [{"login": "erin"}], "teams": []}`,
+	})
+
+	summary, err := newTestClient(server).ReviewSummary("o/r", 1)
+	if err != nil {
+		t.Fatalf("ReviewSummary() error: %v", err)
+	}
+	if summary.Approved != 2 || summary.ChangesRequested != 0 || summary.Commented != 1 || summary.Pending != 1 {
+		t.Errorf("ReviewSummary() = %+v", summary)
+	}
+	if got := summary.Text(); got != "✅ 2 approvals, 💬 1 comment, ⏳ 1 pending" {
+		t.Errorf("Text() = %q", got)
+	}
+}
+
+func TestCheckSummary(t *testing.T) {
+	server := newStubServer(t, map[string]string{
+		"/repos/o/r/commits/abc123/check-runs": `{"total_count": 4, "check_runs": [
+			{"status": "completed", "conclusion": "success"},
+			{"status": "completed", "conclusion": "failure"},
+			{"status": "in_progress", "conclusion": null},
+			{"status": "completed", "conclusion": "skipped"}
+		]}`,
+	})
+
+	summary, err := newTestClient(server).CheckSummary("o/r", "abc123")
+	if err != nil {
+		t.Fatalf("CheckSummary() error: %v", err)
+	}
+	if summary.Total != 4 || summary.Passed != 1 || summary.Failed != 1 || summary.Pending != 1 || summary.Skipped != 1 {
+		t.Errorf("CheckSummary() = %+v", summary)
+	}
+	if got := summary.Badge(); got != "❌ 1 of 4 checks failed" {
+		t.Errorf("Badge() = %q", got)
+	}
+}
+
+func TestCheckSummaryPaginates(t *testing.T) {
+	const total = maxPerPage*maxCheckPages + 5
+	var pages []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page := r.URL.Query().Get("page")
+		pages = append(pages, page)
+		n := maxPerPage
+		if page == "2" && r.URL.Path == "/repos/o/r/commits/short/check-runs" {
+			n = 3
+		}
+		runs := make([]string, n)
+		for i := range runs {
+			runs[i] = `{"status": "completed", "conclusion": "success"}`
+		}
+		count := total
+		if r.URL.Path == "/repos/o/r/commits/short/check-runs" {
+			count = maxPerPage + 3
+		}
+		fmt.Fprintf(w, `{"total_count": %d, "check_runs": [%s]}`, count, strings.Join(runs, ","))
+	}))
+	defer server.Close()
+
+	summary, err := newTestClient(server).CheckSummary("o/r", "short")
+	if err != nil {
+		t.Fatalf("CheckSummary() error: %v", err)
+	}
+	if summary.Total != maxPerPage+3 || summary.Uncounted != 0 || len(pages) != 2 {
+		t.Errorf("CheckSummary() = %+v after pages %v", summary, pages)
+	}
+
+	pages = nil
+	summary, err = newTestClient(server).CheckSummary("o/r", "long")
+	if err != nil {
+		t.Fatalf("CheckSummary() error: %v", err)
+	}
+	if summary.Total != maxPerPage*maxCheckPages || summary.Uncounted != 5 || len(pages) != maxCheckPages {
+		t.Errorf("CheckSummary() = %+v after %d pages", summary, len(pages))
+	}
+	if got, want := summary.Badge(), fmt.Sprintf("✅ %d/%d checks passed (5 more not counted)", summary.Total, summary.Total); got != want {
+		t.Errorf("Badge() = %q, want %q", got, want)
+	}
+}
+
+func TestFailedJobs(t *testing.T) {
+	server := newStubServer(t, map[string]string{
+		"/repos/o/r/actions/runs/42/jobs": `{"jobs": [
+			{"name": "lint", "conclusion": "success"},
+			{"name": "test", "conclusion": "failure"},
+			{"name": "e2e", "conclusion": "timed_out"}
+		]}`,
+	})
+
+	jobs, err := newTestClient(server).FailedJobs("o/r", 42)
+	if err != nil {
+		t.Fatalf("FailedJobs() error: %v", err)
+	}
+	if strings.Join(jobs, ",") != "test,e2e" {
+		t.Errorf("FailedJobs() = %v, want [test e2e]", jobs)
+	}
+}
+
+func TestAPIError(t *testing.T) {
+	server := newStubServer(t, nil)
+
+	_, err := newTestClient(server).PullRequestFiles("o/r", 1, 10)
+	if err == nil {
+		t.Fatal("PullRequestFiles() expected error")
+	}
+	if !strings.Contains(err.Error(), "404") || !strings.Contains(err.Error(), "Not Found") {
+		t.Errorf("error = %v, want status and message", err)
+	}
+}
+
+func TestTokenRedactedFromErrors(t *testing.T) {
+	client := NewClient("secret-token").WithBaseURL("http://secret-token.invalid")
+	_, err := client.CheckSummary("o/r", "abc")
+	if err == nil {
+		t.Fatal("CheckSummary() expected error")
+	}
+	if strings.Contains(err.Error(), "secret-token") {
+		t.Errorf("error leaked token: %v", err)
+	}
+}
+
+func TestPullRequestDiff(t *testing.T) {
+	const diff = "diff --git a/a.go b/a.go\n+x\n"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Accept"); got != "application/vnd.github.diff" {
+			t.Errorf("Accept = %q", got)
+		}
+		w.Write([]byte(diff))
+	}))
+	defer server.Close()
+	client := NewClient("t").WithBaseURL(server.URL).WithHTTPClient(server.Client())
+
+	got, ok, err := client.PullRequestDiff("o/r", 1, 1000)
+	if err != nil || !ok || string(got) != diff {
+		t.Errorf("PullRequestDiff() = %q, %v, %v", got, ok, err)
+	}
+
+	got, ok, err = client.PullRequestDiff("o/r", 1, 10)
+	if err != nil || ok || got != nil {
+		t.Errorf("PullRequestDiff() over limit = %q, %v, %v; want nil, false, nil", got, ok, err)
+	}
+}
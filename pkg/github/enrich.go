@@ -0,0 +1,64 @@
+package github
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/andoniaf/telegram-pr-notify/pkg/events"
+)
+
+// DefaultMaxFiles is the number of changed files fetched when no limit is
+// configured.
+const DefaultMaxFiles = 10
+
+// Enrich fills data with context the webhook payload lacks: changed files,
+// review state and check status for pull requests, and failed job names for
+// workflow runs. Lookups are independent, so a failure leaves only its own
+// fields empty; all failures are returned joined.
+func Enrich(c *Client, data *events.TemplateData, maxFiles int) error {
+	repo := data.Repo.FullName
+	if repo == "" {
+		return nil
+	}
+	if maxFiles < 1 {
+		maxFiles = DefaultMaxFiles
+	}
+
+	var errs []error
+
+	if data.CI.Kind == "workflow_run" && data.CI.Failed() && data.CI.ID != 0 && len(data.CI.FailedJobs) == 0 {
+		jobs, err := c.FailedJobs(repo, data.CI.ID)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("fetching failed jobs: %w", err))
+		}
+		data.CI.FailedJobs = jobs
+	}
+
+	// CI events link the PR without its repository-level details; their
+	// own status is already known.
+	if data.PR.Number == 0 || data.CI.Kind != "" {
+		return errors.Join(errs...)
+	}
+
+	files, err := c.PullRequestFiles(repo, data.PR.Number, maxFiles)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("fetching changed files: %w", err))
+	}
+	data.Files = files
+
+	reviews, err := c.ReviewSummary(repo, data.PR.Number)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("fetching reviews: %w", err))
+	}
+	data.ReviewSummary = reviews
+
+	if sha := data.PR.Head.SHA; sha != "" {
+		checks, err := c.CheckSummary(repo, sha)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("fetching checks: %w", err))
+		}
+		data.Checks = checks
+	}
+
+	return errors.Join(errs...)
+}
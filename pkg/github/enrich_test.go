@@ -0,0 +1,98 @@
+package github
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/andoniaf/telegram-pr-notify/pkg/events"
+)
+
+func TestEnrichPullRequest(t *testing.T) {
+	server := newStubServer(t, map[string]string{
+		"/repos/o/r/pulls/7/files":               `[{"filename": "a.go", "additions": 1}]`,
+		"/repos/o/r/pulls/7/reviews":             `[{"state": "APPROVED", "user": {"login": "bob"}}]`,
+		"/repos/o/r/pulls/7/requested_reviewers": `{"users": [], "teams": []}`,
+		"/repos/o/r/commits/sha7/check-runs":     `{"check_runs": [{"status": "completed", "conclusion": "success"}]}`,
+	})
+
+	data := &events.TemplateData{
+		EventName: "pull_request",
+		Repo:      events.Repository{FullName: "o/r"},
+		PR:        events.PullRequest{Number: 7, Head: events.Branch{SHA: "sha7"}},
+	}
+	if err := Enrich(newTestClient(server), data, 5); err != nil {
+		t.Fatalf("Enrich() error: %v", err)
+	}
+	if len(data.Files) != 1 || data.ReviewSummary.Approved != 1 || data.Checks.State() != "success" {
+		t.Errorf("Enrich() data = files %+v, reviews %+v, checks %+v", data.Files, data.ReviewSummary, data.Checks)
+	}
+}
+
+func TestEnrichExcludesOwnRun(t *testing.T) {
+	// The notify job's own check run is still in progress while it runs.
+	server := newStubServer(t, map[string]string{
+		"/repos/o/r/pulls/7/files":               `[]`,
+		"/repos/o/r/pulls/7/reviews":             `[]`,
+		"/repos/o/r/pulls/7/requested_reviewers": `{"users": [], "teams": []}`,
+		"/repos/o/r/commits/sha7/check-runs": `{"total_count": 3, "check_runs": [
+			{"status": "completed", "conclusion": "success", "details_url": "https://github.com/o/r/actions/runs/10/job/1"},
+			{"status": "completed", "conclusion": "success", "details_url": "https://ci.example.com/build/5"},
+			{"status": "in_progress", "conclusion": null, "details_url": "https://github.com/o/r/actions/runs/11/job/2"}
+		]}`,
+	})
+
+	data := &events.TemplateData{
+		EventName: "pull_request",
+		Repo:      events.Repository{FullName: "o/r"},
+		PR:        events.PullRequest{Number: 7, Head: events.Branch{SHA: "sha7"}},
+	}
+	if err := Enrich(newTestClient(server).WithRunID(11), data, 5); err != nil {
+		t.Fatalf("Enrich() error: %v", err)
+	}
+	if got := data.Checks.Badge(); got != "✅ 2/2 checks passed" {
+		t.Errorf("Checks.Badge() = %q, want %q", got, "✅ 2/2 checks passed")
+	}
+}
+
+func TestEnrichDegradesGracefully(t *testing.T) {
+	// Only the files endpoint works; the other lookups fail.
+	server := newStubServer(t, map[string]string{
+		"/repos/o/r/pulls/7/files": `[{"filename": "a.go"}]`,
+	})
+
+	data := &events.TemplateData{
+		Repo: events.Repository{FullName: "o/r"},
+		PR:   events.PullRequest{Number: 7, Head: events.Branch{SHA: "sha7"}},
+	}
+	err := Enrich(newTestClient(server), data, 5)
+	if err == nil {
+		t.Fatal("Enrich() expected error")
+	}
+	if !strings.Contains(err.Error(), "reviews") || !strings.Contains(err.Error(), "checks") {
+		t.Errorf("Enrich() error = %v, want reviews and checks failures", err)
+	}
+	if len(data.Files) != 1 {
+		t.Errorf("Files = %+v, want the successful lookup kept", data.Files)
+	}
+}
+
+func TestEnrichWorkflowRunFailedJobs(t *testing.T) {
+	server := newStubServer(t, map[string]string{
+		"/repos/o/r/actions/runs/9/jobs": `{"jobs": [{"name": "test", "conclusion": "failure"}]}`,
+	})
+
+	data := &events.TemplateData{
+		Repo: events.Repository{FullName: "o/r"},
+		PR:   events.PullRequest{Number: 3},
+		CI:   events.CIRun{Kind: "workflow_run", ID: 9, Conclusion: "failure"},
+	}
+	if err := Enrich(newTestClient(server), data, 5); err != nil {
+		t.Fatalf("Enrich() error: %v", err)
+	}
+	if len(data.CI.FailedJobs) != 1 || data.CI.FailedJobs[0] != "test" {
+		t.Errorf("FailedJobs = %v, want [test]", data.CI.FailedJobs)
+	}
+	if data.Files != nil {
+		t.Errorf("Files = %+v, want no PR lookups for CI events", data.Files)
+	}
+}
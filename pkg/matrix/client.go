@@ -0,0 +1,178 @@
+package matrix
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/andoniaf/telegram-pr-notify/pkg/notify"
+)
+
+var _ notify.Notifier = (*Client)(nil)
+
+// Client sends HTML messages to a Matrix room through the client-server API.
+type Client struct {
+	homeserver  string
+	accessToken string
+	roomID      string
+	httpClient  *http.Client
+	txnCounter  atomic.Int64
+}
+
+type messageContent struct {
+	MsgType       string          `json:"msgtype"`
+	Body          string          `json:"body"`
+	Format        string          `json:"format"`
+	FormattedBody string          `json:"formatted_body"`
+	NewContent    *messageContent `json:"m.new_content,omitempty"`
+	RelatesTo     *relation       `json:"m.relates_to,omitempty"`
+}
+
+type relation struct {
+	RelType string `json:"rel_type"`
+	EventID string `json:"event_id"`
+}
+
+type apiResponse struct {
+	EventID string `json:"event_id"`
+	ErrCode string `json:"errcode"`
+	Error   string `json:"error"`
+}
+
+// NewClient creates a client for roomID on the given homeserver
+// (e.g. https://matrix.org).
+func NewClient(homeserver, accessToken, roomID string) *Client {
+	return &Client{
+		homeserver:  strings.TrimRight(homeserver, "/"),
+		accessToken: accessToken,
+		roomID:      roomID,
+		httpClient:  &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// WithHTTPClient sets a custom http.Client, useful for testing.
+func (c *Client) WithHTTPClient(hc *http.Client) *Client {
+	c.httpClient = hc
+	return c
+}
+
+// Send posts msg and returns its event ID. Matrix has no buttons, so they
+// are appended to the message as links. Silent messages are sent as
+// m.notice, which clients do not notify for by default.
+func (c *Client) Send(msg notify.Message) (string, error) {
+	content := render(msg)
+	if msg.ThreadID != "" {
+		content.RelatesTo = &relation{RelType: "m.thread", EventID: msg.ThreadID}
+	}
+	return c.send(content)
+}
+
+// Edit replaces a previously sent event using an m.replace relation.
+func (c *Client) Edit(id string, msg notify.Message) error {
+	newContent := render(msg)
+	content := messageContent{
+		MsgType:       newContent.MsgType,
+		Body:          "* " + newContent.Body,
+		Format:        newContent.Format,
+		FormattedBody: newContent.FormattedBody,
+		NewContent:    &newContent,
+		RelatesTo:     &relation{RelType: "m.replace", EventID: id},
+	}
+	_, err := c.send(content)
+	return err
+}
+
+func (c *Client) send(content messageContent) (string, error) {
+	body, err := json.Marshal(content)
+	if err != nil {
+		return "", fmt.Errorf("marshaling request: %w", err)
+	}
+
+	txnID := fmt.Sprintf("tpn-%d-%d", time.Now().UnixNano(), c.txnCounter.Add(1))
+	endpoint := fmt.Sprintf("%s/_matrix/client/v3/rooms/%s/send/m.room.message/%s",
+		c.homeserver, url.PathEscape(c.roomID), txnID)
+
+	req, err := http.NewRequest(http.MethodPut, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+c.accessToken)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("sending request to Matrix homeserver: %w", notify.RedactError(err, c.accessToken))
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", fmt.Errorf("reading response: %w", err)
+	}
+
+	var apiResp apiResponse
+	if err := json.Unmarshal(respBody, &apiResp); err != nil {
+		return "", &notify.StatusError{Code: resp.StatusCode, Err: fmt.Errorf("parsing response: %w", err)}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", &notify.StatusError{Code: resp.StatusCode, Err: fmt.Errorf("matrix API error: %s: %s", apiResp.ErrCode, apiResp.Error)}
+	}
+	return apiResp.EventID, nil
+}
+
+func render(msg notify.Message) messageContent {
+	text := msg.HTML()
+	formatted := lineBreaks(text)
+	plain := notify.ToPlain(text)
+
+	if buttons := msg.FlatButtons(); len(buttons) > 0 {
+		var links, plainLinks []string
+		for _, b := range buttons {
+			links = append(links, `<a href="`+html.EscapeString(b.URL)+`">`+html.EscapeString(b.Text)+`</a>`)
+			plainLinks = append(plainLinks, b.Text+": "+b.URL)
+		}
+		formatted += "<br><br>" + strings.Join(links, " · ")
+		plain += "\n\n" + strings.Join(plainLinks, "\n")
+	}
+
+	msgType := "m.text"
+	if msg.Silent {
+		msgType = "m.notice"
+	}
+	return messageContent{
+		MsgType:       msgType,
+		Body:          plain,
+		Format:        "org.matrix.custom.html",
+		FormattedBody: formatted,
+	}
+}
+
+// lineBreaks converts newlines to <br> outside <pre> blocks, since Matrix
+// clients render formatted_body as regular HTML.
+func lineBreaks(s string) string {
+	var b strings.Builder
+	for {
+		start := strings.Index(s, "<pre>")
+		if start < 0 {
+			b.WriteString(strings.ReplaceAll(s, "\n", "<br>"))
+			return b.String()
+		}
+		end := strings.Index(s[start:], "</pre>")
+		if end < 0 {
+			b.WriteString(strings.ReplaceAll(s[:start], "\n", "<br>"))
+			b.WriteString(s[start:])
+			return b.String()
+		}
+		end += start + len("</pre>")
+		b.WriteString(strings.ReplaceAll(s[:start], "\n", "<br>"))
+		b.WriteString(s[start:end])
+		s = s[end:]
+	}
+}
@@ -0,0 +1,123 @@
+package matrix
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andoniaf/telegram-pr-notify/pkg/notify"
+)
+
+func TestSend(t *testing.T) {
+	var received messageContent
+	var method, path, auth string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method, path, auth = r.Method, r.URL.EscapedPath(), r.Header.Get("Authorization")
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &received)
+		w.Write([]byte(`{"event_id": "$abc:matrix.org"}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL+"/", "syt_secret", "!room:matrix.org").WithHTTPClient(server.Client())
+
+	id, err := client.Send(notify.Message{
+		Blocks:  []string{"<b>New Pull Request</b>\n<a href=\"https://github.com/pr/1\">#1</a> Fix"},
+		Buttons: [][]notify.Button{{{Text: "View PR", URL: "https://github.com/pr/1"}}},
+	})
+	if err != nil {
+		t.Fatalf("Send() error: %v", err)
+	}
+	if id != "$abc:matrix.org" {
+		t.Errorf("Send() id = %q", id)
+	}
+	if method != http.MethodPut || !strings.HasPrefix(path, "/_matrix/client/v3/rooms/%21room:matrix.org/send/m.room.message/") {
+		t.Errorf("request = %s %s", method, path)
+	}
+	if auth != "Bearer syt_secret" {
+		t.Errorf("Authorization = %q", auth)
+	}
+
+	if received.Format != "org.matrix.custom.html" {
+		t.Errorf("Format = %q", received.Format)
+	}
+	if !strings.HasPrefix(received.FormattedBody, "<b>New Pull Request</b><br>") {
+		t.Errorf("FormattedBody = %q", received.FormattedBody)
+	}
+	if !strings.Contains(received.FormattedBody, `<a href="https://github.com/pr/1">View PR</a>`) {
+		t.Errorf("FormattedBody missing button link: %q", received.FormattedBody)
+	}
+	if !strings.Contains(received.Body, "View PR: https://github.com/pr/1") {
+		t.Errorf("Body missing button link: %q", received.Body)
+	}
+	if received.RelatesTo != nil {
+		t.Errorf("RelatesTo = %+v, want nil", received.RelatesTo)
+	}
+}
+
+func TestSendInThreadAndEdit(t *testing.T) {
+	var received []messageContent
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var c messageContent
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &c)
+		received = append(received, c)
+		w.Write([]byte(`{"event_id": "$new"}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "token", "!room:example.org").WithHTTPClient(server.Client())
+
+	if _, err := client.Send(notify.Message{Blocks: []string{"reply"}, ThreadID: "$root"}); err != nil {
+		t.Fatalf("Send() error: %v", err)
+	}
+	if err := client.Edit("$orig", notify.Message{Blocks: []string{"updated"}}); err != nil {
+		t.Fatalf("Edit() error: %v", err)
+	}
+
+	if rel := received[0].RelatesTo; rel == nil || rel.RelType != "m.thread" || rel.EventID != "$root" {
+		t.Errorf("thread relation = %+v", rel)
+	}
+	edit := received[1]
+	if edit.RelatesTo == nil || edit.RelatesTo.RelType != "m.replace" || edit.RelatesTo.EventID != "$orig" {
+		t.Errorf("edit relation = %+v", edit.RelatesTo)
+	}
+	if edit.NewContent == nil || edit.NewContent.Body != "updated" {
+		t.Errorf("m.new_content = %+v", edit.NewContent)
+	}
+}
+
+func TestSendAPIError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"errcode": "M_FORBIDDEN", "error": "not in room"}`))
+	}))
+	defer server.Close()
+
+	_, err := NewClient(server.URL, "token", "!room").WithHTTPClient(server.Client()).Send(notify.Message{Blocks: []string{"x"}})
+	if err == nil || err.Error() != "matrix API error: M_FORBIDDEN: not in room" {
+		t.Errorf("Send() error = %v", err)
+	}
+}
+
+func TestLineBreaksSkipPre(t *testing.T) {
+	got := lineBreaks("a\nb<pre>x\ny</pre>c\nd")
+	want := "a<br>b<pre>x\ny</pre>c<br>d"
+	if got != want {
+		t.Errorf("lineBreaks() = %q, want %q", got, want)
+	}
+}
+
+func TestRenderSilentUsesNotice(t *testing.T) {
+	if got := render(notify.Message{Blocks: []string{"x"}, Silent: true}).MsgType; got != "m.notice" {
+		t.Errorf("MsgType = %q, want m.notice", got)
+	}
+	if got := render(notify.Message{Blocks: []string{"x"}}).MsgType; got != "m.text" {
+		t.Errorf("MsgType = %q, want m.text", got)
+	}
+}
@@ -0,0 +1,71 @@
+package metrics
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWriteText(t *testing.T) {
+	r := NewRegistry()
+	sent := r.Counter("deliveries_total", "Deliveries by event.", "event", "destination")
+	depth := r.Gauge("queue_depth", "Queued notifications.")
+	latency := r.Histogram("api_seconds", "API latency.", []float64{0.1, 1}, "method")
+
+	sent.Inc("push", "chat -100123")
+	sent.Inc("push", "chat -100123")
+	sent.Add(3, "pull_request", `chat "quoted"`)
+	depth.Set(4)
+	depth.Add(-1)
+	latency.Observe(0.05, "sendMessage")
+	latency.Observe(0.5, "sendMessage")
+
+	var buf bytes.Buffer
+	if err := r.WriteText(&buf); err != nil {
+		t.Fatalf("WriteText() error: %v", err)
+	}
+
+	want := `# HELP deliveries_total Deliveries by event.
+# TYPE deliveries_total counter
+deliveries_total{event="pull_request",destination="chat \"quoted\""} 3
+deliveries_total{event="push",destination="chat -100123"} 2
+# HELP queue_depth Queued notifications.
+# TYPE queue_depth gauge
+queue_depth 3
+# HELP api_seconds API latency.
+# TYPE api_seconds histogram
+api_seconds_bucket{method="sendMessage",le="0.1"} 1
+api_seconds_bucket{method="sendMessage",le="1"} 2
+api_seconds_bucket{method="sendMessage",le="+Inf"} 2
+api_seconds_sum{method="sendMessage"} 0.55
+api_seconds_count{method="sendMessage"} 2
+`
+	if buf.String() != want {
+		t.Errorf("WriteText() =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestLabelCountMismatch(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Inc() with missing label values should panic")
+		}
+	}()
+	NewRegistry().Counter("c", "", "event").Inc()
+}
+
+func TestHandler(t *testing.T) {
+	r := NewRegistry()
+	r.Counter("events_total", "Events.").Inc()
+
+	rec := httptest.NewRecorder()
+	r.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	if !strings.Contains(rec.Body.String(), "events_total 1\n") {
+		t.Errorf("body = %q", rec.Body.String())
+	}
+}
@@ -0,0 +1,108 @@
+package notify
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Target is a named destination of a Fanout, such as a chat.
+type Target struct {
+	Name     string
+	Notifier Notifier
+}
+
+// Fanout is a Notifier that delivers every message to several targets
+// concurrently. Message IDs are the targets' IDs joined by commas, in target
+// order, so Edit can address each copy.
+type Fanout struct {
+	targets  []Target
+	parallel int
+}
+
+var _ Notifier = (*Fanout)(nil)
+
+// NewFanout returns a Fanout sending to at most parallel targets at a time.
+func NewFanout(targets []Target, parallel int) *Fanout {
+	if parallel < 1 {
+		parallel = 1
+	}
+	return &Fanout{targets: targets, parallel: parallel}
+}
+
+// Targets returns the fanout's targets.
+func (f *Fanout) Targets() []Target {
+	return f.targets
+}
+
+// Send posts msg to every target. Failing targets do not stop the others;
+// their errors are joined, each prefixed with the target name.
+func (f *Fanout) Send(msg Message) (string, error) {
+	return f.Each(func(_ int, n Notifier) (string, error) {
+		return n.Send(msg)
+	})
+}
+
+// Edit replaces each target's copy of a message sent with Send.
+func (f *Fanout) Edit(id string, msg Message) error {
+	ids := strings.Split(id, ",")
+	if len(ids) != len(f.targets) {
+		return fmt.Errorf("message id %q does not match %d targets", id, len(f.targets))
+	}
+	_, err := f.Each(func(i int, n Notifier) (string, error) {
+		if ids[i] == "" {
+			return "", nil
+		}
+		return "", n.Edit(ids[i], msg)
+	})
+	return err
+}
+
+// Each calls fn for every target like Deliver and aggregates the results
+// like Send.
+func (f *Fanout) Each(fn func(i int, n Notifier) (string, error)) (string, error) {
+	return JoinResults(f.Deliver(fn))
+}
+
+// Deliver calls fn for every target concurrently, at most f.parallel at a
+// time, and returns the results in target order.
+func (f *Fanout) Deliver(fn func(i int, n Notifier) (string, error)) []Result {
+	results := make([]Result, len(f.targets))
+
+	sem := make(chan struct{}, f.parallel)
+	var wg sync.WaitGroup
+	for i, t := range f.targets {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			id, err := fn(i, t.Notifier)
+			results[i] = Result{Target: t.Name, MessageID: id, Err: err}
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+// Result is the outcome of delivering a message to one target.
+type Result struct {
+	Target    string
+	MessageID string
+	Err       error
+}
+
+// JoinResults joins the message IDs of results with commas, in order, and
+// their errors, each prefixed with the target name.
+func JoinResults(results []Result) (string, error) {
+	ids := make([]string, len(results))
+	var errs []error
+	for i, r := range results {
+		ids[i] = r.MessageID
+		if r.Err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", r.Target, r.Err))
+		}
+	}
+	return strings.Join(ids, ","), errors.Join(errs...)
+}
@@ -0,0 +1,86 @@
+package notify
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type fakeNotifier struct {
+	id  string
+	err error
+
+	mu     sync.Mutex
+	edited []string
+
+	active, peak *atomic.Int32
+}
+
+func (f *fakeNotifier) Send(msg Message) (string, error) {
+	if f.active != nil {
+		n := f.active.Add(1)
+		defer f.active.Add(-1)
+		for {
+			p := f.peak.Load()
+			if n <= p || f.peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if f.err != nil {
+		return "", f.err
+	}
+	return f.id, nil
+}
+
+func (f *fakeNotifier) Edit(id string, msg Message) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.edited = append(f.edited, id)
+	return f.err
+}
+
+func TestFanoutSend(t *testing.T) {
+	a := &fakeNotifier{id: "1"}
+	b := &fakeNotifier{err: errors.New("chat not found")}
+	c := &fakeNotifier{id: "3"}
+	f := NewFanout([]Target{{"chat a", a}, {"chat b", b}, {"chat c", c}}, 2)
+
+	id, err := f.Send(Message{Blocks: []string{"hello"}})
+	if id != "1,,3" {
+		t.Errorf("Send() id = %q, want %q", id, "1,,3")
+	}
+	if err == nil || !strings.Contains(err.Error(), "chat b: chat not found") {
+		t.Errorf("Send() error = %v, want the failing target", err)
+	}
+
+	// The failed target has no copy to edit.
+	if err := f.Edit(id, Message{}); err != nil {
+		t.Errorf("Edit() error: %v", err)
+	}
+	if len(a.edited) != 1 || a.edited[0] != "1" || len(b.edited) != 0 || c.edited[0] != "3" {
+		t.Errorf("edited = %v, %v, %v", a.edited, b.edited, c.edited)
+	}
+	if err := f.Edit("1,3", Message{}); err == nil {
+		t.Error("Edit() with a mismatched id expected error")
+	}
+}
+
+func TestFanoutBoundsParallelism(t *testing.T) {
+	var active, peak atomic.Int32
+	var targets []Target
+	for i := 0; i < 6; i++ {
+		targets = append(targets, Target{Name: "t", Notifier: &fakeNotifier{id: "x", active: &active, peak: &peak}})
+	}
+
+	if _, err := NewFanout(targets, 2).Send(Message{}); err != nil {
+		t.Fatalf("Send() error: %v", err)
+	}
+	if p := peak.Load(); p > 2 || p < 1 {
+		t.Errorf("peak concurrency = %d, want at most 2", p)
+	}
+}
@@ -0,0 +1,302 @@
+package notify
+
+import (
+	"html"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	tagPattern  = regexp.MustCompile(`(?is)<(/?)([a-z][a-z0-9-]*)([^>]*)>`)
+	hrefPattern = regexp.MustCompile(`(?is)href\s*=\s*"([^"]*)"`)
+)
+
+// dialect describes how a target format expresses the Telegram HTML tags.
+type dialect struct {
+	escape func(string) string
+	wrap   func(tag, href, content string) string
+}
+
+type frame struct {
+	tag  string
+	href string
+	buf  strings.Builder
+}
+
+// convert walks the Telegram HTML in s and re-emits it in dialect d.
+// Unknown tags are dropped and their content kept.
+func convert(s string, d dialect) string {
+	stack := []*frame{{}}
+	top := func() *frame { return stack[len(stack)-1] }
+	inCode := func() bool {
+		for _, f := range stack {
+			if f.tag == "code" || f.tag == "pre" {
+				return true
+			}
+		}
+		return false
+	}
+	text := func(t string) {
+		t = html.UnescapeString(t)
+		if !inCode() {
+			t = d.escape(t)
+		}
+		top().buf.WriteString(t)
+	}
+
+	pos := 0
+	for _, m := range tagPattern.FindAllStringSubmatchIndex(s, -1) {
+		text(s[pos:m[0]])
+		pos = m[1]
+
+		closing := s[m[2]:m[3]] == "/"
+		tag := canonicalTag(strings.ToLower(s[m[4]:m[5]]))
+		if tag == "" {
+			continue
+		}
+
+		if !closing {
+			f := &frame{tag: tag}
+			if tag == "a" {
+				if h := hrefPattern.FindStringSubmatch(s[m[6]:m[7]]); h != nil {
+					f.href = html.UnescapeString(h[1])
+				}
+			}
+			stack = append(stack, f)
+			continue
+		}
+
+		// Pop up to and including the matching frame; stray closing tags
+		// are ignored.
+		for i := len(stack) - 1; i > 0; i-- {
+			if stack[i].tag != tag {
+				continue
+			}
+			for len(stack) > i {
+				f := top()
+				stack = stack[:len(stack)-1]
+				top().buf.WriteString(d.wrap(f.tag, f.href, f.buf.String()))
+			}
+			break
+		}
+	}
+	text(s[pos:])
+
+	for len(stack) > 1 {
+		f := top()
+		stack = stack[:len(stack)-1]
+		top().buf.WriteString(d.wrap(f.tag, f.href, f.buf.String()))
+	}
+	return stack[0].buf.String()
+}
+
+func canonicalTag(tag string) string {
+	switch tag {
+	case "b", "strong":
+		return "b"
+	case "i", "em":
+		return "i"
+	case "u", "ins":
+		return "u"
+	case "s", "strike", "del":
+		return "s"
+	case "code", "pre", "a", "blockquote":
+		return tag
+	}
+	return ""
+}
+
+func quoteLines(prefix, content string) string {
+	lines := strings.Split(strings.Trim(content, "\n"), "\n")
+	for i, l := range lines {
+		lines[i] = prefix + l
+	}
+	return strings.Join(lines, "\n")
+}
+
+// emphasis wraps content in marker, keeping surrounding whitespace outside
+// so Markdown parsers recognize it.
+func emphasis(marker, content string) string {
+	trimmed := strings.TrimSpace(content)
+	if trimmed == "" {
+		return content
+	}
+	lead := content[:strings.Index(content, trimmed)]
+	trail := content[len(lead)+len(trimmed):]
+	return lead + marker + trimmed + marker + trail
+}
+
+var markdownEscaper = strings.NewReplacer(`\`, `\\`, `*`, `\*`, `_`, `\_`, `~`, `\~`, "`", "\\`", `|`, `\|`, `[`, `\[`, `]`, `\]`)
+
+func markdownWrap(tag, href, content string) string {
+	switch tag {
+	case "b":
+		return emphasis("**", content)
+	case "i":
+		return emphasis("*", content)
+	case "u":
+		return emphasis("__", content)
+	case "s":
+		return emphasis("~~", content)
+	case "code":
+		return "`" + content + "`"
+	case "pre":
+		return "```\n" + strings.Trim(content, "\n") + "\n```"
+	case "a":
+		if href == "" {
+			return content
+		}
+		return "[" + content + "](" + href + ")"
+	case "blockquote":
+		return quoteLines("> ", content)
+	}
+	return content
+}
+
+// ToMarkdown converts Telegram HTML to Markdown, escaping Markdown syntax in
+// plain text. Used by Discord.
+func ToMarkdown(s string) string {
+	return convert(s, dialect{escape: markdownEscaper.Replace, wrap: markdownWrap})
+}
+
+// ToLightMarkdown converts Telegram HTML to Markdown without escaping plain
+// text, for renderers such as Adaptive Cards that do not support escapes.
+func ToLightMarkdown(s string) string {
+	return convert(s, dialect{escape: func(t string) string { return t }, wrap: markdownWrap})
+}
+
+var slackEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// ToSlack converts Telegram HTML to Slack mrkdwn.
+func ToSlack(s string) string {
+	return convert(s, dialect{
+		escape: slackEscaper.Replace,
+		wrap: func(tag, href, content string) string {
+			switch tag {
+			case "b":
+				return emphasis("*", content)
+			case "i":
+				return emphasis("_", content)
+			case "s":
+				return emphasis("~", content)
+			case "code":
+				return "`" + slackEscaper.Replace(content) + "`"
+			case "pre":
+				return "```" + slackEscaper.Replace(strings.Trim(content, "\n")) + "```"
+			case "a":
+				if href == "" {
+					return content
+				}
+				return "<" + href + "|" + content + ">"
+			case "blockquote":
+				return quoteLines("> ", content)
+			}
+			return content
+		},
+	})
+}
+
+// ToPlain strips Telegram HTML down to plain text. Links keep their URL in
+// parentheses when it differs from the link text.
+func ToPlain(s string) string {
+	return convert(s, dialect{
+		escape: func(t string) string { return t },
+		wrap: func(tag, href, content string) string {
+			switch tag {
+			case "a":
+				if href == "" || href == content {
+					return content
+				}
+				return content + " (" + href + ")"
+			case "blockquote":
+				return quoteLines("> ", content)
+			}
+			return content
+		},
+	})
+}
+
+// entityPattern matches an HTML character reference at the start of a
+// string.
+var entityPattern = regexp.MustCompile(`^&(#[0-9]+|#[xX][0-9a-fA-F]+|[a-zA-Z]+);`)
+
+// TruncateHTML shortens Telegram HTML to at most max runes of visible text,
+// which is how Telegram counts message and caption length, ending with
+// marker when cut. Tags and character references are never split, and tags
+// left open at the cut are closed before the marker.
+func TruncateHTML(s string, max int, marker string) string {
+	if visibleLen(s) <= max {
+		return s
+	}
+	budget := max - visibleLen(marker)
+	if budget < 0 {
+		budget = 0
+	}
+
+	var b strings.Builder
+	var open []string
+	pos := 0
+	for _, m := range append(tagPattern.FindAllStringSubmatchIndex(s, -1), []int{len(s), len(s)}) {
+		text, rest := cutVisible(s[pos:m[0]], budget)
+		b.WriteString(text)
+		budget -= rest
+		if budget < 0 || m[0] == len(s) {
+			break
+		}
+		b.WriteString(s[m[0]:m[1]])
+		pos = m[1]
+
+		tag := strings.ToLower(s[m[4]:m[5]])
+		if s[m[2]:m[3]] != "/" {
+			open = append(open, tag)
+			continue
+		}
+		for i := len(open) - 1; i >= 0; i-- {
+			if open[i] == tag {
+				open = open[:i]
+				break
+			}
+		}
+	}
+	for i := len(open) - 1; i >= 0; i-- {
+		b.WriteString("</" + open[i] + ">")
+	}
+	b.WriteString(marker)
+	return b.String()
+}
+
+// cutVisible returns the longest prefix of text, an HTML text run, with at
+// most budget visible runes, and how many visible runes text has; more than
+// budget means it was cut.
+func cutVisible(text string, budget int) (string, int) {
+	n, end := 0, -1
+	for i := 0; i < len(text); {
+		size := len(entityPattern.FindString(text[i:]))
+		if size == 0 {
+			_, size = utf8.DecodeRuneInString(text[i:])
+		}
+		if n == budget {
+			end = i
+		}
+		n++
+		i += size
+	}
+	if n <= budget {
+		return text, n
+	}
+	return text[:end], n
+}
+
+// visibleLen returns the number of runes of text in Telegram HTML, counting
+// each character reference as one.
+func visibleLen(s string) int {
+	n, pos := 0, 0
+	for _, m := range tagPattern.FindAllStringIndex(s, -1) {
+		_, k := cutVisible(s[pos:m[0]], 0)
+		n += k
+		pos = m[1]
+	}
+	_, k := cutVisible(s[pos:], 0)
+	return n + k
+}
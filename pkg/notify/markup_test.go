@@ -0,0 +1,220 @@
+package notify
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+const sampleHTML = `🔀 <b>New Pull Request</b>
+<a href="https://github.com/o/r/pull/42">#42</a> Fix *stars* &amp; &lt;tags&gt;
+<code>a_b</code>
+
+<blockquote>line one
+line two</blockquote>`
+
+func TestToMarkdown(t *testing.T) {
+	want := "🔀 **New Pull Request**\n" +
+		"[#42](https://github.com/o/r/pull/42) Fix \\*stars\\* & <tags>\n" +
+		"`a_b`\n\n" +
+		"> line one\n> line two"
+	if got := ToMarkdown(sampleHTML); got != want {
+		t.Errorf("ToMarkdown() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestToLightMarkdown(t *testing.T) {
+	got := ToLightMarkdown(sampleHTML)
+	if !strings.Contains(got, "Fix *stars* & <tags>") {
+		t.Errorf("ToLightMarkdown() should not escape text:\n%s", got)
+	}
+}
+
+func TestToSlack(t *testing.T) {
+	want := "🔀 *New Pull Request*\n" +
+		"<https://github.com/o/r/pull/42|#42> Fix *stars* &amp; &lt;tags&gt;\n" +
+		"`a_b`\n\n" +
+		"> line one\n> line two"
+	if got := ToSlack(sampleHTML); got != want {
+		t.Errorf("ToSlack() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestToPlain(t *testing.T) {
+	want := "🔀 New Pull Request\n" +
+		"#42 (https://github.com/o/r/pull/42) Fix *stars* & <tags>\n" +
+		"a_b\n\n" +
+		"> line one\n> line two"
+	if got := ToPlain(sampleHTML); got != want {
+		t.Errorf("ToPlain() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestConvertEdgeCases(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"emphasis keeps whitespace outside", "<b>bold </b>x", "**bold** x"},
+		{"synonym tags", "<strong>a</strong><em>b</em><del>c</del>", "**a***b*~~c~~"},
+		{"unknown tags are dropped", "<span class=\"x\">text</span>", "text"},
+		{"unclosed tag is flushed", "<b>open", "**open**"},
+		{"stray closing tag is ignored", "text</i>", "text"},
+		{"pre block", "<pre>x := 1</pre>", "```\nx := 1\n```"},
+		{"link without href", "<a>text</a>", "text"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ToMarkdown(tt.in); got != tt.want {
+				t.Errorf("ToMarkdown(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTruncateHTML(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		max  int
+		want string
+	}{
+		{"fits", "<b>short</b> &amp; sweet", 13, "<b>short</b> &amp; sweet"},
+		{"plain", "abcdefgh", 5, "abcd…"},
+		{"inside bold", "<b>New Pull Request</b> by someone", 8, "<b>New Pul</b>…"},
+		{"inside link", `see <a href="https://github.com/o/r/pull/42">pull request #42</a>`, 10, `see <a href="https://github.com/o/r/pull/42">pull </a>…`},
+		{"nested", "<blockquote><b>one two</b> three</blockquote>", 5, "<blockquote><b>one </b></blockquote>…"},
+		{"entity kept whole", "a &amp; b &lt;c&gt;", 4, "a &amp;…"},
+		{"cut at tag", "<b>abc</b><i>def</i>", 4, "<b>abc</b><i></i>…"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := TruncateHTML(tt.in, tt.max, "…")
+			if got != tt.want {
+				t.Errorf("TruncateHTML() = %q, want %q", got, tt.want)
+			}
+			if n := visibleLen(got); n > tt.max {
+				t.Errorf("visible length = %d, want at most %d", n, tt.max)
+			}
+		})
+	}
+}
+
+func TestRedactError(t *testing.T) {
+	err := errors.New(`Post "https://hooks.slack.com/services/T0/B0/secret": dial tcp: timeout`)
+	got := RedactError(err, "https://hooks.slack.com/services/T0/B0/secret")
+	if strings.Contains(got.Error(), "secret") {
+		t.Errorf("RedactError() leaked secret: %v", got)
+	}
+	if !strings.Contains(got.Error(), "[REDACTED]") {
+		t.Errorf("RedactError() = %v, want [REDACTED] marker", got)
+	}
+	if RedactError(nil, "x") != nil {
+		t.Error("RedactError(nil) should be nil")
+	}
+	if RedactError(err, "") != err {
+		t.Error("RedactError() without secrets should return the original error")
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	if got := Truncate("hello", 10, "…"); got != "hello" {
+		t.Errorf("Truncate() = %q, want %q", got, "hello")
+	}
+	if got := Truncate("hello world", 6, "…"); got != "hello…" {
+		t.Errorf("Truncate() = %q, want %q", got, "hello…")
+	}
+}
+
+func TestTemporary(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"rate limited", &StatusError{Code: 429, Err: errors.New("Too Many Requests")}, true},
+		{"server error", fmt.Errorf("chat a: %w", &StatusError{Code: 502, Err: errors.New("Bad Gateway")}), true},
+		{"network", RedactError(&url.Error{Op: "Post", URL: "https://x/bot" + testToken, Err: errors.New("timeout")}, testToken), true},
+		{"chat not found", &StatusError{Code: 400, Err: errors.New("Bad Request: chat not found")}, false},
+		{"blocked", &StatusError{Code: 403, Err: errors.New("Forbidden: bot was blocked by the user")}, false},
+		{"other", errors.New("marshaling request"), false},
+	}
+	for _, tt := range tests {
+		if got := Temporary(tt.err); got != tt.want {
+			t.Errorf("%s: Temporary() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMessageHTML(t *testing.T) {
+	msg := Message{
+		Title:  "Deploy &amp; verify",
+		Blocks: []string{"first\n", "", "second"},
+		Fields: []Field{{Name: "Branch", Value: "<code>main</code>"}, {Name: "A&B", Value: "x"}},
+	}
+
+	want := "<b>Deploy &amp; verify</b>\nfirst\n\nsecond\n\n<b>Branch:</b> <code>main</code>\n<b>A&amp;B:</b> x"
+	if got := msg.HTML(); got != want {
+		t.Errorf("HTML() =\n%q\nwant\n%q", got, want)
+	}
+	if got := msg.BodyHTML(); got != "first\n\nsecond" {
+		t.Errorf("BodyHTML() = %q", got)
+	}
+	if got := (Message{Title: "Only"}).HTML(); got != "<b>Only</b>" {
+		t.Errorf("HTML() title only = %q", got)
+	}
+}
+
+func TestMessageFlatButtons(t *testing.T) {
+	msg := Message{Buttons: [][]Button{{{Text: "a"}, {Text: "b"}}, nil, {{Text: "c"}}}}
+	got := msg.FlatButtons()
+	if len(got) != 3 || got[2].Text != "c" {
+		t.Errorf("FlatButtons() = %+v", got)
+	}
+}
+
+func TestLayout(t *testing.T) {
+	var buttons []Button
+	for _, s := range []string{"a", "b", "", "c", "d", "e"} {
+		url := ""
+		if s != "" {
+			url = "https://example.com/" + s
+		}
+		buttons = append(buttons, Button{Text: s, URL: url})
+	}
+
+	tests := []struct {
+		name string
+		rows []int
+		want string
+	}{
+		{"single row", nil, "abcde"},
+		{"max per row", []int{2}, "ab|cd|e"},
+		{"explicit rows", []int{1, 3}, "a|bcd|e"},
+		{"row larger than remaining", []int{8}, "abcde"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var rows []string
+			for _, row := range Layout(buttons, tt.rows) {
+				var s string
+				for _, b := range row {
+					s += b.Text
+				}
+				rows = append(rows, s)
+			}
+			if got := strings.Join(rows, "|"); got != tt.want {
+				t.Errorf("Layout() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	if got := Layout([]Button{{Text: "x"}}, nil); got != nil {
+		t.Errorf("Layout() without URLs = %+v, want nil", got)
+	}
+}
@@ -0,0 +1,207 @@
+package notify
+
+import (
+	"errors"
+	"html"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Button represents a link button attached to a message.
+type Button struct {
+	Text string
+	URL  string
+}
+
+// Field is a labelled value shown alongside the message body, such as
+// "Branch: main". Value uses the same HTML subset as the body.
+type Field struct {
+	Name  string
+	Value string
+}
+
+// Priority hints how prominently a backend should present a message.
+type Priority int
+
+const (
+	PriorityNormal Priority = iota
+	PriorityLow
+	PriorityHigh
+)
+
+// Message is a rendered notification ready for delivery. Title, Blocks and
+// field values use the HTML subset produced by the templates package;
+// backends that do not speak HTML convert them with the markup helpers in
+// this package.
+type Message struct {
+	// Title is an optional heading. Backends with a native title (embeds,
+	// cards, header blocks) use it; others render it in bold.
+	Title string
+
+	// Blocks are body paragraphs, rendered separated by a blank line.
+	Blocks []string
+
+	Fields []Field
+
+	// Buttons is a grid of link buttons, one slice per row.
+	Buttons [][]Button
+
+	// Images are image URLs to post with the message, which becomes their
+	// caption. Backends without media support ignore them.
+	Images []string
+
+	// ThreadID places the message in a thread: a Telegram forum topic, a
+	// Slack thread_ts, a Discord thread ID or a Matrix thread root event.
+	// Empty means the backend's configured default.
+	ThreadID string
+
+	Priority Priority
+
+	// Silent delivers the message without a sound or push notification
+	// where the backend supports it.
+	Silent bool
+
+	// Protected asks the backend to prevent forwarding and saving the
+	// message. Only Telegram supports it.
+	Protected bool
+
+	// Preview controls the link preview. The zero value disables it.
+	Preview LinkPreview
+}
+
+// LinkPreview selects the web page preview shown with a message.
+type LinkPreview struct {
+	// URL is the page to preview. Empty disables the preview.
+	URL string
+
+	// Size is PreviewSmall, PreviewLarge or empty to let the backend choose.
+	Size string
+}
+
+// Link preview sizes.
+const (
+	PreviewSmall = "small"
+	PreviewLarge = "large"
+)
+
+// Body returns the blocks and fields as HTML, without the title.
+func (m Message) Body() string {
+	parts := make([]string, 0, len(m.Blocks)+1)
+	for _, b := range m.Blocks {
+		if b = strings.TrimSpace(b); b != "" {
+			parts = append(parts, b)
+		}
+	}
+	if len(m.Fields) > 0 {
+		lines := make([]string, len(m.Fields))
+		for i, f := range m.Fields {
+			lines[i] = "<b>" + html.EscapeString(f.Name) + ":</b> " + f.Value
+		}
+		parts = append(parts, strings.Join(lines, "\n"))
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// HTML returns the whole message as a single HTML document, with the title
+// in bold on the first line.
+func (m Message) HTML() string {
+	body := m.Body()
+	if m.Title == "" {
+		return body
+	}
+	if body == "" {
+		return "<b>" + m.Title + "</b>"
+	}
+	return "<b>" + m.Title + "</b>\n" + body
+}
+
+// BodyHTML returns the blocks without title and fields, for backends that
+// render fields natively.
+func (m Message) BodyHTML() string {
+	return Message{Blocks: m.Blocks}.Body()
+}
+
+// FlatButtons returns the button grid as a single list, row by row.
+func (m Message) FlatButtons() []Button {
+	var all []Button
+	for _, row := range m.Buttons {
+		all = append(all, row...)
+	}
+	return all
+}
+
+// Notifier delivers messages to a chat backend.
+type Notifier interface {
+	// Send posts a new message and returns its backend-specific ID.
+	// Backends that cannot address sent messages return an empty ID.
+	Send(msg Message) (string, error)
+
+	// Edit replaces the content of a previously sent message.
+	Edit(id string, msg Message) error
+}
+
+// ErrEditUnsupported is returned by backends that cannot edit messages.
+var ErrEditUnsupported = errors.New("editing messages is not supported by this backend")
+
+// StatusError is an API error response with its HTTP status code, so
+// callers can tell failures worth retrying from permanent ones.
+type StatusError struct {
+	Code int
+	Err  error
+}
+
+func (e *StatusError) Error() string { return e.Err.Error() }
+
+func (e *StatusError) Unwrap() error { return e.Err }
+
+// Temporary reports whether sending again later may succeed after err: a
+// network error, a rate limit (429) or a server error (5xx). Other API
+// errors, such as a missing chat, a message that is too long or a blocked
+// bot, are permanent.
+func Temporary(err error) bool {
+	var se *StatusError
+	if errors.As(err, &se) {
+		return se.Code == http.StatusTooManyRequests || se.Code >= 500
+	}
+	var ue *url.Error
+	return errors.As(err, &ue)
+}
+
+// Truncate shortens s to at most max runes, ending with marker when cut.
+func Truncate(s string, max int, marker string) string {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	return string(runes[:max-len([]rune(marker))]) + marker
+}
+
+// Layout arranges buttons into rows. rows gives the number of buttons in
+// each row; the last size repeats for any remaining buttons, and an empty
+// rows puts everything in one row. Buttons without a URL are dropped.
+func Layout(buttons []Button, rows []int) [][]Button {
+	var kept []Button
+	for _, b := range buttons {
+		if strings.TrimSpace(b.URL) != "" {
+			kept = append(kept, b)
+		}
+	}
+	if len(kept) == 0 {
+		return nil
+	}
+	if len(rows) == 0 {
+		return [][]Button{kept}
+	}
+
+	var grid [][]Button
+	for i := 0; len(kept) > 0; i++ {
+		size := rows[min(i, len(rows)-1)]
+		if size < 1 || size > len(kept) {
+			size = len(kept)
+		}
+		grid = append(grid, kept[:size])
+		kept = kept[size:]
+	}
+	return grid
+}
@@ -0,0 +1,229 @@
+package notify
+
+import (
+	"errors"
+	"io"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// redacted replaces secrets in redacted text.
+const redacted = "[REDACTED]"
+
+// minPartial is the shortest run of the first or last characters of a
+// secret's token that is redacted on its own, as left by messages that
+// truncate a URL.
+const minPartial = 8
+
+// Redact removes secrets (tokens, webhook URLs) from s, including their
+// URL-escaped forms and leading or trailing fragments of at least
+// minPartial characters of their token (see secretPart).
+func Redact(s string, secrets ...string) string {
+	for _, secret := range secrets {
+		if secret == "" {
+			continue
+		}
+		for _, v := range encodings(secret) {
+			s = strings.ReplaceAll(s, v, redacted)
+		}
+		if part := secretPart(secret); len(part) > minPartial {
+			s = redactPartial(s, encodings(part))
+		}
+	}
+	return s
+}
+
+// secretPart returns the token in secret that fragments are matched
+// against: a URL's last path segment, or what follows a bot token's
+// numeric ID. Schemes, hosts and IDs are not secret and show up in
+// unrelated text, so they are only redacted as part of the whole secret.
+func secretPart(secret string) string {
+	if i := strings.Index(secret, "://"); i >= 0 {
+		rest := strings.TrimRight(secret[i+3:], "/")
+		j := strings.LastIndexByte(rest, '/')
+		if j < 0 {
+			return ""
+		}
+		return rest[j+1:]
+	}
+	if _, token, ok := strings.Cut(secret, ":"); ok {
+		return token
+	}
+	return secret
+}
+
+// encodings returns the forms secret may take in a URL or error message.
+func encodings(secret string) []string {
+	forms := []string{secret}
+	add := func(v string) {
+		for _, f := range forms {
+			if f == v {
+				return
+			}
+		}
+		forms = append(forms, v)
+	}
+	add(url.PathEscape(secret))
+	add(url.QueryEscape(secret))
+	add(lowerEscapes(url.QueryEscape(secret)))
+	return forms
+}
+
+// lowerEscapes lowercases the hex digits of percent escapes in s.
+func lowerEscapes(s string) string {
+	b := []byte(s)
+	for i := 0; i+2 < len(b); i++ {
+		if b[i] == '%' {
+			b[i+1] = toLower(b[i+1])
+			b[i+2] = toLower(b[i+2])
+			i += 2
+		}
+	}
+	return string(b)
+}
+
+func toLower(c byte) byte {
+	if c >= 'A' && c <= 'Z' {
+		return c + 'a' - 'A'
+	}
+	return c
+}
+
+// redactPartial replaces runs of s that continue the first minPartial
+// characters of any of forms, or lead up to its last minPartial characters.
+func redactPartial(s string, forms []string) string {
+	mask := make([]bool, len(s))
+	for _, form := range forms {
+		if len(form) > minPartial {
+			markPartial(mask, s, form)
+		}
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if !mask[i] {
+			b.WriteByte(s[i])
+			continue
+		}
+		b.WriteString(redacted)
+		for i+1 < len(s) && mask[i+1] {
+			i++
+		}
+	}
+	return b.String()
+}
+
+// markPartial sets mask for the runs of s that redactPartial replaces for
+// secret.
+func markPartial(mask []bool, s, secret string) {
+	head, tail := secret[:minPartial], secret[len(secret)-minPartial:]
+
+	for off := 0; ; {
+		i := strings.Index(s[off:], head)
+		if i < 0 {
+			break
+		}
+		start, n := off+i, minPartial
+		for n < len(secret) && start+n < len(s) && s[start+n] == secret[n] {
+			n++
+		}
+		for j := start; j < start+n; j++ {
+			mask[j] = true
+		}
+		off = start + n
+	}
+	for off := 0; ; {
+		i := strings.Index(s[off:], tail)
+		if i < 0 {
+			break
+		}
+		end, n := off+i+minPartial, minPartial
+		for n < len(secret) && end-n > 0 && s[end-n-1] == secret[len(secret)-n-1] {
+			n++
+		}
+		for j := end - n; j < end; j++ {
+			mask[j] = true
+		}
+		off = end
+	}
+}
+
+// RedactError removes secrets (tokens, webhook URLs) from an error and
+// every error it wraps, to prevent log leakage. Links whose message holds a
+// secret are replaced by redacted copies: a *url.Error and a *StatusError
+// keep their type, so errors.As still finds them, and other errors become a
+// RedactedError. Links without a secret, such as sentinel errors, are kept,
+// so errors.Is keeps working.
+func RedactError(err error, secrets ...string) error {
+	if err == nil {
+		return nil
+	}
+	msg := Redact(err.Error(), secrets...)
+	if msg == err.Error() {
+		return err
+	}
+
+	switch e := err.(type) {
+	case *url.Error:
+		return &url.Error{Op: e.Op, URL: Redact(e.URL, secrets...), Err: RedactError(e.Err, secrets...)}
+	case *StatusError:
+		return &StatusError{Code: e.Code, Err: RedactError(e.Err, secrets...)}
+	}
+
+	r := &RedactedError{msg: msg}
+	switch e := err.(type) {
+	case interface{ Unwrap() error }:
+		r.err = RedactError(e.Unwrap(), secrets...)
+	case interface{ Unwrap() []error }:
+		var errs []error
+		for _, inner := range e.Unwrap() {
+			errs = append(errs, RedactError(inner, secrets...))
+		}
+		r.err = errors.Join(errs...)
+	}
+	return r
+}
+
+// RedactedError is an error whose message has had secrets removed. It
+// unwraps to the redacted errors it wrapped.
+type RedactedError struct {
+	err error
+	msg string
+}
+
+func (e *RedactedError) Error() string { return e.msg }
+
+func (e *RedactedError) Unwrap() error { return e.err }
+
+// RedactWriter is an io.Writer that removes secrets from everything written
+// through it. Secrets may be added while it is in use.
+type RedactWriter struct {
+	w io.Writer
+
+	mu      sync.Mutex
+	secrets []string
+}
+
+// NewRedactWriter returns a RedactWriter writing to w.
+func NewRedactWriter(w io.Writer, secrets ...string) *RedactWriter {
+	return &RedactWriter{w: w, secrets: secrets}
+}
+
+// Add registers more secrets to remove.
+func (r *RedactWriter) Add(secrets ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.secrets = append(r.secrets, secrets...)
+}
+
+// Write writes p to the underlying writer with secrets removed. A secret
+// split across two writes is not redacted; callers write whole lines.
+func (r *RedactWriter) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, err := io.WriteString(r.w, Redact(string(p), r.secrets...)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,162 @@
+package queue
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/andoniaf/telegram-pr-notify/pkg/notify"
+)
+
+// Journal is an append-only file of queue operations, so jobs that were
+// queued but not delivered survive a restart. Each line is a JSON record:
+// a job added, delivered, failed once more, or dropped after a permanent
+// failure.
+type Journal struct {
+	path    string
+	secrets []string
+
+	mu       sync.Mutex
+	f        *os.File
+	pending  []Job
+	attempts map[string]int
+}
+
+type record struct {
+	Op    string `json:"op"`
+	Job   *Job   `json:"job,omitempty"`
+	ID    string `json:"id,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// Journal operations.
+const (
+	opAdd  = "add"
+	opDone = "done"
+	opFail = "fail"
+	opDrop = "drop"
+)
+
+// OpenJournal replays the journal at path, creating it if missing. Jobs
+// added but neither delivered, dropped nor failed maxAttempts times are
+// pending. When nothing is pending the file is truncated, so it does not
+// grow without bound. secrets are removed from the errors it records, as
+// the file may be cached or uploaded.
+func OpenJournal(path string, maxAttempts int, secrets ...string) (*Journal, error) {
+	j := &Journal{path: path, secrets: secrets, attempts: make(map[string]int)}
+
+	f, err := os.Open(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+	case err != nil:
+		return nil, fmt.Errorf("reading queue journal: %w", err)
+	default:
+		err := j.replay(f, maxAttempts)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("creating queue journal directory: %w", err)
+		}
+	}
+	flags := os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	if len(j.pending) == 0 {
+		flags |= os.O_TRUNC
+	}
+	if j.f, err = os.OpenFile(path, flags, 0o600); err != nil {
+		return nil, fmt.Errorf("opening queue journal: %w", err)
+	}
+	return j, nil
+}
+
+func (j *Journal) replay(f *os.File, maxAttempts int) error {
+	var order []string
+	jobs := make(map[string]Job)
+
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 0, 64*1024), 4<<20)
+	for line := 1; sc.Scan(); line++ {
+		var r record
+		if err := json.Unmarshal(sc.Bytes(), &r); err != nil {
+			// A crash mid-write leaves a partial last line; skip it.
+			continue
+		}
+		switch r.Op {
+		case opAdd:
+			if r.Job == nil {
+				return fmt.Errorf("queue journal %s line %d: add without a job", j.path, line)
+			}
+			if _, ok := jobs[r.Job.ID]; !ok {
+				order = append(order, r.Job.ID)
+			}
+			jobs[r.Job.ID] = *r.Job
+		case opDone, opDrop:
+			delete(jobs, r.ID)
+		case opFail:
+			j.attempts[r.ID]++
+			if maxAttempts > 0 && j.attempts[r.ID] >= maxAttempts {
+				delete(jobs, r.ID)
+			}
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return fmt.Errorf("reading queue journal: %w", err)
+	}
+
+	for _, id := range order {
+		if job, ok := jobs[id]; ok {
+			job.Attempts = j.attempts[id]
+			j.pending = append(j.pending, job)
+		}
+	}
+	return nil
+}
+
+// Pending returns the jobs replayed from the journal, in the order they
+// were added.
+func (j *Journal) Pending() []Job {
+	return j.pending
+}
+
+func (j *Journal) add(job Job) error {
+	return j.write(record{Op: opAdd, Job: &job})
+}
+
+func (j *Journal) done(id string) error {
+	return j.write(record{Op: opDone, ID: id})
+}
+
+func (j *Journal) fail(id string, err error) error {
+	return j.write(record{Op: opFail, ID: id, Error: notify.RedactError(err, j.secrets...).Error()})
+}
+
+func (j *Journal) drop(id string, err error) error {
+	return j.write(record{Op: opDrop, ID: id, Error: notify.RedactError(err, j.secrets...).Error()})
+}
+
+func (j *Journal) write(r record) error {
+	line, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("marshaling queue record: %w", err)
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if _, err := j.f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("writing queue journal: %w", err)
+	}
+	return j.f.Sync()
+}
+
+// Close closes the journal file.
+func (j *Journal) Close() error {
+	return j.f.Close()
+}
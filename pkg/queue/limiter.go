@@ -0,0 +1,115 @@
+package queue
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Rate is a token bucket: Burst sends at once, refilled at one per Every.
+type Rate struct {
+	Every time.Duration
+	Burst int
+}
+
+// Telegram's documented limits for bots.
+var (
+	// ChatRate is about one message per second in any chat.
+	ChatRate = Rate{Every: time.Second, Burst: 1}
+	// GroupRate is at most 20 messages per minute in a group or channel.
+	GroupRate = Rate{Every: 3 * time.Second, Burst: 20}
+)
+
+// IsGroup reports whether a Telegram chat ID names a group or channel:
+// negative IDs and public @usernames.
+func IsGroup(chat string) bool {
+	return strings.HasPrefix(chat, "-") || strings.HasPrefix(chat, "@")
+}
+
+// Limiter rate limits sends per chat, with GroupRate applied on top of
+// ChatRate for groups and channels.
+type Limiter struct {
+	chat, group Rate
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// NewLimiter returns a limiter applying chat to every chat, and also group
+// to groups and channels. A zero Rate does not limit.
+func NewLimiter(chat, group Rate) *Limiter {
+	return &Limiter{chat: chat, group: group, buckets: make(map[string]*bucket)}
+}
+
+// Wait blocks until chat may be sent to, and takes a token from its
+// buckets, or returns ctx's error.
+func (l *Limiter) Wait(ctx context.Context, chat string) error {
+	for {
+		delay := l.reserve(chat)
+		if delay == 0 {
+			return nil
+		}
+		t := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// reserve takes a token from each of chat's buckets if all have one, and
+// otherwise returns how long to wait before trying again.
+func (l *Limiter) reserve(chat string) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	type limit struct {
+		b    *bucket
+		rate Rate
+	}
+	limits := []limit{{l.bucket("chat:"+chat, l.chat), l.chat}}
+	if IsGroup(chat) {
+		limits = append(limits, limit{l.bucket("group:"+chat, l.group), l.group})
+	}
+
+	now := time.Now()
+	var wait time.Duration
+	for _, lim := range limits {
+		if lim.rate.Every <= 0 {
+			continue
+		}
+		lim.b.tokens += float64(now.Sub(lim.b.last)) / float64(lim.rate.Every)
+		lim.b.tokens = min(lim.b.tokens, float64(lim.rate.Burst))
+		lim.b.last = now
+		if lim.b.tokens < 1 {
+			d := time.Duration((1 - lim.b.tokens) * float64(lim.rate.Every))
+			wait = max(wait, d, time.Millisecond)
+		}
+	}
+	if wait > 0 {
+		return wait
+	}
+	for _, lim := range limits {
+		if lim.rate.Every > 0 {
+			lim.b.tokens--
+		}
+	}
+	return 0
+}
+
+func (l *Limiter) bucket(key string, rate Rate) *bucket {
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(rate.Burst), last: time.Now()}
+		l.buckets[key] = b
+	}
+	return b
+}
@@ -0,0 +1,192 @@
+// Package queue delivers notifications through a worker pool with
+// per-chat rate limiting, ordering per key, and an optional journal so
+// undelivered jobs survive a restart.
+package queue
+
+import (
+	"context"
+	"sync"
+
+	"github.com/andoniaf/telegram-pr-notify/pkg/notify"
+)
+
+// DefaultMaxAttempts is how many failed deliveries a journaled job gets
+// before it is dropped.
+const DefaultMaxAttempts = 3
+
+// Job is one queued notification.
+type Job struct {
+	ID string `json:"id"`
+
+	// Target names the destination, as in notify.Target.
+	Target string `json:"target"`
+
+	// Chat is the chat that rate limits apply to.
+	Chat string `json:"chat"`
+
+	// Key orders jobs: jobs with the same key, such as a PR's notifications
+	// to one chat, are sent one at a time in the order they were added.
+	Key string `json:"key"`
+
+	Message notify.Message `json:"message"`
+
+	// Attempts counts earlier failed deliveries, from the journal.
+	Attempts int `json:"-"`
+}
+
+// Result is the outcome of delivering a job.
+type Result struct {
+	Job       Job
+	MessageID string
+	Err       error
+}
+
+// Options configure a Queue.
+type Options struct {
+	// Workers is how many keys are delivered concurrently; at least 1.
+	Workers int
+
+	// ChatRate limits every chat, and GroupRate groups and channels on top.
+	// Zero rates do not limit.
+	ChatRate, GroupRate Rate
+
+	// Journal, when set, records jobs until they are delivered. Its pending
+	// jobs are queued first.
+	Journal *Journal
+
+	// OnDepth, when set, is called with the number of jobs waiting to be
+	// sent whenever it changes.
+	OnDepth func(depth int)
+}
+
+// Queue holds jobs until Run delivers them.
+type Queue struct {
+	opts    Options
+	limiter *Limiter
+
+	mu    sync.Mutex
+	jobs  []Job
+	depth int
+}
+
+// New returns a queue holding the journal's pending jobs, if any.
+func New(opts Options) *Queue {
+	if opts.Workers < 1 {
+		opts.Workers = 1
+	}
+	q := &Queue{opts: opts, limiter: NewLimiter(opts.ChatRate, opts.GroupRate)}
+	if opts.Journal != nil {
+		q.jobs = append(q.jobs, opts.Journal.Pending()...)
+	}
+	q.setDepth(len(q.jobs))
+	return q
+}
+
+// Add queues jobs after those already queued, recording them in the
+// journal first.
+func (q *Queue) Add(jobs ...Job) error {
+	for _, job := range jobs {
+		if q.opts.Journal != nil {
+			if err := q.opts.Journal.add(job); err != nil {
+				return err
+			}
+		}
+		q.mu.Lock()
+		q.jobs = append(q.jobs, job)
+		q.mu.Unlock()
+		q.addDepth(1)
+	}
+	return nil
+}
+
+// Len returns the number of queued jobs.
+func (q *Queue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.jobs)
+}
+
+// Run delivers every queued job with send and returns the results in queue
+// order. Jobs with the same key go one at a time, in order, even when one
+// fails; other keys are delivered by up to Workers goroutines. A job that
+// fails with a temporary error (see notify.Temporary) stays in the journal
+// for a later run; other failures would fail again, so the job is dropped.
+// When ctx is done, the jobs not yet sent fail with its error and stay
+// queued in the journal.
+func (q *Queue) Run(ctx context.Context, send func(Job) (string, error)) []Result {
+	q.mu.Lock()
+	jobs := q.jobs
+	q.jobs = nil
+	q.mu.Unlock()
+
+	// Group job indexes by key, in order of first appearance.
+	var keys []string
+	groups := make(map[string][]int)
+	for i, job := range jobs {
+		if _, ok := groups[job.Key]; !ok {
+			keys = append(keys, job.Key)
+		}
+		groups[job.Key] = append(groups[job.Key], i)
+	}
+
+	results := make([]Result, len(jobs))
+	work := make(chan []int)
+	var wg sync.WaitGroup
+	for range min(q.opts.Workers, len(keys)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for group := range work {
+				for _, i := range group {
+					results[i] = q.deliver(ctx, jobs[i], send)
+				}
+			}
+		}()
+	}
+	for _, k := range keys {
+		work <- groups[k]
+	}
+	close(work)
+	wg.Wait()
+	return results
+}
+
+func (q *Queue) deliver(ctx context.Context, job Job, send func(Job) (string, error)) Result {
+	if err := q.limiter.Wait(ctx, job.Chat); err != nil {
+		return Result{Job: job, Err: err}
+	}
+	id, err := send(job)
+	if j := q.opts.Journal; j != nil {
+		// Delivery already happened; a journal write error only risks a
+		// duplicate on the next run, so it does not fail the job.
+		switch {
+		case err == nil:
+			j.done(job.ID)
+		case notify.Temporary(err):
+			j.fail(job.ID, err)
+		default:
+			j.drop(job.ID, err)
+		}
+	}
+	q.addDepth(-1)
+	return Result{Job: job, MessageID: id, Err: err}
+}
+
+func (q *Queue) setDepth(n int) {
+	q.mu.Lock()
+	q.depth = n
+	q.mu.Unlock()
+	if q.opts.OnDepth != nil {
+		q.opts.OnDepth(n)
+	}
+}
+
+func (q *Queue) addDepth(delta int) {
+	q.mu.Lock()
+	q.depth += delta
+	n := q.depth
+	q.mu.Unlock()
+	if q.opts.OnDepth != nil {
+		q.opts.OnDepth(n)
+	}
+}
@@ -0,0 +1,236 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/andoniaf/telegram-pr-notify/pkg/notify"
+)
+
+func job(id, chat, key string) Job {
+	return Job{ID: id, Target: "chat " + chat, Chat: chat, Key: key, Message: notify.Message{Blocks: []string{id}}}
+}
+
+func TestRunOrdersByKey(t *testing.T) {
+	q := New(Options{Workers: 4})
+	q.Add(
+		job("1", "a", "repo#1"),
+		job("2", "b", "repo#2"),
+		job("3", "a", "repo#1"),
+		job("4", "a", "repo#1"),
+	)
+
+	var mu sync.Mutex
+	var sent []string
+	results := q.Run(context.Background(), func(j Job) (string, error) {
+		time.Sleep(time.Millisecond)
+		mu.Lock()
+		defer mu.Unlock()
+		sent = append(sent, j.ID)
+		return "m" + j.ID, nil
+	})
+
+	var order []string
+	for _, id := range sent {
+		if id != "2" {
+			order = append(order, id)
+		}
+	}
+	if strings.Join(order, ",") != "1,3,4" {
+		t.Errorf("repo#1 sent in order %v, want 1,3,4", order)
+	}
+	for i, r := range results {
+		if want := fmt.Sprintf("m%d", i+1); r.MessageID != want || r.Err != nil {
+			t.Errorf("result %d = %+v, want message %s", i, r, want)
+		}
+	}
+	if q.Len() != 0 {
+		t.Errorf("Len() = %d after Run, want 0", q.Len())
+	}
+}
+
+func TestRunWorkers(t *testing.T) {
+	q := New(Options{Workers: 2})
+	for i := range 6 {
+		q.Add(job(fmt.Sprint(i), "chat", fmt.Sprint(i)))
+	}
+
+	var active, peak atomic.Int32
+	q.Run(context.Background(), func(Job) (string, error) {
+		n := active.Add(1)
+		defer active.Add(-1)
+		for p := peak.Load(); n > p && !peak.CompareAndSwap(p, n); p = peak.Load() {
+		}
+		time.Sleep(10 * time.Millisecond)
+		return "", nil
+	})
+	if p := peak.Load(); p != 2 {
+		t.Errorf("peak concurrency = %d, want 2", p)
+	}
+}
+
+func TestRunRateLimit(t *testing.T) {
+	q := New(Options{Workers: 3, ChatRate: Rate{Every: 30 * time.Millisecond, Burst: 1}})
+	q.Add(job("1", "-100", "a"), job("2", "-100", "b"), job("3", "-100", "c"), job("4", "other", "d"))
+
+	var mu sync.Mutex
+	times := map[string]time.Duration{}
+	start := time.Now()
+	q.Run(context.Background(), func(j Job) (string, error) {
+		mu.Lock()
+		times[j.ID] = time.Since(start)
+		mu.Unlock()
+		return "", nil
+	})
+
+	if elapsed := time.Since(start); elapsed < 60*time.Millisecond {
+		t.Errorf("three sends to one chat took %v, want at least 60ms", elapsed)
+	}
+	if times["4"] > 20*time.Millisecond {
+		t.Errorf("another chat waited %v, want no wait", times["4"])
+	}
+}
+
+func TestLimiterGroupRate(t *testing.T) {
+	l := NewLimiter(Rate{}, Rate{Every: time.Hour, Burst: 2})
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	for i := range 2 {
+		if err := l.Wait(ctx, "-100123"); err != nil {
+			t.Fatalf("Wait() %d error: %v", i, err)
+		}
+	}
+	if err := l.Wait(ctx, "-100123"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("third Wait() = %v, want the group limit to block", err)
+	}
+	if err := l.Wait(ctx, "12345"); err != nil {
+		t.Errorf("Wait() for a private chat = %v, want no group limit", err)
+	}
+}
+
+func TestRunCanceled(t *testing.T) {
+	q := New(Options{ChatRate: Rate{Every: time.Hour, Burst: 1}})
+	q.Add(job("1", "a", "k"), job("2", "a", "k"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	results := q.Run(ctx, func(Job) (string, error) { return "1", nil })
+	if results[0].Err != nil || !errors.Is(results[1].Err, context.DeadlineExceeded) {
+		t.Errorf("results = %+v, want the second job to time out", results)
+	}
+}
+
+func TestJournal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queue", "journal.jsonl")
+
+	j, err := OpenJournal(path, 2)
+	if err != nil {
+		t.Fatalf("OpenJournal() error: %v", err)
+	}
+	var depths []int
+	q := New(Options{Journal: j, OnDepth: func(n int) { depths = append(depths, n) }})
+	q.Add(job("1", "a", "k1"), job("2", "a", "k2"))
+	q.Run(context.Background(), func(j Job) (string, error) {
+		if j.ID == "2" {
+			return "", &notify.StatusError{Code: 429, Err: errors.New("Too Many Requests")}
+		}
+		return "10", nil
+	})
+	j.Close()
+	if fmt.Sprint(depths) != "[0 1 2 1 0]" {
+		t.Errorf("depths = %v", depths)
+	}
+
+	// A restart picks up the failed job, with its attempt counted.
+	j, err = OpenJournal(path, 2)
+	if err != nil {
+		t.Fatalf("OpenJournal() error: %v", err)
+	}
+	pending := j.Pending()
+	if len(pending) != 1 || pending[0].ID != "2" || pending[0].Attempts != 1 || pending[0].Message.Blocks[0] != "2" {
+		t.Fatalf("Pending() = %+v, want job 2 after one attempt", pending)
+	}
+	q = New(Options{Journal: j})
+	if q.Len() != 1 {
+		t.Errorf("Len() = %d, want the journaled job queued", q.Len())
+	}
+	q.Run(context.Background(), func(Job) (string, error) {
+		return "", &notify.StatusError{Code: 502, Err: errors.New("Bad Gateway")}
+	})
+	j.Close()
+
+	// The second failure reaches the limit: the job is dropped and the
+	// journal truncated.
+	j, err = OpenJournal(path, 2)
+	if err != nil {
+		t.Fatalf("OpenJournal() error: %v", err)
+	}
+	defer j.Close()
+	if len(j.Pending()) != 0 {
+		t.Errorf("Pending() = %+v, want none", j.Pending())
+	}
+	if info, _ := os.Stat(path); info.Size() != 0 {
+		t.Errorf("journal size = %d, want truncated", info.Size())
+	}
+}
+
+func TestJournalDropsPermanentFailures(t *testing.T) {
+	const token = "123456789:AAHdqTcvCH1vGWJxfSeofSAs0K5PALDsaw"
+	path := filepath.Join(t.TempDir(), "journal.jsonl")
+
+	j, err := OpenJournal(path, DefaultMaxAttempts, token)
+	if err != nil {
+		t.Fatalf("OpenJournal() error: %v", err)
+	}
+	q := New(Options{Journal: j})
+	q.Add(job("1", "a", "k1"), job("2", "b", "k2"), job("3", "c", "k3"))
+	q.Run(context.Background(), func(j Job) (string, error) {
+		switch j.ID {
+		case "1":
+			return "", &notify.StatusError{Code: 400, Err: errors.New("Bad Request: chat not found")}
+		case "2":
+			return "", &notify.StatusError{Code: 403, Err: errors.New("Forbidden: bot was blocked by the user")}
+		}
+		return "", fmt.Errorf("Post \"https://api.telegram.org/bot%s/sendMessage\": %w", token, &url.Error{Op: "Post", Err: errors.New("timeout")})
+	})
+	j.Close()
+
+	b, _ := os.ReadFile(path)
+	if strings.Contains(string(b), "AAHdqTcv") {
+		t.Errorf("journal leaks the token:\n%s", b)
+	}
+
+	// Only the network failure is retried.
+	j, err = OpenJournal(path, DefaultMaxAttempts)
+	if err != nil {
+		t.Fatalf("OpenJournal() error: %v", err)
+	}
+	defer j.Close()
+	if pending := j.Pending(); len(pending) != 1 || pending[0].ID != "3" {
+		t.Errorf("Pending() = %+v, want job 3", pending)
+	}
+}
+
+func TestJournalSkipsPartialLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.jsonl")
+	os.WriteFile(path, []byte(`{"op":"add","job":{"id":"1","chat":"a","key":"k"}}`+"\n"+`{"op":"done","i`), 0o600)
+
+	j, err := OpenJournal(path, DefaultMaxAttempts)
+	if err != nil {
+		t.Fatalf("OpenJournal() error: %v", err)
+	}
+	defer j.Close()
+	if len(j.Pending()) != 1 {
+		t.Errorf("Pending() = %+v, want job 1", j.Pending())
+	}
+}
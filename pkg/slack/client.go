@@ -0,0 +1,234 @@
+package slack
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/andoniaf/telegram-pr-notify/pkg/notify"
+)
+
+const apiBase = "https://slack.com/api"
+
+const (
+	maxSectionText  = 3000
+	maxHeaderText   = 150
+	maxFieldText    = 2000
+	maxFields       = 10
+	maxButtonText   = 75
+	maxActionsBlock = 25
+)
+
+var _ notify.Notifier = (*Client)(nil)
+
+// Client posts Block Kit messages either through an incoming webhook or
+// through chat.postMessage with a bot token.
+type Client struct {
+	webhookURL string
+	token      string
+	channel    string
+	apiURL     string
+	httpClient *http.Client
+}
+
+type messageRequest struct {
+	Channel     string  `json:"channel,omitempty"`
+	TS          string  `json:"ts,omitempty"`
+	ThreadTS    string  `json:"thread_ts,omitempty"`
+	Text        string  `json:"text"`
+	Blocks      []block `json:"blocks"`
+	UnfurlLinks bool    `json:"unfurl_links"`
+	UnfurlMedia bool    `json:"unfurl_media"`
+}
+
+type block struct {
+	Type     string    `json:"type"`
+	Text     *textObj  `json:"text,omitempty"`
+	Fields   []textObj `json:"fields,omitempty"`
+	Elements []element `json:"elements,omitempty"`
+}
+
+type textObj struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type element struct {
+	Type string  `json:"type"`
+	Text textObj `json:"text"`
+	URL  string  `json:"url"`
+}
+
+type apiResponse struct {
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+	TS    string `json:"ts,omitempty"`
+}
+
+// NewWebhookClient creates a client that posts to an incoming webhook.
+// Webhook messages cannot be edited.
+func NewWebhookClient(webhookURL string) *Client {
+	return &Client{
+		webhookURL: webhookURL,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// NewClient creates a client that posts to channel with a bot token.
+func NewClient(token, channel string) *Client {
+	return &Client{
+		token:      token,
+		channel:    channel,
+		apiURL:     apiBase,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// WithHTTPClient sets a custom http.Client, useful for testing.
+func (c *Client) WithHTTPClient(hc *http.Client) *Client {
+	c.httpClient = hc
+	return c
+}
+
+// Send posts msg and returns its timestamp, which Slack uses as message ID.
+// Webhook clients return an empty ID.
+func (c *Client) Send(msg notify.Message) (string, error) {
+	req := c.request(msg)
+	req.ThreadTS = msg.ThreadID
+
+	if c.webhookURL != "" {
+		return "", c.postWebhook(req)
+	}
+
+	req.Channel = c.channel
+	resp, err := c.call("chat.postMessage", req)
+	if err != nil {
+		return "", err
+	}
+	return resp.TS, nil
+}
+
+// Edit updates a message previously sent with a bot token.
+func (c *Client) Edit(id string, msg notify.Message) error {
+	if c.webhookURL != "" {
+		return notify.ErrEditUnsupported
+	}
+	req := c.request(msg)
+	req.Channel = c.channel
+	req.TS = id
+	_, err := c.call("chat.update", req)
+	return err
+}
+
+func (c *Client) request(msg notify.Message) messageRequest {
+	var blocks []block
+	if msg.Title != "" {
+		blocks = append(blocks, block{
+			Type: "header",
+			Text: &textObj{Type: "plain_text", Text: notify.Truncate(notify.ToPlain(msg.Title), maxHeaderText, "…")},
+		})
+	}
+
+	if body := msg.BodyHTML(); body != "" {
+		blocks = append(blocks, block{
+			Type: "section",
+			Text: &textObj{Type: "mrkdwn", Text: notify.Truncate(notify.ToSlack(body), maxSectionText, "…")},
+		})
+	}
+
+	if len(msg.Fields) > 0 {
+		fields := block{Type: "section"}
+		for i, f := range msg.Fields {
+			if i == maxFields {
+				break
+			}
+			text := "*" + slackEscape(f.Name) + "*\n" + notify.ToSlack(f.Value)
+			fields.Fields = append(fields.Fields, textObj{Type: "mrkdwn", Text: notify.Truncate(text, maxFieldText, "…")})
+		}
+		blocks = append(blocks, fields)
+	}
+
+	for _, row := range msg.Buttons {
+		if len(row) == 0 {
+			continue
+		}
+		actions := block{Type: "actions"}
+		for i, b := range row {
+			if i == maxActionsBlock {
+				break
+			}
+			actions.Elements = append(actions.Elements, element{
+				Type: "button",
+				Text: textObj{Type: "plain_text", Text: notify.Truncate(b.Text, maxButtonText, "…")},
+				URL:  b.URL,
+			})
+		}
+		blocks = append(blocks, actions)
+	}
+
+	// The top-level text is only shown in notifications and as a fallback.
+	fallback, _, _ := strings.Cut(notify.ToPlain(msg.HTML()), "\n")
+	return messageRequest{Text: fallback, Blocks: blocks}
+}
+
+func slackEscape(s string) string {
+	return notify.ToSlack(html.EscapeString(s))
+}
+
+func (c *Client) postWebhook(req messageRequest) error {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("marshaling request: %w", err)
+	}
+
+	resp, err := c.httpClient.Post(c.webhookURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("sending request to Slack webhook: %w", notify.RedactError(err, c.webhookURL))
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode != http.StatusOK {
+		return &notify.StatusError{Code: resp.StatusCode, Err: fmt.Errorf("slack webhook error: %s: %s", resp.Status, strings.TrimSpace(string(respBody)))}
+	}
+	return nil
+}
+
+func (c *Client) call(method string, req messageRequest) (*apiResponse, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling request: %w", err)
+	}
+
+	httpReq, err := http.NewRequest(http.MethodPost, c.apiURL+"/"+method, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json; charset=utf-8")
+	httpReq.Header.Set("Authorization", "Bearer "+c.token)
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("sending request to Slack API: %w", notify.RedactError(err, c.token))
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("reading response: %w", err)
+	}
+
+	var apiResp apiResponse
+	if err := json.Unmarshal(respBody, &apiResp); err != nil {
+		return nil, &notify.StatusError{Code: resp.StatusCode, Err: fmt.Errorf("parsing response: %w", err)}
+	}
+	if !apiResp.OK {
+		return nil, &notify.StatusError{Code: resp.StatusCode, Err: fmt.Errorf("slack API error: %s", apiResp.Error)}
+	}
+	return &apiResp, nil
+}
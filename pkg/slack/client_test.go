@@ -0,0 +1,169 @@
+package slack
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andoniaf/telegram-pr-notify/pkg/notify"
+)
+
+func TestWebhookSend(t *testing.T) {
+	var received messageRequest
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &received)
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	client := NewWebhookClient(server.URL).WithHTTPClient(server.Client())
+
+	id, err := client.Send(notify.Message{
+		Blocks:  []string{"<b>New Pull Request</b>\n<a href=\"https://github.com/pr/1\">#1</a> Fix"},
+		Buttons: [][]notify.Button{{{Text: "View PR", URL: "https://github.com/pr/1"}}},
+	})
+	if err != nil {
+		t.Fatalf("Send() error: %v", err)
+	}
+	if id != "" {
+		t.Errorf("Send() id = %q, want empty for webhooks", id)
+	}
+
+	if received.Text != "New Pull Request" {
+		t.Errorf("Text = %q, want %q", received.Text, "New Pull Request")
+	}
+	if len(received.Blocks) != 2 {
+		t.Fatalf("len(Blocks) = %d, want 2", len(received.Blocks))
+	}
+	section := received.Blocks[0]
+	if section.Type != "section" || section.Text.Type != "mrkdwn" {
+		t.Errorf("first block = %+v, want mrkdwn section", section)
+	}
+	if !strings.Contains(section.Text.Text, "*New Pull Request*") || !strings.Contains(section.Text.Text, "<https://github.com/pr/1|#1>") {
+		t.Errorf("section text = %q", section.Text.Text)
+	}
+	actions := received.Blocks[1]
+	if actions.Type != "actions" || len(actions.Elements) != 1 || actions.Elements[0].URL != "https://github.com/pr/1" {
+		t.Errorf("actions block = %+v", actions)
+	}
+}
+
+func TestWebhookError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("no_service"))
+	}))
+	defer server.Close()
+
+	_, err := NewWebhookClient(server.URL).WithHTTPClient(server.Client()).Send(notify.Message{Blocks: []string{"x"}})
+	if err == nil || !strings.Contains(err.Error(), "no_service") {
+		t.Errorf("Send() error = %v, want no_service", err)
+	}
+}
+
+func TestWebhookEditUnsupported(t *testing.T) {
+	err := NewWebhookClient("http://localhost").Edit("1", notify.Message{Blocks: []string{"x"}})
+	if !errors.Is(err, notify.ErrEditUnsupported) {
+		t.Errorf("Edit() error = %v, want ErrEditUnsupported", err)
+	}
+}
+
+func TestPostMessageAndUpdate(t *testing.T) {
+	var paths []string
+	var received messageRequest
+	var auth string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		auth = r.Header.Get("Authorization")
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &received)
+		w.Write([]byte(`{"ok": true, "ts": "1700000000.000100"}`))
+	}))
+	defer server.Close()
+
+	client := NewClient("xoxb-secret", "C123").WithHTTPClient(server.Client())
+	client.apiURL = server.URL
+
+	id, err := client.Send(notify.Message{Blocks: []string{"Hello"}, ThreadID: "1699999999.000001"})
+	if err != nil {
+		t.Fatalf("Send() error: %v", err)
+	}
+	if id != "1700000000.000100" {
+		t.Errorf("Send() id = %q", id)
+	}
+	if received.Channel != "C123" || received.ThreadTS != "1699999999.000001" {
+		t.Errorf("request = %+v, want channel C123 in thread", received)
+	}
+	if auth != "Bearer xoxb-secret" {
+		t.Errorf("Authorization = %q", auth)
+	}
+
+	if err := client.Edit(id, notify.Message{Blocks: []string{"Updated"}}); err != nil {
+		t.Fatalf("Edit() error: %v", err)
+	}
+	if received.TS != id {
+		t.Errorf("update ts = %q, want %q", received.TS, id)
+	}
+	if strings.Join(paths, ",") != "/chat.postMessage,/chat.update" {
+		t.Errorf("paths = %v", paths)
+	}
+}
+
+func TestPostMessageAPIError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"ok": false, "error": "channel_not_found"}`))
+	}))
+	defer server.Close()
+
+	client := NewClient("xoxb-secret", "C123").WithHTTPClient(server.Client())
+	client.apiURL = server.URL
+
+	_, err := client.Send(notify.Message{Blocks: []string{"x"}})
+	if err == nil || err.Error() != "slack API error: channel_not_found" {
+		t.Errorf("Send() error = %v", err)
+	}
+}
+
+func TestWebhookRedactsURL(t *testing.T) {
+	client := NewWebhookClient("http://127.0.0.1:1/services/T0/B0/secret")
+	_, err := client.Send(notify.Message{Blocks: []string{"x"}})
+	if err == nil {
+		t.Fatal("Send() expected connection error")
+	}
+	if strings.Contains(err.Error(), "secret") {
+		t.Errorf("error leaks webhook URL: %v", err)
+	}
+}
+
+func TestRequestStructuredMessage(t *testing.T) {
+	req := NewWebhookClient("http://localhost").request(notify.Message{
+		Title:   "Deploy <b>failed</b>",
+		Blocks:  []string{"body"},
+		Fields:  []notify.Field{{Name: "Env", Value: "prod"}},
+		Buttons: [][]notify.Button{{{Text: "A", URL: "https://a"}}, {{Text: "B", URL: "https://b"}}},
+	})
+
+	var types []string
+	for _, b := range req.Blocks {
+		types = append(types, b.Type)
+	}
+	if got := strings.Join(types, ","); got != "header,section,section,actions,actions" {
+		t.Fatalf("block types = %s", got)
+	}
+	if req.Blocks[0].Text.Text != "Deploy failed" {
+		t.Errorf("header = %q, want plain text", req.Blocks[0].Text.Text)
+	}
+	if req.Blocks[2].Fields[0].Text != "*Env*\nprod" {
+		t.Errorf("field = %q", req.Blocks[2].Fields[0].Text)
+	}
+	if req.Text != "Deploy failed" {
+		t.Errorf("fallback text = %q, want the title", req.Text)
+	}
+}
@@ -0,0 +1,112 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// Store is a small JSON file that carries state between action runs.
+// Persist the file between workflow runs with actions/cache or similar.
+type Store struct {
+	path string
+	data fileData
+}
+
+type fileData struct {
+	// CI maps a CIRun key to the conclusion of the last completed run.
+	CI map[string]string `json:"ci,omitempty"`
+
+	// Messages maps a key, such as a PR's pinned announcement, to the ID of
+	// a message sent earlier.
+	Messages map[string]string `json:"messages,omitempty"`
+
+	// Topics maps a repository or PR to the forum topic created for it.
+	Topics map[string]string `json:"topics,omitempty"`
+}
+
+// Open loads the store at path. A missing file yields an empty store.
+func Open(path string) (*Store, error) {
+	s := &Store{path: path}
+	raw, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading state file: %w", err)
+	}
+	if err := json.Unmarshal(raw, &s.data); err != nil {
+		return nil, fmt.Errorf("parsing state file %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// Save writes the store back to disk atomically.
+func (s *Store) Save() error {
+	raw, err := json.MarshalIndent(s.data, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling state: %w", err)
+	}
+
+	if dir := filepath.Dir(s.path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("creating state directory: %w", err)
+		}
+	}
+
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, raw, 0o600); err != nil {
+		return fmt.Errorf("writing state file: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("replacing state file: %w", err)
+	}
+	return nil
+}
+
+// LastConclusion returns the recorded conclusion for a CI run key.
+func (s *Store) LastConclusion(key string) string {
+	return s.data.CI[key]
+}
+
+// SetConclusion records the conclusion of the latest CI run for key.
+func (s *Store) SetConclusion(key, conclusion string) {
+	if s.data.CI == nil {
+		s.data.CI = make(map[string]string)
+	}
+	s.data.CI[key] = conclusion
+}
+
+// Message returns the message ID recorded for key, or "" if none.
+func (s *Store) Message(key string) string {
+	return s.data.Messages[key]
+}
+
+// SetMessage records the message ID for key.
+func (s *Store) SetMessage(key, messageID string) {
+	if s.data.Messages == nil {
+		s.data.Messages = make(map[string]string)
+	}
+	s.data.Messages[key] = messageID
+}
+
+// DeleteMessage forgets the message recorded for key.
+func (s *Store) DeleteMessage(key string) {
+	delete(s.data.Messages, key)
+}
+
+// Topic returns the forum topic ID recorded for key, or "" if none.
+func (s *Store) Topic(key string) string {
+	return s.data.Topics[key]
+}
+
+// SetTopic records the forum topic ID for key.
+func (s *Store) SetTopic(key, topicID string) {
+	if s.data.Topics == nil {
+		s.data.Topics = make(map[string]string)
+	}
+	s.data.Topics[key] = topicID
+}
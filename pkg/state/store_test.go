@@ -0,0 +1,95 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOpenMissingFile(t *testing.T) {
+	s, err := Open(filepath.Join(t.TempDir(), "missing.json"))
+	if err != nil {
+		t.Fatalf("Open() error: %v", err)
+	}
+	if got := s.LastConclusion("any"); got != "" {
+		t.Errorf("LastConclusion() = %q, want empty", got)
+	}
+}
+
+func TestSaveAndReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "state.json")
+
+	s, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() error: %v", err)
+	}
+	s.SetConclusion("octocat/Hello-World:workflow_run:CI:main", "failure")
+	if err := s.Save(); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+
+	reopened, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() error: %v", err)
+	}
+	if got := reopened.LastConclusion("octocat/Hello-World:workflow_run:CI:main"); got != "failure" {
+		t.Errorf("LastConclusion() = %q, want %q", got, "failure")
+	}
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Error("temporary file was left behind")
+	}
+}
+
+func TestOpenInvalidFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	if err := os.WriteFile(path, []byte("not json"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Open(path); err == nil {
+		t.Error("Open() expected error for invalid JSON")
+	}
+}
+
+func TestMessages(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	s, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() error: %v", err)
+	}
+	s.SetMessage("pin:octocat/Hello-World#7", "42")
+	if err := s.Save(); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+
+	reopened, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() error: %v", err)
+	}
+	if got := reopened.Message("pin:octocat/Hello-World#7"); got != "42" {
+		t.Errorf("Message() = %q, want 42", got)
+	}
+	reopened.DeleteMessage("pin:octocat/Hello-World#7")
+	if got := reopened.Message("pin:octocat/Hello-World#7"); got != "" {
+		t.Errorf("Message() after delete = %q, want empty", got)
+	}
+}
+
+func TestTopics(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	s, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() error: %v", err)
+	}
+	s.SetTopic("repo:octocat/Hello-World", "55")
+	if err := s.Save(); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+
+	reopened, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() error: %v", err)
+	}
+	if got := reopened.Topic("repo:octocat/Hello-World"); got != "55" {
+		t.Errorf("Topic() = %q, want 55", got)
+	}
+}
@@ -0,0 +1,150 @@
+package teams
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/andoniaf/telegram-pr-notify/pkg/notify"
+)
+
+var _ notify.Notifier = (*Client)(nil)
+
+// Client posts Adaptive Cards to a Microsoft Teams incoming webhook or
+// Workflows (Power Automate) webhook URL.
+type Client struct {
+	webhookURL string
+	httpClient *http.Client
+}
+
+type webhookRequest struct {
+	Type        string       `json:"type"`
+	Attachments []attachment `json:"attachments"`
+}
+
+type attachment struct {
+	ContentType string       `json:"contentType"`
+	Content     adaptiveCard `json:"content"`
+}
+
+type adaptiveCard struct {
+	Schema  string       `json:"$schema"`
+	Type    string       `json:"type"`
+	Version string       `json:"version"`
+	Body    []cardBlock  `json:"body"`
+	Actions []cardAction `json:"actions,omitempty"`
+}
+
+type cardBlock struct {
+	Type    string `json:"type"`
+	Text    string `json:"text,omitempty"`
+	Wrap    bool   `json:"wrap,omitempty"`
+	Spacing string `json:"spacing,omitempty"`
+	Size    string `json:"size,omitempty"`
+	Weight  string `json:"weight,omitempty"`
+	Color   string `json:"color,omitempty"`
+	Facts   []fact `json:"facts,omitempty"`
+}
+
+type fact struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+}
+
+type cardAction struct {
+	Type  string `json:"type"`
+	Title string `json:"title"`
+	URL   string `json:"url"`
+}
+
+// NewClient creates a client for the webhook URL.
+func NewClient(webhookURL string) *Client {
+	return &Client{
+		webhookURL: webhookURL,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// WithHTTPClient sets a custom http.Client, useful for testing.
+func (c *Client) WithHTTPClient(hc *http.Client) *Client {
+	c.httpClient = hc
+	return c
+}
+
+// Send posts msg as an Adaptive Card. Webhooks return no message ID and
+// do not support threads, so msg.ThreadID is ignored.
+func (c *Client) Send(msg notify.Message) (string, error) {
+	body, err := json.Marshal(request(msg))
+	if err != nil {
+		return "", fmt.Errorf("marshaling request: %w", err)
+	}
+
+	resp, err := c.httpClient.Post(c.webhookURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("sending request to Teams webhook: %w", notify.RedactError(err, c.webhookURL))
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return "", &notify.StatusError{Code: resp.StatusCode, Err: fmt.Errorf("teams webhook error: %s: %s", resp.Status, strings.TrimSpace(string(respBody)))}
+	}
+	return "", nil
+}
+
+// Edit is not supported by Teams webhooks.
+func (c *Client) Edit(id string, msg notify.Message) error {
+	return notify.ErrEditUnsupported
+}
+
+func request(msg notify.Message) webhookRequest {
+	card := adaptiveCard{
+		Schema:  "http://adaptivecards.io/schemas/adaptive-card.json",
+		Type:    "AdaptiveCard",
+		Version: "1.4",
+	}
+
+	if msg.Title != "" {
+		title := cardBlock{Type: "TextBlock", Text: notify.ToPlain(msg.Title), Wrap: true, Size: "Medium", Weight: "Bolder"}
+		if msg.Priority == notify.PriorityHigh {
+			title.Color = "Attention"
+		}
+		card.Body = append(card.Body, title)
+	}
+
+	// TextBlocks do not reliably honour single newlines, so each line gets
+	// its own block; blank lines become extra spacing.
+	spacing := "None"
+	for _, line := range strings.Split(notify.ToLightMarkdown(msg.BodyHTML()), "\n") {
+		if strings.TrimSpace(line) == "" {
+			spacing = "Medium"
+			continue
+		}
+		card.Body = append(card.Body, cardBlock{Type: "TextBlock", Text: line, Wrap: true, Spacing: spacing})
+		spacing = "None"
+	}
+
+	if len(msg.Fields) > 0 {
+		facts := cardBlock{Type: "FactSet", Spacing: "Medium"}
+		for _, f := range msg.Fields {
+			facts.Facts = append(facts.Facts, fact{Title: f.Name, Value: notify.ToLightMarkdown(f.Value)})
+		}
+		card.Body = append(card.Body, facts)
+	}
+
+	for _, b := range msg.FlatButtons() {
+		card.Actions = append(card.Actions, cardAction{Type: "Action.OpenUrl", Title: b.Text, URL: b.URL})
+	}
+
+	return webhookRequest{
+		Type: "message",
+		Attachments: []attachment{{
+			ContentType: "application/vnd.microsoft.card.adaptive",
+			Content:     card,
+		}},
+	}
+}
@@ -0,0 +1,97 @@
+package teams
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andoniaf/telegram-pr-notify/pkg/notify"
+)
+
+func TestSend(t *testing.T) {
+	var received webhookRequest
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &received)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL).WithHTTPClient(server.Client())
+
+	id, err := client.Send(notify.Message{
+		Blocks:  []string{"<b>New Pull Request</b>\n<a href=\"https://github.com/pr/1\">#1</a> Fix\n\nby octocat"},
+		Buttons: [][]notify.Button{{{Text: "View PR", URL: "https://github.com/pr/1"}}},
+	})
+	if err != nil {
+		t.Fatalf("Send() error: %v", err)
+	}
+	if id != "" {
+		t.Errorf("Send() id = %q, want empty", id)
+	}
+
+	if received.Type != "message" || len(received.Attachments) != 1 {
+		t.Fatalf("request = %+v", received)
+	}
+	att := received.Attachments[0]
+	if att.ContentType != "application/vnd.microsoft.card.adaptive" {
+		t.Errorf("ContentType = %q", att.ContentType)
+	}
+	body := att.Content.Body
+	if len(body) != 3 {
+		t.Fatalf("len(Body) = %d, want 3: %+v", len(body), body)
+	}
+	if body[0].Text != "**New Pull Request**" || body[1].Text != "[#1](https://github.com/pr/1) Fix" {
+		t.Errorf("Body = %+v", body)
+	}
+	if body[1].Spacing != "None" || body[2].Spacing != "Medium" {
+		t.Errorf("spacing = %q, %q, want None, Medium", body[1].Spacing, body[2].Spacing)
+	}
+	if len(att.Content.Actions) != 1 || att.Content.Actions[0].Type != "Action.OpenUrl" || att.Content.Actions[0].URL != "https://github.com/pr/1" {
+		t.Errorf("Actions = %+v", att.Content.Actions)
+	}
+}
+
+func TestSendError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Invalid webhook request"))
+	}))
+	defer server.Close()
+
+	_, err := NewClient(server.URL).WithHTTPClient(server.Client()).Send(notify.Message{Blocks: []string{"x"}})
+	if err == nil || !strings.Contains(err.Error(), "Invalid webhook request") {
+		t.Errorf("Send() error = %v", err)
+	}
+}
+
+func TestEditUnsupported(t *testing.T) {
+	if err := NewClient("http://localhost").Edit("1", notify.Message{}); !errors.Is(err, notify.ErrEditUnsupported) {
+		t.Errorf("Edit() error = %v, want ErrEditUnsupported", err)
+	}
+}
+
+func TestRequestStructuredMessage(t *testing.T) {
+	req := request(notify.Message{
+		Title:    "CI failed",
+		Blocks:   []string{"body"},
+		Fields:   []notify.Field{{Name: "Branch", Value: "<code>main</code>"}},
+		Priority: notify.PriorityHigh,
+	})
+
+	body := req.Attachments[0].Content.Body
+	if len(body) != 3 {
+		t.Fatalf("body = %+v, want title, text and facts", body)
+	}
+	if body[0].Text != "CI failed" || body[0].Weight != "Bolder" || body[0].Color != "Attention" {
+		t.Errorf("title block = %+v", body[0])
+	}
+	if body[2].Type != "FactSet" || body[2].Facts[0].Value != "`main`" {
+		t.Errorf("facts block = %+v", body[2])
+	}
+}
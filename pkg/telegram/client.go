@@ -5,22 +5,26 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"mime/multipart"
 	"net/http"
 	"strconv"
-	"strings"
 	"time"
+
+	"github.com/andoniaf/telegram-pr-notify/pkg/notify"
 )
 
 const apiBase = "https://api.telegram.org"
 
 const telegramMaxMessageLength = 4096
+const telegramMaxCaptionLength = 1024
+const maxMediaGroupSize = 10
+const telegramMaxTopicNameLength = 128
 const truncationMarker = "\n\n[message truncated]"
 
 // Button represents an inline keyboard button.
-type Button struct {
-	Text string
-	URL  string
-}
+type Button = notify.Button
+
+var _ notify.Notifier = (*Client)(nil)
 
 type Client struct {
 	botToken   string
@@ -31,12 +35,103 @@ type Client struct {
 }
 
 type sendMessageRequest struct {
-	ChatID                string       `json:"chat_id"`
-	Text                  string       `json:"text"`
-	ParseMode             string       `json:"parse_mode"`
-	DisableWebPagePreview bool         `json:"disable_web_page_preview"`
-	MessageThreadID       *int         `json:"message_thread_id,omitempty"`
-	ReplyMarkup           *replyMarkup `json:"reply_markup,omitempty"`
+	ChatID              string              `json:"chat_id"`
+	Text                string              `json:"text"`
+	ParseMode           string              `json:"parse_mode"`
+	LinkPreviewOptions  *linkPreviewOptions `json:"link_preview_options"`
+	DisableNotification bool                `json:"disable_notification,omitempty"`
+	ProtectContent      bool                `json:"protect_content,omitempty"`
+	MessageThreadID     *int                `json:"message_thread_id,omitempty"`
+	ReplyMarkup         *replyMarkup        `json:"reply_markup,omitempty"`
+}
+
+type editMessageRequest struct {
+	ChatID             string              `json:"chat_id"`
+	MessageID          int                 `json:"message_id"`
+	Text               string              `json:"text"`
+	ParseMode          string              `json:"parse_mode"`
+	LinkPreviewOptions *linkPreviewOptions `json:"link_preview_options"`
+	ReplyMarkup        *replyMarkup        `json:"reply_markup,omitempty"`
+}
+
+type linkPreviewOptions struct {
+	IsDisabled       bool   `json:"is_disabled,omitempty"`
+	URL              string `json:"url,omitempty"`
+	PreferSmallMedia bool   `json:"prefer_small_media,omitempty"`
+	PreferLargeMedia bool   `json:"prefer_large_media,omitempty"`
+}
+
+type sendPhotoRequest struct {
+	ChatID              string       `json:"chat_id"`
+	Photo               string       `json:"photo"`
+	Caption             string       `json:"caption,omitempty"`
+	ParseMode           string       `json:"parse_mode,omitempty"`
+	DisableNotification bool         `json:"disable_notification,omitempty"`
+	ProtectContent      bool         `json:"protect_content,omitempty"`
+	MessageThreadID     *int         `json:"message_thread_id,omitempty"`
+	ReplyMarkup         *replyMarkup `json:"reply_markup,omitempty"`
+}
+
+type sendMediaGroupRequest struct {
+	ChatID              string            `json:"chat_id"`
+	Media               []inputMediaPhoto `json:"media"`
+	DisableNotification bool              `json:"disable_notification,omitempty"`
+	ProtectContent      bool              `json:"protect_content,omitempty"`
+	MessageThreadID     *int              `json:"message_thread_id,omitempty"`
+}
+
+type inputMediaPhoto struct {
+	Type      string `json:"type"`
+	Media     string `json:"media"`
+	Caption   string `json:"caption,omitempty"`
+	ParseMode string `json:"parse_mode,omitempty"`
+}
+
+type editCaptionRequest struct {
+	ChatID      string       `json:"chat_id"`
+	MessageID   int          `json:"message_id"`
+	Caption     string       `json:"caption"`
+	ParseMode   string       `json:"parse_mode"`
+	ReplyMarkup *replyMarkup `json:"reply_markup,omitempty"`
+}
+
+type pinMessageRequest struct {
+	ChatID              string `json:"chat_id"`
+	MessageID           int    `json:"message_id"`
+	DisableNotification bool   `json:"disable_notification,omitempty"`
+}
+
+type setReactionRequest struct {
+	ChatID    string         `json:"chat_id"`
+	MessageID int            `json:"message_id"`
+	Reaction  []reactionType `json:"reaction"`
+}
+
+type reactionType struct {
+	Type  string `json:"type"`
+	Emoji string `json:"emoji"`
+}
+
+type createTopicRequest struct {
+	ChatID    string `json:"chat_id"`
+	Name      string `json:"name"`
+	IconColor int    `json:"icon_color,omitempty"`
+}
+
+type topicRequest struct {
+	ChatID          string `json:"chat_id"`
+	MessageThreadID int    `json:"message_thread_id"`
+}
+
+type chatRequest struct {
+	ChatID string `json:"chat_id"`
+	UserID int64  `json:"user_id,omitempty"`
+}
+
+type chatActionRequest struct {
+	ChatID          string `json:"chat_id"`
+	Action          string `json:"action"`
+	MessageThreadID *int   `json:"message_thread_id,omitempty"`
 }
 
 type replyMarkup struct {
@@ -49,8 +144,25 @@ type inlineButton struct {
 }
 
 type apiResponse struct {
-	OK          bool   `json:"ok"`
-	Description string `json:"description,omitempty"`
+	OK          bool            `json:"ok"`
+	Description string          `json:"description,omitempty"`
+	Result      json.RawMessage `json:"result,omitempty"`
+}
+
+type replyParameters struct {
+	MessageID                int  `json:"message_id"`
+	AllowSendingWithoutReply bool `json:"allow_sending_without_reply"`
+}
+
+type messageResult struct {
+	MessageID int `json:"message_id"`
+}
+
+func (r messageResult) id() string {
+	if r.MessageID == 0 {
+		return ""
+	}
+	return strconv.Itoa(r.MessageID)
 }
 
 // NewClient creates a new Telegram client.
@@ -74,46 +186,438 @@ func (c *Client) WithHTTPClient(hc *http.Client) *Client {
 
 // SendMessage sends an HTML message with optional inline keyboard buttons.
 func (c *Client) SendMessage(text string, buttons []Button) error {
-	if len([]rune(text)) > telegramMaxMessageLength {
-		runes := []rune(text)
-		maxLen := telegramMaxMessageLength - len([]rune(truncationMarker))
-		text = string(runes[:maxLen]) + truncationMarker
+	msg := notify.Message{Blocks: []string{text}}
+	if len(buttons) > 0 {
+		msg.Buttons = [][]Button{buttons}
+	}
+	_, err := c.Send(msg)
+	return err
+}
+
+// Send sends msg as HTML and returns its message ID. msg.ThreadID overrides
+// the client's topic ID; silent messages are sent without a notification
+// and protected ones cannot be forwarded or saved. Messages with images are
+// sent with SendMediaGroup.
+func (c *Client) Send(msg notify.Message) (string, error) {
+	if len(msg.Images) > 0 {
+		return c.SendMediaGroup(msg)
 	}
 
 	req := sendMessageRequest{
-		ChatID:                c.chatID,
-		Text:                  text,
-		ParseMode:             "HTML",
-		DisableWebPagePreview: true,
+		ChatID:              c.chatID,
+		Text:                truncate(msg.HTML()),
+		ParseMode:           "HTML",
+		LinkPreviewOptions:  linkPreview(msg.Preview),
+		DisableNotification: msg.Silent,
+		ProtectContent:      msg.Protected,
+		ReplyMarkup:         keyboard(msg.Buttons),
 	}
 
-	if c.topicID != "" {
-		tid, err := strconv.Atoi(c.topicID)
-		if err != nil {
-			return fmt.Errorf("invalid topic_id %q: %w", c.topicID, err)
+	tid, err := c.threadID(msg.ThreadID)
+	if err != nil {
+		return "", err
+	}
+	req.MessageThreadID = tid
+
+	var result messageResult
+	if err := c.call("sendMessage", req, &result); err != nil {
+		return "", err
+	}
+	return result.id(), nil
+}
+
+// SendMediaGroup sends msg.Images as a photo album captioned with the
+// message, and returns the ID of the first photo. Captions are limited to
+// 1024 characters, and albums cannot carry buttons, so a single image is
+// sent with sendPhoto to keep them. Up to 10 images are sent.
+func (c *Client) SendMediaGroup(msg notify.Message) (string, error) {
+	if len(msg.Images) == 0 {
+		return "", fmt.Errorf("no images to send")
+	}
+	tid, err := c.threadID(msg.ThreadID)
+	if err != nil {
+		return "", err
+	}
+	caption := truncateCaption(msg.HTML())
+
+	if len(msg.Images) == 1 {
+		req := sendPhotoRequest{
+			ChatID:              c.chatID,
+			Photo:               msg.Images[0],
+			Caption:             caption,
+			ParseMode:           "HTML",
+			DisableNotification: msg.Silent,
+			ProtectContent:      msg.Protected,
+			MessageThreadID:     tid,
+			ReplyMarkup:         keyboard(msg.Buttons),
+		}
+		var result messageResult
+		if err := c.call("sendPhoto", req, &result); err != nil {
+			return "", err
 		}
-		req.MessageThreadID = &tid
+		return result.id(), nil
 	}
 
-	if len(buttons) > 0 {
+	req := sendMediaGroupRequest{
+		ChatID:              c.chatID,
+		DisableNotification: msg.Silent,
+		ProtectContent:      msg.Protected,
+		MessageThreadID:     tid,
+	}
+	for i, url := range msg.Images {
+		if i == maxMediaGroupSize {
+			break
+		}
+		media := inputMediaPhoto{Type: "photo", Media: url}
+		if i == 0 {
+			media.Caption, media.ParseMode = caption, "HTML"
+		}
+		req.Media = append(req.Media, media)
+	}
+
+	var results []messageResult
+	if err := c.call("sendMediaGroup", req, &results); err != nil {
+		return "", err
+	}
+	if len(results) == 0 {
+		return "", nil
+	}
+	return results[0].id(), nil
+}
+
+// Edit replaces the text and buttons of a previously sent message.
+func (c *Client) Edit(id string, msg notify.Message) error {
+	messageID, err := strconv.Atoi(id)
+	if err != nil {
+		return fmt.Errorf("invalid message id %q: %w", id, err)
+	}
+
+	if len(msg.Images) > 0 {
+		req := editCaptionRequest{
+			ChatID:      c.chatID,
+			MessageID:   messageID,
+			Caption:     truncateCaption(msg.HTML()),
+			ParseMode:   "HTML",
+			ReplyMarkup: keyboard(msg.Buttons),
+		}
+		return c.call("editMessageCaption", req, nil)
+	}
+
+	req := editMessageRequest{
+		ChatID:             c.chatID,
+		MessageID:          messageID,
+		Text:               truncate(msg.HTML()),
+		ParseMode:          "HTML",
+		LinkPreviewOptions: linkPreview(msg.Preview),
+		ReplyMarkup:        keyboard(msg.Buttons),
+	}
+	return c.call("editMessageText", req, nil)
+}
+
+// ChatID returns the chat the client sends to.
+func (c *Client) ChatID() string {
+	return c.chatID
+}
+
+// TopicID returns the client's default forum topic, or "" for none.
+func (c *Client) TopicID() string {
+	return c.topicID
+}
+
+// PinChatMessage pins a message in the chat. The bot needs the right to
+// pin messages; silent pins do not notify chat members.
+func (c *Client) PinChatMessage(id string, silent bool) error {
+	messageID, err := strconv.Atoi(id)
+	if err != nil {
+		return fmt.Errorf("invalid message id %q: %w", id, err)
+	}
+	req := pinMessageRequest{ChatID: c.chatID, MessageID: messageID, DisableNotification: silent}
+	return c.call("pinChatMessage", req, nil)
+}
+
+// UnpinChatMessage unpins a message pinned with PinChatMessage.
+func (c *Client) UnpinChatMessage(id string) error {
+	messageID, err := strconv.Atoi(id)
+	if err != nil {
+		return fmt.Errorf("invalid message id %q: %w", id, err)
+	}
+	req := pinMessageRequest{ChatID: c.chatID, MessageID: messageID}
+	return c.call("unpinChatMessage", req, nil)
+}
+
+// SetReaction replaces the bot's reaction to a message with emoji, which
+// must be one of the reactions Telegram allows. An empty emoji removes the
+// reaction.
+func (c *Client) SetReaction(id, emoji string) error {
+	messageID, err := strconv.Atoi(id)
+	if err != nil {
+		return fmt.Errorf("invalid message id %q: %w", id, err)
+	}
+	req := setReactionRequest{ChatID: c.chatID, MessageID: messageID, Reaction: []reactionType{}}
+	if emoji != "" {
+		req.Reaction = append(req.Reaction, reactionType{Type: "emoji", Emoji: emoji})
+	}
+	return c.call("setMessageReaction", req, nil)
+}
+
+// Forum topic icon colors accepted by CreateForumTopic.
+var TopicColors = map[string]int{
+	"blue":   0x6FB9F0,
+	"yellow": 0xFFD67E,
+	"violet": 0xCB86DB,
+	"green":  0x8EEE98,
+	"rose":   0xFF93B2,
+	"red":    0xFB6F5F,
+}
+
+// CreateForumTopic creates a topic in the forum supergroup and returns its
+// ID. name is truncated to Telegram's 128 characters; iconColor is one of
+// TopicColors, or 0 for the default. The bot needs the right to manage
+// topics.
+func (c *Client) CreateForumTopic(name string, iconColor int) (string, error) {
+	req := createTopicRequest{
+		ChatID:    c.chatID,
+		Name:      notify.Truncate(name, telegramMaxTopicNameLength, "…"),
+		IconColor: iconColor,
+	}
+	var result struct {
+		MessageThreadID int `json:"message_thread_id"`
+	}
+	if err := c.call("createForumTopic", req, &result); err != nil {
+		return "", err
+	}
+	return strconv.Itoa(result.MessageThreadID), nil
+}
+
+// CloseForumTopic closes a topic so only admins, including the bot, can
+// post in it.
+func (c *Client) CloseForumTopic(topicID string) error {
+	return c.topicCall("closeForumTopic", topicID)
+}
+
+// ReopenForumTopic reopens a topic closed with CloseForumTopic.
+func (c *Client) ReopenForumTopic(topicID string) error {
+	return c.topicCall("reopenForumTopic", topicID)
+}
+
+func (c *Client) topicCall(method, topicID string) error {
+	tid, err := strconv.Atoi(topicID)
+	if err != nil {
+		return fmt.Errorf("invalid topic_id %q: %w", topicID, err)
+	}
+	return c.call(method, topicRequest{ChatID: c.chatID, MessageThreadID: tid}, nil)
+}
+
+// User is the bot account returned by GetMe.
+type User struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+}
+
+// Chat describes the configured chat.
+type Chat struct {
+	ID      int64  `json:"id"`
+	Type    string `json:"type"`
+	Title   string `json:"title"`
+	IsForum bool   `json:"is_forum"`
+}
+
+// ChatMember is a user's status and rights in the chat. Rights only apply
+// to administrators, except CanSendMessages for restricted members.
+type ChatMember struct {
+	Status          string `json:"status"`
+	CanPostMessages bool   `json:"can_post_messages"`
+	CanPinMessages  bool   `json:"can_pin_messages"`
+	CanManageTopics bool   `json:"can_manage_topics"`
+	CanSendMessages bool   `json:"can_send_messages"`
+}
+
+// GetMe returns the bot's own account, checking the bot token.
+func (c *Client) GetMe() (User, error) {
+	var u User
+	err := c.call("getMe", struct{}{}, &u)
+	return u, err
+}
+
+// GetChat returns the configured chat.
+func (c *Client) GetChat() (Chat, error) {
+	var chat Chat
+	err := c.call("getChat", chatRequest{ChatID: c.chatID}, &chat)
+	return chat, err
+}
+
+// GetChatMember returns the status of userID in the configured chat.
+func (c *Client) GetChatMember(userID int64) (ChatMember, error) {
+	var m ChatMember
+	err := c.call("getChatMember", chatRequest{ChatID: c.chatID, UserID: userID}, &m)
+	return m, err
+}
+
+// SendChatAction shows a chat action such as "typing" in the client's
+// topic, or topicID when set. It posts nothing, so it checks that the bot
+// can post in the topic.
+func (c *Client) SendChatAction(action, topicID string) error {
+	tid, err := c.threadID(topicID)
+	if err != nil {
+		return err
+	}
+	return c.call("sendChatAction", chatActionRequest{ChatID: c.chatID, Action: action, MessageThreadID: tid}, nil)
+}
+
+// threadID resolves the forum topic for a message: override when set,
+// otherwise the client's topic ID.
+func (c *Client) threadID(override string) (*int, error) {
+	topicID := c.topicID
+	if override != "" {
+		topicID = override
+	}
+	if topicID == "" {
+		return nil, nil
+	}
+	tid, err := strconv.Atoi(topicID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid topic_id %q: %w", topicID, err)
+	}
+	return &tid, nil
+}
+
+// linkPreview converts p to Telegram's link_preview_options, disabling the
+// preview when p has no URL.
+func linkPreview(p notify.LinkPreview) *linkPreviewOptions {
+	if p.URL == "" {
+		return &linkPreviewOptions{IsDisabled: true}
+	}
+	return &linkPreviewOptions{
+		URL:              p.URL,
+		PreferSmallMedia: p.Size == notify.PreviewSmall,
+		PreferLargeMedia: p.Size == notify.PreviewLarge,
+	}
+}
+
+// truncate and truncateCaption shorten HTML to Telegram's limits, which
+// count visible text, keeping tags balanced so the HTML still parses.
+func truncate(text string) string {
+	return notify.TruncateHTML(text, telegramMaxMessageLength, truncationMarker)
+}
+
+func truncateCaption(text string) string {
+	return notify.TruncateHTML(text, telegramMaxCaptionLength, "…")
+}
+
+func keyboard(grid [][]Button) *replyMarkup {
+	var rows [][]inlineButton
+	for _, buttons := range grid {
+		if len(buttons) == 0 {
+			continue
+		}
 		row := make([]inlineButton, len(buttons))
 		for i, b := range buttons {
 			row[i] = inlineButton{Text: b.Text, URL: b.URL}
 		}
-		req.ReplyMarkup = &replyMarkup{
-			InlineKeyboard: [][]inlineButton{row},
+		rows = append(rows, row)
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+	return &replyMarkup{InlineKeyboard: rows}
+}
+
+// Document is a file sent with SendDocument.
+type Document struct {
+	Filename string
+	Content  []byte
+
+	// Caption is optional HTML shown below the file.
+	Caption string
+
+	// ThreadID overrides the client's topic ID.
+	ThreadID string
+
+	// ReplyTo is the ID of a message to reply to, e.g. the notification
+	// the document belongs to. Optional.
+	ReplyTo string
+
+	// Protected prevents the document from being forwarded or saved.
+	Protected bool
+}
+
+// SendDocument uploads doc as a file with a multipart request and returns
+// the ID of the resulting message.
+func (c *Client) SendDocument(doc Document) (string, error) {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+
+	fields := [][2]string{{"chat_id", c.chatID}}
+	if doc.Caption != "" {
+		fields = append(fields,
+			[2]string{"caption", truncateCaption(doc.Caption)},
+			[2]string{"parse_mode", "HTML"})
+	}
+	topicID := c.topicID
+	if doc.ThreadID != "" {
+		topicID = doc.ThreadID
+	}
+	if topicID != "" {
+		if _, err := strconv.Atoi(topicID); err != nil {
+			return "", fmt.Errorf("invalid topic_id %q: %w", topicID, err)
+		}
+		fields = append(fields, [2]string{"message_thread_id", topicID})
+	}
+	if doc.ReplyTo != "" {
+		replyTo, err := strconv.Atoi(doc.ReplyTo)
+		if err != nil {
+			return "", fmt.Errorf("invalid message id %q: %w", doc.ReplyTo, err)
 		}
+		params, _ := json.Marshal(replyParameters{MessageID: replyTo, AllowSendingWithoutReply: true})
+		fields = append(fields, [2]string{"reply_parameters", string(params)})
+	}
+	if doc.Protected {
+		fields = append(fields, [2]string{"protect_content", "true"})
+	}
+
+	for _, f := range fields {
+		if err := w.WriteField(f[0], f[1]); err != nil {
+			return "", fmt.Errorf("writing %s field: %w", f[0], err)
+		}
+	}
+	part, err := w.CreateFormFile("document", doc.Filename)
+	if err != nil {
+		return "", fmt.Errorf("creating document part: %w", err)
+	}
+	if _, err := part.Write(doc.Content); err != nil {
+		return "", fmt.Errorf("writing document: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return "", fmt.Errorf("closing multipart body: %w", err)
 	}
 
+	var result messageResult
+	if err := c.post("sendDocument", w.FormDataContentType(), &body, &result); err != nil {
+		return "", err
+	}
+	return result.id(), nil
+}
+
+// call invokes a Bot API method and decodes its result into out, if non-nil.
+func (c *Client) call(method string, req any, out any) error {
 	body, err := json.Marshal(req)
 	if err != nil {
 		return fmt.Errorf("marshaling request: %w", err)
 	}
+	return c.post(method, "application/json", bytes.NewReader(body), out)
+}
+
+// post sends a Bot API request. Every error it returns has the bot token
+// removed.
+func (c *Client) post(method, contentType string, body io.Reader, out any) error {
+	return sanitizeErr(c.doPost(method, contentType, body, out), c.botToken)
+}
 
-	url := fmt.Sprintf("%s/bot%s/sendMessage", c.apiURL, c.botToken)
-	resp, err := c.httpClient.Post(url, "application/json", bytes.NewReader(body))
+func (c *Client) doPost(method, contentType string, body io.Reader, out any) error {
+	url := fmt.Sprintf("%s/bot%s/%s", c.apiURL, c.botToken, method)
+	resp, err := c.httpClient.Post(url, contentType, body)
 	if err != nil {
-		return fmt.Errorf("sending request to Telegram API: %w", sanitizeErr(err, c.botToken))
+		return fmt.Errorf("sending request to Telegram API: %w", err)
 	}
 	defer resp.Body.Close()
 
@@ -124,21 +628,24 @@ func (c *Client) SendMessage(text string, buttons []Button) error {
 
 	var apiResp apiResponse
 	if err := json.Unmarshal(respBody, &apiResp); err != nil {
-		return fmt.Errorf("parsing response: %w", err)
+		return &notify.StatusError{Code: resp.StatusCode, Err: fmt.Errorf("parsing response: %w", err)}
 	}
 
 	if !apiResp.OK {
-		return fmt.Errorf("telegram API error: %s", apiResp.Description)
+		return &notify.StatusError{Code: resp.StatusCode, Err: fmt.Errorf("telegram API error: %s", apiResp.Description)}
 	}
 
+	if out != nil && len(apiResp.Result) > 0 {
+		if err := json.Unmarshal(apiResp.Result, out); err != nil {
+			return fmt.Errorf("parsing %s result: %w", method, err)
+		}
+	}
 	return nil
 }
 
-// sanitizeErr removes the bot token from error messages to prevent log leakage.
+// sanitizeErr removes the bot token, in any encoding, from an error and
+// every error it wraps, to prevent log leakage. Every request goes through
+// post, so this covers transport, response parsing and API errors alike.
 func sanitizeErr(err error, token string) error {
-	if err == nil || token == "" {
-		return err
-	}
-	cleaned := strings.ReplaceAll(err.Error(), token, "[REDACTED]")
-	return fmt.Errorf("%s", cleaned)
+	return notify.RedactError(err, token)
 }
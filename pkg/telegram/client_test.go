@@ -2,11 +2,15 @@ package telegram
 
 import (
 	"encoding/json"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"strings"
 	"testing"
+
+	"github.com/andoniaf/telegram-pr-notify/pkg/notify"
 )
 
 func newTestClient(serverURL string) *Client {
@@ -45,8 +49,8 @@ func TestSendMessageSuccess(t *testing.T) {
 	if received.ParseMode != "HTML" {
 		t.Errorf("ParseMode = %q, want %q", received.ParseMode, "HTML")
 	}
-	if !received.DisableWebPagePreview {
-		t.Error("DisableWebPagePreview = false, want true")
+	if received.LinkPreviewOptions == nil || !received.LinkPreviewOptions.IsDisabled {
+		t.Errorf("LinkPreviewOptions = %+v, want disabled", received.LinkPreviewOptions)
 	}
 	if received.MessageThreadID != nil {
 		t.Errorf("MessageThreadID = %v, want nil", received.MessageThreadID)
@@ -160,6 +164,60 @@ func TestSendMessageAPIError(t *testing.T) {
 	}
 }
 
+func TestAPIErrorRedactsToken(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"ok": false, "description": "Not Found: ` + r.URL.Path + `"}`))
+	}))
+	defer server.Close()
+
+	client := newTestClient(server.URL)
+	client.botToken = "my-secret-token"
+
+	err := client.SendMessage("Hello", nil)
+	if err == nil {
+		t.Fatal("SendMessage() expected error")
+	}
+	if strings.Contains(err.Error(), "my-secret-token") {
+		t.Errorf("error leaks the bot token: %v", err)
+	}
+}
+
+func FuzzAPIErrorRedactsToken(f *testing.F) {
+	const token = "123456789:AAHdqTcvCH1vGWJxfSeofSAs0K5PALDsaw"
+	f.Add("Unauthorized: bot%s", token)
+	f.Add("Bad Request: see /bot%s/sendMessage", url.QueryEscape(token))
+	f.Add("Not Found: bot%s…", token[:20])
+	f.Add("Forbidden: …%s", token[10:])
+	f.Fuzz(func(t *testing.T, format, leaked string) {
+		// Whole tokens and fragments cut at either end, as left by
+		// truncated messages, must not survive.
+		escaped := url.QueryEscape(token)
+		if len(leaked) < 8 || !(strings.HasPrefix(token, leaked) || strings.HasSuffix(token, leaked) ||
+			strings.HasPrefix(escaped, leaked) || strings.HasSuffix(escaped, leaked)) {
+			t.Skip()
+		}
+		description, _ := json.Marshal(strings.ReplaceAll(format, "%s", leaked))
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, `{"ok": false, "description": %s}`, description)
+		}))
+		defer server.Close()
+
+		client := newTestClient(server.URL)
+		client.botToken = token
+
+		_, err := client.Send(notify.Message{Blocks: []string{"Hello"}})
+		if err == nil {
+			t.Fatal("Send() expected error")
+		}
+		if strings.Contains(err.Error(), leaked) {
+			t.Errorf("error leaks the token: %v", err)
+		}
+	})
+}
+
 func TestSendMessageInvalidTopicID(t *testing.T) {
 	client := &Client{
 		botToken:   "test-token",
@@ -258,3 +316,488 @@ func TestWithHTTPClient(t *testing.T) {
 		t.Error("WithHTTPClient did not set the custom http client")
 	}
 }
+
+func TestSendReturnsMessageID(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"ok": true, "result": {"message_id": 1234}}`))
+	}))
+	defer server.Close()
+
+	client := newTestClient(server.URL)
+
+	id, err := client.Send(notify.Message{Blocks: []string{"Hello"}})
+	if err != nil {
+		t.Fatalf("Send() error: %v", err)
+	}
+	if id != "1234" {
+		t.Errorf("Send() id = %q, want %q", id, "1234")
+	}
+}
+
+func TestSendThreadIDOverridesTopic(t *testing.T) {
+	var received sendMessageRequest
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &received)
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"ok": true}`))
+	}))
+	defer server.Close()
+
+	client := newTestClient(server.URL)
+	client.topicID = "456"
+
+	if _, err := client.Send(notify.Message{Blocks: []string{"Hello"}, ThreadID: "789"}); err != nil {
+		t.Fatalf("Send() error: %v", err)
+	}
+	if received.MessageThreadID == nil || *received.MessageThreadID != 789 {
+		t.Errorf("MessageThreadID = %v, want 789", received.MessageThreadID)
+	}
+}
+
+func TestEditMessage(t *testing.T) {
+	var received editMessageRequest
+	var requestPath string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestPath = r.URL.Path
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &received)
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"ok": true, "result": {"message_id": 1234}}`))
+	}))
+	defer server.Close()
+
+	client := newTestClient(server.URL)
+
+	err := client.Edit("1234", notify.Message{
+		Blocks:  []string{"Updated"},
+		Buttons: [][]Button{{{Text: "View PR", URL: "https://github.com/pr/1"}}},
+	})
+	if err != nil {
+		t.Fatalf("Edit() error: %v", err)
+	}
+
+	if requestPath != "/bottest-token/editMessageText" {
+		t.Errorf("request path = %q, want %q", requestPath, "/bottest-token/editMessageText")
+	}
+	if received.MessageID != 1234 {
+		t.Errorf("MessageID = %d, want 1234", received.MessageID)
+	}
+	if received.Text != "Updated" {
+		t.Errorf("Text = %q, want %q", received.Text, "Updated")
+	}
+	if received.ReplyMarkup == nil || len(received.ReplyMarkup.InlineKeyboard[0]) != 1 {
+		t.Error("expected inline keyboard with one button")
+	}
+}
+
+func TestEditInvalidMessageID(t *testing.T) {
+	client := newTestClient("http://localhost")
+	if err := client.Edit("not-a-number", notify.Message{Blocks: []string{"x"}}); err == nil {
+		t.Error("Edit() expected error for invalid message id")
+	}
+}
+
+func TestSendStructuredMessage(t *testing.T) {
+	var received sendMessageRequest
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &received)
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"ok": true}`))
+	}))
+	defer server.Close()
+
+	client := newTestClient(server.URL)
+
+	_, err := client.Send(notify.Message{
+		Title:   "Title",
+		Blocks:  []string{"Body"},
+		Fields:  []notify.Field{{Name: "Branch", Value: "main"}},
+		Buttons: [][]Button{{{Text: "A", URL: "https://a"}, {Text: "B", URL: "https://b"}}, {{Text: "C", URL: "https://c"}}},
+		Silent:  true,
+	})
+	if err != nil {
+		t.Fatalf("Send() error: %v", err)
+	}
+
+	if want := "<b>Title</b>\nBody\n\n<b>Branch:</b> main"; received.Text != want {
+		t.Errorf("Text = %q, want %q", received.Text, want)
+	}
+	if !received.DisableNotification {
+		t.Error("DisableNotification = false, want true for silent messages")
+	}
+	rows := received.ReplyMarkup.InlineKeyboard
+	if len(rows) != 2 || len(rows[0]) != 2 || rows[1][0].Text != "C" {
+		t.Errorf("InlineKeyboard = %+v, want rows [A B] [C]", rows)
+	}
+}
+
+func TestSendDocument(t *testing.T) {
+	var path, filename, content string
+	fields := map[string]string{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Errorf("ParseMultipartForm() error: %v", err)
+		}
+		for k, v := range r.MultipartForm.Value {
+			fields[k] = v[0]
+		}
+		file, header, err := r.FormFile("document")
+		if err != nil {
+			t.Errorf("FormFile() error: %v", err)
+		} else {
+			b, _ := io.ReadAll(file)
+			filename, content = header.Filename, string(b)
+		}
+		w.Write([]byte(`{"ok": true, "result": {"message_id": 77}}`))
+	}))
+	defer server.Close()
+
+	client := newTestClient(server.URL)
+	client.topicID = "5"
+
+	id, err := client.SendDocument(Document{
+		Filename: "pr-1.diff",
+		Content:  []byte("diff --git a/x b/x\n"),
+		Caption:  "📄 Diff for <b>#1</b>",
+		ReplyTo:  "76",
+	})
+	if err != nil {
+		t.Fatalf("SendDocument() error: %v", err)
+	}
+	if id != "77" {
+		t.Errorf("SendDocument() id = %q, want 77", id)
+	}
+	if path != "/bottest-token/sendDocument" {
+		t.Errorf("path = %q", path)
+	}
+	if filename != "pr-1.diff" || content != "diff --git a/x b/x\n" {
+		t.Errorf("document = %q %q", filename, content)
+	}
+
+	want := map[string]string{
+		"chat_id":           "-100123",
+		"message_thread_id": "5",
+		"caption":           "📄 Diff for <b>#1</b>",
+		"parse_mode":        "HTML",
+		"reply_parameters":  `{"message_id":76,"allow_sending_without_reply":true}`,
+	}
+	for k, v := range want {
+		if fields[k] != v {
+			t.Errorf("field %s = %q, want %q", k, fields[k], v)
+		}
+	}
+}
+
+func TestSendDocumentAPIError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"ok": false, "description": "Bad Request: file is too big"}`))
+	}))
+	defer server.Close()
+
+	_, err := newTestClient(server.URL).SendDocument(Document{Filename: "a.diff", Content: []byte("x")})
+	if err == nil || !strings.Contains(err.Error(), "file is too big") {
+		t.Errorf("SendDocument() error = %v, want API description", err)
+	}
+}
+
+func TestSendDocumentInvalidReplyTo(t *testing.T) {
+	client := newTestClient("http://localhost")
+	if _, err := client.SendDocument(Document{Filename: "a.diff", ReplyTo: "abc"}); err == nil {
+		t.Error("SendDocument() with invalid ReplyTo expected error")
+	}
+}
+
+func TestSendSinglePhoto(t *testing.T) {
+	var path string
+	var received sendPhotoRequest
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &received)
+		w.Write([]byte(`{"ok": true, "result": {"message_id": 12}}`))
+	}))
+	defer server.Close()
+
+	id, err := newTestClient(server.URL).Send(notify.Message{
+		Blocks:  []string{"<b>New Pull Request</b>"},
+		Buttons: [][]notify.Button{{{Text: "View PR", URL: "https://github.com/pr/1"}}},
+		Images:  []string{"https://example.com/a.png"},
+		Silent:  true,
+	})
+	if err != nil {
+		t.Fatalf("Send() error: %v", err)
+	}
+	if id != "12" {
+		t.Errorf("Send() id = %q, want 12", id)
+	}
+	if path != "/bottest-token/sendPhoto" {
+		t.Errorf("path = %q, want sendPhoto", path)
+	}
+	if received.Photo != "https://example.com/a.png" || received.Caption != "<b>New Pull Request</b>" || received.ParseMode != "HTML" {
+		t.Errorf("request = %+v", received)
+	}
+	if !received.DisableNotification {
+		t.Error("DisableNotification = false, want true")
+	}
+	if received.ReplyMarkup == nil || received.ReplyMarkup.InlineKeyboard[0][0].URL != "https://github.com/pr/1" {
+		t.Errorf("ReplyMarkup = %+v, want the message buttons", received.ReplyMarkup)
+	}
+}
+
+func TestSendMediaGroup(t *testing.T) {
+	var path string
+	var received sendMediaGroupRequest
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &received)
+		w.Write([]byte(`{"ok": true, "result": [{"message_id": 20}, {"message_id": 21}]}`))
+	}))
+	defer server.Close()
+
+	images := make([]string, 12)
+	for i := range images {
+		images[i] = fmt.Sprintf("https://example.com/%d.png", i)
+	}
+	client := newTestClient(server.URL)
+	client.topicID = "9"
+
+	id, err := client.Send(notify.Message{
+		Blocks: []string{strings.Repeat("a", 2000)},
+		Images: images,
+	})
+	if err != nil {
+		t.Fatalf("Send() error: %v", err)
+	}
+	if id != "20" {
+		t.Errorf("Send() id = %q, want the first message", id)
+	}
+	if path != "/bottest-token/sendMediaGroup" {
+		t.Errorf("path = %q, want sendMediaGroup", path)
+	}
+	if len(received.Media) != maxMediaGroupSize {
+		t.Fatalf("len(Media) = %d, want %d", len(received.Media), maxMediaGroupSize)
+	}
+	first := received.Media[0]
+	if first.Type != "photo" || first.Media != images[0] || first.ParseMode != "HTML" {
+		t.Errorf("first media = %+v", first)
+	}
+	if n := len([]rune(first.Caption)); n != telegramMaxCaptionLength {
+		t.Errorf("caption length = %d, want %d", n, telegramMaxCaptionLength)
+	}
+	if received.Media[1].Caption != "" {
+		t.Errorf("second media caption = %q, want empty", received.Media[1].Caption)
+	}
+	if received.MessageThreadID == nil || *received.MessageThreadID != 9 {
+		t.Errorf("MessageThreadID = %v, want 9", received.MessageThreadID)
+	}
+}
+
+func TestSendMediaGroupCaptionCutInMarkup(t *testing.T) {
+	var received sendPhotoRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &received)
+		w.Write([]byte(`{"ok": true, "result": {"message_id": 20}}`))
+	}))
+	defer server.Close()
+
+	// The caption limit falls inside the link, then inside the bold span.
+	link := `<a href="https://github.com/o/r/pull/1">` + strings.Repeat("x", 1100) + `</a>`
+	bold := strings.Repeat("a", 1000) + " <b>" + strings.Repeat("&amp;", 100) + "</b>"
+	tests := []struct {
+		name, html, want string
+	}{
+		{"link", link, `<a href="https://github.com/o/r/pull/1">` + strings.Repeat("x", 1023) + "</a>…"},
+		{"bold", bold, strings.Repeat("a", 1000) + " <b>" + strings.Repeat("&amp;", 22) + "</b>…"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newTestClient(server.URL)
+			if _, err := client.SendMediaGroup(notify.Message{Blocks: []string{tt.html}, Images: []string{"https://example.com/a.png"}}); err != nil {
+				t.Fatalf("SendMediaGroup() error: %v", err)
+			}
+			if received.Caption != tt.want {
+				t.Errorf("caption = %q, want %q", received.Caption, tt.want)
+			}
+		})
+	}
+}
+
+func TestEditMessageWithImages(t *testing.T) {
+	var path string
+	var received editCaptionRequest
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &received)
+		w.Write([]byte(`{"ok": true, "result": {"message_id": 12}}`))
+	}))
+	defer server.Close()
+
+	err := newTestClient(server.URL).Edit("12", notify.Message{
+		Blocks: []string{"Updated"},
+		Images: []string{"https://example.com/a.png"},
+	})
+	if err != nil {
+		t.Fatalf("Edit() error: %v", err)
+	}
+	if path != "/bottest-token/editMessageCaption" {
+		t.Errorf("path = %q, want editMessageCaption", path)
+	}
+	if received.MessageID != 12 || received.Caption != "Updated" {
+		t.Errorf("request = %+v", received)
+	}
+}
+
+func TestSendLinkPreviewAndProtectContent(t *testing.T) {
+	var received sendMessageRequest
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &received)
+		w.Write([]byte(`{"ok": true, "result": {"message_id": 1}}`))
+	}))
+	defer server.Close()
+
+	_, err := newTestClient(server.URL).Send(notify.Message{
+		Blocks:    []string{"Hello"},
+		Silent:    true,
+		Protected: true,
+		Preview:   notify.LinkPreview{URL: "https://github.com/pr/1", Size: notify.PreviewLarge},
+	})
+	if err != nil {
+		t.Fatalf("Send() error: %v", err)
+	}
+	if !received.DisableNotification || !received.ProtectContent {
+		t.Errorf("DisableNotification = %v, ProtectContent = %v, want both true", received.DisableNotification, received.ProtectContent)
+	}
+	want := linkPreviewOptions{URL: "https://github.com/pr/1", PreferLargeMedia: true}
+	if received.LinkPreviewOptions == nil || *received.LinkPreviewOptions != want {
+		t.Errorf("LinkPreviewOptions = %+v, want %+v", received.LinkPreviewOptions, want)
+	}
+}
+
+func TestPinAndUnpinChatMessage(t *testing.T) {
+	var paths []string
+	var received pinMessageRequest
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &received)
+		w.Write([]byte(`{"ok": true, "result": true}`))
+	}))
+	defer server.Close()
+
+	client := newTestClient(server.URL)
+	if err := client.PinChatMessage("42", true); err != nil {
+		t.Fatalf("PinChatMessage() error: %v", err)
+	}
+	if received.MessageID != 42 || !received.DisableNotification || received.ChatID != "-100123" {
+		t.Errorf("pin request = %+v", received)
+	}
+	if err := client.UnpinChatMessage("42"); err != nil {
+		t.Fatalf("UnpinChatMessage() error: %v", err)
+	}
+	if strings.Join(paths, ",") != "/bottest-token/pinChatMessage,/bottest-token/unpinChatMessage" {
+		t.Errorf("paths = %v", paths)
+	}
+	if err := client.PinChatMessage("abc", false); err == nil {
+		t.Error("PinChatMessage() with invalid id expected error")
+	}
+}
+
+func TestSetReaction(t *testing.T) {
+	var path string
+	var received map[string]any
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		body, _ := io.ReadAll(r.Body)
+		received = nil
+		json.Unmarshal(body, &received)
+		w.Write([]byte(`{"ok": true, "result": true}`))
+	}))
+	defer server.Close()
+
+	client := newTestClient(server.URL)
+	if err := client.SetReaction("42", "👍"); err != nil {
+		t.Fatalf("SetReaction() error: %v", err)
+	}
+	if path != "/bottest-token/setMessageReaction" {
+		t.Errorf("path = %q", path)
+	}
+	reaction, _ := received["reaction"].([]any)
+	if len(reaction) != 1 || reaction[0].(map[string]any)["emoji"] != "👍" || reaction[0].(map[string]any)["type"] != "emoji" {
+		t.Errorf("reaction = %v", received["reaction"])
+	}
+
+	if err := client.SetReaction("42", ""); err != nil {
+		t.Fatalf("SetReaction() error: %v", err)
+	}
+	if reaction, ok := received["reaction"].([]any); !ok || len(reaction) != 0 {
+		t.Errorf("reaction = %v, want an empty list to clear it", received["reaction"])
+	}
+}
+
+func TestForumTopics(t *testing.T) {
+	var paths []string
+	var received map[string]any
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		body, _ := io.ReadAll(r.Body)
+		received = nil
+		json.Unmarshal(body, &received)
+		if strings.HasSuffix(r.URL.Path, "/createForumTopic") {
+			w.Write([]byte(`{"ok": true, "result": {"message_thread_id": 55, "name": "x", "icon_color": 7322096}}`))
+			return
+		}
+		w.Write([]byte(`{"ok": true, "result": true}`))
+	}))
+	defer server.Close()
+
+	client := newTestClient(server.URL)
+	id, err := client.CreateForumTopic(strings.Repeat("n", 200), TopicColors["blue"])
+	if err != nil {
+		t.Fatalf("CreateForumTopic() error: %v", err)
+	}
+	if id != "55" {
+		t.Errorf("CreateForumTopic() id = %q, want 55", id)
+	}
+	if name, _ := received["name"].(string); len([]rune(name)) != telegramMaxTopicNameLength {
+		t.Errorf("name length = %d, want %d", len([]rune(name)), telegramMaxTopicNameLength)
+	}
+	if received["icon_color"] != float64(0x6FB9F0) {
+		t.Errorf("icon_color = %v", received["icon_color"])
+	}
+
+	if err := client.CloseForumTopic(id); err != nil {
+		t.Fatalf("CloseForumTopic() error: %v", err)
+	}
+	if received["message_thread_id"] != float64(55) {
+		t.Errorf("message_thread_id = %v, want 55", received["message_thread_id"])
+	}
+	if err := client.ReopenForumTopic(id); err != nil {
+		t.Fatalf("ReopenForumTopic() error: %v", err)
+	}
+	want := "/bottest-token/createForumTopic,/bottest-token/closeForumTopic,/bottest-token/reopenForumTopic"
+	if got := strings.Join(paths, ","); got != want {
+		t.Errorf("paths = %s", got)
+	}
+	if err := client.CloseForumTopic("general"); err == nil {
+		t.Error("CloseForumTopic() with invalid id expected error")
+	}
+}
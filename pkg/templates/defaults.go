@@ -1,8 +1,30 @@
 package templates
 
+// prStatus and prFiles render API enrichment (see github_token); both are
+// empty when it is not available.
+const prStatus = `
+{{- with .ReviewSummary.Text}}
+{{.}}
+{{- end}}
+{{- with .Checks.Badge}}
+{{.}}
+{{- end}}`
+
+const prFiles = `
+{{- if .Files}}
+
+📁 <b>Files changed</b>{{with .PR.ChangedFiles}} ({{.}}){{end}}
+{{- range .Files}}
+<code>{{.Filename}}</code> +{{.Additions}} −{{.Deletions}}
+{{- end}}
+{{- with .HiddenFiles}}
+…and {{.}} more
+{{- end}}
+{{- end}}`
+
 const prOpened = `🔀 <b>New Pull Request</b>
 <a href="{{.PR.HTMLURL}}">#{{.PR.Number}}</a> {{truncate .PR.Title 100}}
-{{.PR.Head.Ref}} → {{.PR.Base.Ref}}
+{{.PR.Head.Ref}} → {{.PR.Base.Ref}}` + prStatus + prFiles + `
 
 by <a href="{{.Actor.HTMLURL}}">{{.Actor.Login}}</a> in <a href="{{.Repo.HTMLURL}}"><b>{{.Repo.FullName}}</b></a>`
 
@@ -25,13 +47,24 @@ by <a href="{{.Actor.HTMLURL}}">{{.Actor.Login}}</a> in <a href="{{.Repo.HTMLURL
 
 const prSynchronize = `🔄 <b>Pull Request Updated</b>
 <a href="{{.PR.HTMLURL}}">#{{.PR.Number}}</a> {{truncate .PR.Title 100}}
-{{.PR.Head.Ref}} → {{.PR.Base.Ref}}
+{{.PR.Head.Ref}} → {{.PR.Base.Ref}}` + prStatus + prFiles + `
 
 New commits pushed by <a href="{{.Actor.HTMLURL}}">{{.Actor.Login}}</a> in <a href="{{.Repo.HTMLURL}}"><b>{{.Repo.FullName}}</b></a>`
 
 const prReadyForReview = `👀 <b>Pull Request Ready for Review</b>
 <a href="{{.PR.HTMLURL}}">#{{.PR.Number}}</a> {{truncate .PR.Title 100}}
+{{.PR.Head.Ref}} → {{.PR.Base.Ref}}` + prStatus + prFiles + `
+
+by <a href="{{.Actor.HTMLURL}}">{{.Actor.Login}}</a> in <a href="{{.Repo.HTMLURL}}"><b>{{.Repo.FullName}}</b></a>`
+
+const prReviewRequested = `🙋 <b>Review Requested</b>
+<a href="{{.PR.HTMLURL}}">#{{.PR.Number}}</a> {{truncate .PR.Title 100}}
 {{.PR.Head.Ref}} → {{.PR.Base.Ref}}
+{{- with .ReviewRequest.Reviewer.Login}}
+Reviewer: <a href="{{$.ReviewRequest.Reviewer.HTMLURL}}">{{.}}</a>
+{{- else}}{{with .ReviewRequest.Team.Name}}
+Reviewer: <a href="{{$.ReviewRequest.Team.HTMLURL}}">{{.}}</a> (team)
+{{- end}}{{end}}
 
 by <a href="{{.Actor.HTMLURL}}">{{.Actor.Login}}</a> in <a href="{{.Repo.HTMLURL}}"><b>{{.Repo.FullName}}</b></a>`
 
@@ -41,7 +74,7 @@ const prConvertedToDraft = `📝 <b>Pull Request Converted to Draft</b>
 by <a href="{{.Actor.HTMLURL}}">{{.Actor.Login}}</a> in <a href="{{.Repo.HTMLURL}}"><b>{{.Repo.FullName}}</b></a>`
 
 const reviewApproved = `✅ <b>Pull Request Approved</b>
-<a href="{{.PR.HTMLURL}}">#{{.PR.Number}}</a> {{truncate .PR.Title 100}}
+<a href="{{.PR.HTMLURL}}">#{{.PR.Number}}</a> {{truncate .PR.Title 100}}` + prStatus + `
 
 by <a href="{{.Actor.HTMLURL}}">{{.Actor.Login}}</a> in <a href="{{.Repo.HTMLURL}}"><b>{{.Repo.FullName}}</b></a>
 {{- if .Review.Body}}
@@ -50,7 +83,7 @@ by <a href="{{.Actor.HTMLURL}}">{{.Actor.Login}}</a> in <a href="{{.Repo.HTMLURL
 {{- end}}`
 
 const reviewChangesRequested = `🔴 <b>Changes Requested</b>
-<a href="{{.PR.HTMLURL}}">#{{.PR.Number}}</a> {{truncate .PR.Title 100}}
+<a href="{{.PR.HTMLURL}}">#{{.PR.Number}}</a> {{truncate .PR.Title 100}}` + prStatus + `
 
 by <a href="{{.Actor.HTMLURL}}">{{.Actor.Login}}</a> in <a href="{{.Repo.HTMLURL}}"><b>{{.Repo.FullName}}</b></a>
 {{- if .Review.Body}}
@@ -59,7 +92,7 @@ by <a href="{{.Actor.HTMLURL}}">{{.Actor.Login}}</a> in <a href="{{.Repo.HTMLURL
 {{- end}}`
 
 const reviewCommented = `💬 <b>Review Submitted</b>
-<a href="{{.PR.HTMLURL}}">#{{.PR.Number}}</a> {{truncate .PR.Title 100}}
+<a href="{{.PR.HTMLURL}}">#{{.PR.Number}}</a> {{truncate .PR.Title 100}}` + prStatus + `
 
 by <a href="{{.Actor.HTMLURL}}">{{.Actor.Login}}</a> in <a href="{{.Repo.HTMLURL}}"><b>{{.Repo.FullName}}</b></a>
 {{- if .Review.Body}}
@@ -79,6 +112,164 @@ by <a href="{{.Actor.HTMLURL}}">{{.Actor.Login}}</a> in <a href="{{.Repo.HTMLURL
 <blockquote>{{truncate .Comment.Body 500}}</blockquote>
 {{- end}}`
 
+const ciCompleted = `{{if .CI.Failed}}❌ <b>CI Failed</b>{{else if .CI.Recovered}}✅ <b>CI Recovered</b>{{else if .CI.Succeeded}}✅ <b>CI Passed</b>{{else}}⚪ <b>CI {{.CI.Conclusion}}</b>{{end}}
+<a href="{{.CI.HTMLURL}}">{{.CI.Name}}</a>{{if .CI.HeadBranch}} on {{.CI.HeadBranch}}{{end}} (<code>{{.CI.ShortSHA}}</code>)
+{{- if .PR.Number}}
+<a href="{{.PR.HTMLURL}}">#{{.PR.Number}}</a>{{if .PR.Title}} {{truncate .PR.Title 100}}{{end}}
+{{- end}}
+{{- if .CI.FailedJobs}}
+Failed: {{range $i, $job := .CI.FailedJobs}}{{if $i}}, {{end}}<code>{{$job}}</code>{{end}}
+{{- end}}
+{{- if .CI.Duration}}
+⏱ {{.CI.Duration}}
+{{- end}}
+
+in <a href="{{.Repo.HTMLURL}}"><b>{{.Repo.FullName}}</b></a>`
+
+const pushCommitList = `
+{{- range .Push.ShownCommits}}
+<a href="{{.URL}}"><code>{{.ShortID}}</code></a> {{truncate .Title 80}} — {{if .Author.Username}}{{.Author.Username}}{{else}}{{.Author.Name}}{{end}}
+{{- end}}
+{{- with .Push.HiddenCommits}}
+…and {{.}} more
+{{- end}}`
+
+const pushPushed = `⬆️ <b>{{len .Push.Commits}} new commit{{if ne (len .Push.Commits) 1}}s{{end}} pushed</b>
+to <code>{{.Push.RefName}}</code>
+` + pushCommitList + `
+
+by <a href="{{.Actor.HTMLURL}}">{{.Actor.Login}}</a> in <a href="{{.Repo.HTMLURL}}"><b>{{.Repo.FullName}}</b></a>`
+
+const pushForced = `⚠️ <b>Force Push</b>
+to <code>{{.Push.RefName}}</code> (<code>{{.Push.ShortBefore}}</code> → <code>{{.Push.ShortAfter}}</code>)
+` + pushCommitList + `
+
+by <a href="{{.Actor.HTMLURL}}">{{.Actor.Login}}</a> in <a href="{{.Repo.HTMLURL}}"><b>{{.Repo.FullName}}</b></a>`
+
+const pushCreated = `🌱 <b>{{if .Push.IsTag}}Tag{{else}}Branch{{end}} Created</b>
+<code>{{.Push.RefName}}</code>
+` + pushCommitList + `
+
+by <a href="{{.Actor.HTMLURL}}">{{.Actor.Login}}</a> in <a href="{{.Repo.HTMLURL}}"><b>{{.Repo.FullName}}</b></a>`
+
+const pushDeleted = `🗑 <b>{{if .Push.IsTag}}Tag{{else}}Branch{{end}} Deleted</b>
+<code>{{.Push.RefName}}</code>
+
+by <a href="{{.Actor.HTMLURL}}">{{.Actor.Login}}</a> in <a href="{{.Repo.HTMLURL}}"><b>{{.Repo.FullName}}</b></a>`
+
+const releasePublished = `{{if .Release.Prerelease}}🧪 <b>Pre-release Published</b>{{else}}🚀 <b>Release Published</b>{{end}}
+<a href="{{.Release.HTMLURL}}">{{.Release.DisplayName}}</a>{{if ne .Release.DisplayName .Release.TagName}} (<code>{{.Release.TagName}}</code>){{end}}
+{{- if .Release.Body}}
+
+{{markdown (truncate .Release.Body 1500)}}
+{{- end}}
+
+by <a href="{{.Actor.HTMLURL}}">{{.Actor.Login}}</a> in <a href="{{.Repo.HTMLURL}}"><b>{{.Repo.FullName}}</b></a>`
+
+const refCreated = `{{if eq .Ref.Type "tag"}}🏷 <b>Tag Created</b>{{else}}🌱 <b>Branch Created</b>{{end}}
+<code>{{.Ref.Name}}</code>
+
+by <a href="{{.Actor.HTMLURL}}">{{.Actor.Login}}</a> in <a href="{{.Repo.HTMLURL}}"><b>{{.Repo.FullName}}</b></a>`
+
+const refDeleted = `🗑 <b>{{if eq .Ref.Type "tag"}}Tag{{else}}Branch{{end}} Deleted</b>
+<code>{{.Ref.Name}}</code>
+
+by <a href="{{.Actor.HTMLURL}}">{{.Actor.Login}}</a> in <a href="{{.Repo.HTMLURL}}"><b>{{.Repo.FullName}}</b></a>`
+
+const deploymentSucceeded = `✅ <b>Deployed to {{.Deployment.Environment}}</b>
+<code>{{.Deployment.Ref}}</code> (<code>{{.Deployment.ShortSHA}}</code>)
+{{- if .Deployment.Description}}
+{{truncate .Deployment.Description 200}}
+{{- end}}
+
+by <a href="{{.Actor.HTMLURL}}">{{.Actor.Login}}</a> in <a href="{{.Repo.HTMLURL}}"><b>{{.Repo.FullName}}</b></a>`
+
+const deploymentFailed = `❌ <b>Deployment to {{.Deployment.Environment}} Failed</b>
+<code>{{.Deployment.Ref}}</code> (<code>{{.Deployment.ShortSHA}}</code>)
+{{- if .Deployment.Description}}
+{{truncate .Deployment.Description 200}}
+{{- end}}
+
+by <a href="{{.Actor.HTMLURL}}">{{.Actor.Login}}</a> in <a href="{{.Repo.HTMLURL}}"><b>{{.Repo.FullName}}</b></a>`
+
+const discussionCreated = `💡 <b>New Discussion</b>{{if .Discussion.Category.Name}} in {{.Discussion.Category.Emoji}} {{.Discussion.Category.Name}}{{end}}
+<a href="{{.Discussion.HTMLURL}}">#{{.Discussion.Number}}</a> {{truncate .Discussion.Title 100}}
+
+by <a href="{{.Actor.HTMLURL}}">{{.Actor.Login}}</a> in <a href="{{.Repo.HTMLURL}}"><b>{{.Repo.FullName}}</b></a>
+{{- if .Discussion.Body}}
+
+<blockquote>{{truncate .Discussion.Body 500}}</blockquote>
+{{- end}}`
+
+const discussionAnswered = `✅ <b>Discussion Answered</b>
+<a href="{{.Discussion.HTMLURL}}">#{{.Discussion.Number}}</a> {{truncate .Discussion.Title 100}}
+
+answer by <a href="{{.Comment.User.HTMLURL}}">{{.Comment.User.Login}}</a>, chosen by <a href="{{.Actor.HTMLURL}}">{{.Actor.Login}}</a> in <a href="{{.Repo.HTMLURL}}"><b>{{.Repo.FullName}}</b></a>
+{{- if .Comment.Body}}
+
+<blockquote>{{truncate .Comment.Body 500}}</blockquote>
+{{- end}}`
+
+const discussionCategoryChanged = `🗂 <b>Discussion Moved</b>
+<a href="{{.Discussion.HTMLURL}}">#{{.Discussion.Number}}</a> {{truncate .Discussion.Title 100}}
+{{if .Discussion.PreviousCategory.Name}}{{.Discussion.PreviousCategory.Name}} → {{end}}{{.Discussion.Category.Name}}
+
+by <a href="{{.Actor.HTMLURL}}">{{.Actor.Login}}</a> in <a href="{{.Repo.HTMLURL}}"><b>{{.Repo.FullName}}</b></a>`
+
+const discussionCommentCreated = `💬 <b>Discussion Comment</b>
+<a href="{{.Discussion.HTMLURL}}">#{{.Discussion.Number}}</a> {{truncate .Discussion.Title 100}}
+
+by <a href="{{.Actor.HTMLURL}}">{{.Actor.Login}}</a> in <a href="{{.Repo.HTMLURL}}"><b>{{.Repo.FullName}}</b></a>
+{{- if .Comment.Body}}
+
+<blockquote>{{truncate .Comment.Body 500}}</blockquote>
+{{- end}}`
+
+const prEnqueued = `🚦 <b>Added to Merge Queue</b>
+<a href="{{.PR.HTMLURL}}">#{{.PR.Number}}</a> {{truncate .PR.Title 100}}
+{{.PR.Head.Ref}} → {{.PR.Base.Ref}}
+
+by <a href="{{.Actor.HTMLURL}}">{{.Actor.Login}}</a> in <a href="{{.Repo.HTMLURL}}"><b>{{.Repo.FullName}}</b></a>`
+
+const prDequeued = `{{if .QueueMerged}}🟣 <b>Landed via Merge Queue</b>{{else}}⛔ <b>Removed from Merge Queue</b>{{end}}
+<a href="{{.PR.HTMLURL}}">#{{.PR.Number}}</a> {{truncate .PR.Title 100}}
+{{- if and .MergeGroup.Reason (not .QueueMerged)}}
+Reason: {{.MergeGroup.ReasonText}}
+{{- end}}
+
+by <a href="{{.Actor.HTMLURL}}">{{.Actor.Login}}</a> in <a href="{{.Repo.HTMLURL}}"><b>{{.Repo.FullName}}</b></a>`
+
+const mergeGroupChecksRequested = `🧪 <b>Merge Queue Checks Started</b>
+{{if .PR.Number}}<a href="{{.PR.HTMLURL}}">#{{.PR.Number}}</a> {{truncate .PR.Title 100}}{{else}}{{truncate .MergeGroup.HeadCommit.Title 100}}{{end}}
+→ {{.MergeGroup.BaseBranch}} (<code>{{.MergeGroup.HeadCommit.ShortID}}</code>)
+
+in <a href="{{.Repo.HTMLURL}}"><b>{{.Repo.FullName}}</b></a>`
+
+const mergeGroupDestroyed = `⛔ <b>Merge Group Removed</b>
+{{if .PR.Number}}<a href="{{.PR.HTMLURL}}">#{{.PR.Number}}</a> {{truncate .PR.Title 100}}{{else}}{{truncate .MergeGroup.HeadCommit.Title 100}}{{end}}
+→ {{.MergeGroup.BaseBranch}}
+{{- if .MergeGroup.Reason}}
+Reason: {{.MergeGroup.ReasonText}}
+{{- end}}
+
+in <a href="{{.Repo.HTMLURL}}"><b>{{.Repo.FullName}}</b></a>`
+
+const mergeGroupMerged = `🟣 <b>Landed via Merge Queue</b>
+{{if .PR.Number}}<a href="{{.PR.HTMLURL}}">#{{.PR.Number}}</a> {{truncate .PR.Title 100}}{{else}}{{truncate .MergeGroup.HeadCommit.Title 100}}{{end}}
+→ {{.MergeGroup.BaseBranch}} (<code>{{.MergeGroup.HeadCommit.ShortID}}</code>)
+
+in <a href="{{.Repo.HTMLURL}}"><b>{{.Repo.FullName}}</b></a>`
+
+// Defaults returns a copy of the default templates, keyed by
+// "event:action".
+func Defaults() map[string]string {
+	m := make(map[string]string, len(defaultTemplates))
+	for k, v := range defaultTemplates {
+		m[k] = v
+	}
+	return m
+}
+
 // defaultTemplates maps event_name + action to a default template string.
 var defaultTemplates = map[string]string{
 	"pull_request:opened":             prOpened,
@@ -88,10 +279,42 @@ var defaultTemplates = map[string]string{
 	"pull_request:synchronize":        prSynchronize,
 	"pull_request:ready_for_review":   prReadyForReview,
 	"pull_request:converted_to_draft": prConvertedToDraft,
+	"pull_request:review_requested":   prReviewRequested,
+	"pull_request:enqueued":           prEnqueued,
+	"pull_request:dequeued":           prDequeued,
 
 	"pull_request_review:approved":          reviewApproved,
 	"pull_request_review:changes_requested": reviewChangesRequested,
 	"pull_request_review:commented":         reviewCommented,
 
 	"pull_request_review_comment:created": reviewCommentCreated,
+
+	"workflow_run:completed": ciCompleted,
+	"check_suite:completed":  ciCompleted,
+	"check_run:completed":    ciCompleted,
+
+	"push:pushed":  pushPushed,
+	"push:forced":  pushForced,
+	"push:created": pushCreated,
+	"push:deleted": pushDeleted,
+
+	"release:published": releasePublished,
+
+	"create:tag":    refCreated,
+	"create:branch": refCreated,
+	"delete:tag":    refDeleted,
+	"delete:branch": refDeleted,
+
+	"deployment_status:success": deploymentSucceeded,
+	"deployment_status:failure": deploymentFailed,
+	"deployment_status:error":   deploymentFailed,
+
+	"discussion:created":          discussionCreated,
+	"discussion:answered":         discussionAnswered,
+	"discussion:category_changed": discussionCategoryChanged,
+	"discussion_comment:created":  discussionCommentCreated,
+
+	"merge_group:checks_requested": mergeGroupChecksRequested,
+	"merge_group:destroyed":        mergeGroupDestroyed,
+	"merge_group:merged":           mergeGroupMerged,
 }
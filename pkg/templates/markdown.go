@@ -0,0 +1,137 @@
+package templates
+
+import (
+	"fmt"
+	"html"
+	"html/template"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	mdComment     = regexp.MustCompile(`(?s)<!--.*?-->`)
+	mdFence       = regexp.MustCompile("^\\s*(```|~~~)")
+	mdHeading     = regexp.MustCompile(`^\s{0,3}#{1,6}\s+(.*?)\s*#*\s*$`)
+	mdListItem    = regexp.MustCompile(`^(\s*)[-*+]\s+(.*)$`)
+	mdQuote       = regexp.MustCompile(`^\s{0,3}>\s?(.*)$`)
+	mdRule        = regexp.MustCompile(`^\s{0,3}([-*_])(\s*([-*_])){2,}\s*$`)
+	mdCodeSpan    = regexp.MustCompile("`([^`]+)`")
+	mdImage       = regexp.MustCompile(`!\[([^\]]*)\]\(([^)\s]+)(?:\s+"[^"]*")?\)`)
+	mdLink        = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)(?:\s+"[^"]*")?\)`)
+	mdBold        = regexp.MustCompile(`\*\*([^*]+)\*\*|__([^_]+)__`)
+	mdItalicStar  = regexp.MustCompile(`\*([^*\s][^*]*)\*`)
+	mdItalicUnder = regexp.MustCompile(`(^|[^\w])_([^_\s][^_]*)_([^\w]|$)`)
+	mdStrike      = regexp.MustCompile(`~~([^~]+)~~`)
+	mdPlaceholder = regexp.MustCompile("\x00(\\d+)\x00")
+	mdBlankLines  = regexp.MustCompile(`\n{3,}`)
+)
+
+// markdownToHTML converts the GitHub-flavored Markdown commonly found in
+// release notes and PR bodies into the HTML subset Telegram supports.
+// Everything that is not converted is HTML-escaped.
+func markdownToHTML(md string) template.HTML {
+	md = mdComment.ReplaceAllString(strings.ReplaceAll(md, "\r\n", "\n"), "")
+
+	var out []string
+	var quote []string
+	var code []string
+	inCode := false
+
+	flushQuote := func() {
+		if len(quote) > 0 {
+			out = append(out, "<blockquote>"+strings.Join(quote, "\n")+"</blockquote>")
+			quote = nil
+		}
+	}
+
+	for _, line := range strings.Split(md, "\n") {
+		if mdFence.MatchString(line) {
+			if inCode {
+				out = append(out, "<pre>"+html.EscapeString(strings.Join(code, "\n"))+"</pre>")
+				code = nil
+			} else {
+				flushQuote()
+			}
+			inCode = !inCode
+			continue
+		}
+		if inCode {
+			code = append(code, line)
+			continue
+		}
+
+		if m := mdQuote.FindStringSubmatch(line); m != nil {
+			quote = append(quote, markdownInline(m[1]))
+			continue
+		}
+		flushQuote()
+
+		switch {
+		case mdRule.MatchString(line):
+			out = append(out, "──────────")
+		case mdHeading.MatchString(line):
+			m := mdHeading.FindStringSubmatch(line)
+			out = append(out, "<b>"+markdownInline(m[1])+"</b>")
+		case mdListItem.MatchString(line):
+			m := mdListItem.FindStringSubmatch(line)
+			out = append(out, m[1]+"• "+markdownInline(m[2]))
+		default:
+			out = append(out, markdownInline(strings.TrimRight(line, " \t")))
+		}
+	}
+
+	if inCode {
+		out = append(out, "<pre>"+html.EscapeString(strings.Join(code, "\n"))+"</pre>")
+	}
+	flushQuote()
+
+	result := mdBlankLines.ReplaceAllString(strings.Join(out, "\n"), "\n\n")
+	return template.HTML(strings.TrimSpace(result))
+}
+
+// markdownInline converts inline Markdown within a single line. Code spans
+// and links are replaced by placeholders first so emphasis rules never
+// touch their contents.
+func markdownInline(s string) string {
+	var parts []string
+	hold := func(fragment string) string {
+		parts = append(parts, fragment)
+		return fmt.Sprintf("\x00%d\x00", len(parts)-1)
+	}
+
+	s = mdCodeSpan.ReplaceAllStringFunc(s, func(m string) string {
+		return hold("<code>" + html.EscapeString(mdCodeSpan.FindStringSubmatch(m)[1]) + "</code>")
+	})
+	s = mdImage.ReplaceAllStringFunc(s, func(m string) string {
+		sub := mdImage.FindStringSubmatch(m)
+		text := sub[1]
+		if text == "" {
+			text = "image"
+		}
+		return hold(markdownAnchor(text, sub[2]))
+	})
+	s = mdLink.ReplaceAllStringFunc(s, func(m string) string {
+		sub := mdLink.FindStringSubmatch(m)
+		return hold(markdownAnchor(sub[1], sub[2]))
+	})
+
+	s = html.EscapeString(s)
+	s = mdBold.ReplaceAllString(s, "<b>$1$2</b>")
+	s = mdItalicStar.ReplaceAllString(s, "<i>$1</i>")
+	s = mdItalicUnder.ReplaceAllString(s, "$1<i>$2</i>$3")
+	s = mdStrike.ReplaceAllString(s, "<s>$1</s>")
+
+	return mdPlaceholder.ReplaceAllStringFunc(s, func(m string) string {
+		i, _ := strconv.Atoi(mdPlaceholder.FindStringSubmatch(m)[1])
+		return parts[i]
+	})
+}
+
+// markdownAnchor renders a link, keeping only the text for non-HTTP URLs.
+func markdownAnchor(text, url string) string {
+	if !strings.HasPrefix(url, "https://") && !strings.HasPrefix(url, "http://") {
+		return html.EscapeString(text)
+	}
+	return `<a href="` + html.EscapeString(url) + `">` + html.EscapeString(text) + `</a>`
+}
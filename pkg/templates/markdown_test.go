@@ -0,0 +1,53 @@
+package templates
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMarkdownToHTML(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"plain text", "Hello world", "Hello world"},
+		{"escapes html", "a < b && <script>", "a &lt; b &amp;&amp; &lt;script&gt;"},
+		{"heading", "## What's Changed", "<b>What&#39;s Changed</b>"},
+		{"bold", "**important** and __also__", "<b>important</b> and <b>also</b>"},
+		{"italic", "*note* and _aside_", "<i>note</i> and <i>aside</i>"},
+		{"snake_case untouched", "use some_var_name here", "use some_var_name here"},
+		{"strikethrough", "~~old~~", "<s>old</s>"},
+		{"code span", "run `go test ./...` now", "run <code>go test ./...</code> now"},
+		{"code span keeps markup", "`**not bold** <b>`", "<code>**not bold** &lt;b&gt;</code>"},
+		{"link", "[docs](https://example.com/a_b_/c?x=1&y=2)", `<a href="https://example.com/a_b_/c?x=1&amp;y=2">docs</a>`},
+		{"non-http link keeps text", "[click](javascript:void)", "click"},
+		{"image", "![screenshot](https://example.com/s.png)", `<a href="https://example.com/s.png">screenshot</a>`},
+		{"list", "- one\n* two", "• one\n• two"},
+		{"fenced code", "```go\nfmt.Println(\"<hi>\")\n```", "<pre>fmt.Println(&#34;&lt;hi&gt;&#34;)</pre>"},
+		{"unterminated fence", "```\ncode", "<pre>code</pre>"},
+		{"blockquote", "> quoted\n> more\nafter", "<blockquote>quoted\nmore</blockquote>\nafter"},
+		{"html comment removed", "<!-- hidden -->visible", "visible"},
+		{"collapses blank lines", "a\n\n\n\nb", "a\n\nb"},
+		{"crlf", "a\r\nb", "a\nb"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(markdownToHTML(tt.in)); got != tt.want {
+				t.Errorf("markdownToHTML(%q) =\n%q\nwant\n%q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMarkdownToHTMLReleaseNotes(t *testing.T) {
+	notes := "## What's Changed\n* Add feature by @octocat in https://github.com/o/r/pull/1\n\n**Full Changelog**: https://github.com/o/r/compare/v1.0.0...v1.1.0"
+	got := string(markdownToHTML(notes))
+
+	for _, want := range []string{"<b>What&#39;s Changed</b>", "• Add feature", "<b>Full Changelog</b>"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("result missing %q:\n%s", want, got)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package templates
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"html/template"
+	"strconv"
+	"strings"
+
+	"github.com/andoniaf/telegram-pr-notify/pkg/events"
+	"github.com/andoniaf/telegram-pr-notify/pkg/notify"
+)
+
+// Build renders data into a structured message. The template output becomes
+// the body; a template may also {{define "title"}} to set a title that
+// backends with native headings display separately, and {{define "buttons"}}
+// to add buttons (see Buttons). Buttons link to the event and its secondary
+// links. Linked issues and external tickets come next, then the buttons the
+// template defines. They all share a single row. A {{define "fields"}}
+// template sets fields, one "Name | Value" per line.
+func Build(data *events.TemplateData, customTpl string) (notify.Message, error) {
+	tpl, err := parse(data, customTpl)
+	if err != nil {
+		return notify.Message{}, err
+	}
+
+	body, err := execute(tpl, data)
+	if err != nil {
+		return notify.Message{}, err
+	}
+
+	msg := notify.Message{
+		Blocks:   []string{body},
+		Priority: priority(data),
+	}
+	row := buttons(data)
+
+	if t := tpl.Lookup("title"); t != nil {
+		var buf bytes.Buffer
+		if err := t.Execute(&buf, data); err != nil {
+			return notify.Message{}, fmt.Errorf("executing title template: %w", err)
+		}
+		msg.Title = strings.TrimSpace(buf.String())
+	}
+
+	if t := tpl.Lookup("fields"); t != nil {
+		fields, err := executeFields(t, data)
+		if err != nil {
+			return notify.Message{}, err
+		}
+		msg.Fields = fields
+	}
+
+	if t := tpl.Lookup("buttons"); t != nil {
+		extra, err := executeButtons(t, data)
+		if err != nil {
+			return notify.Message{}, err
+		}
+		row = append(row, extra...)
+	}
+
+	msg.Buttons = [][]notify.Button{row}
+	return msg, nil
+}
+
+// Buttons renders a buttons template against data. Each non-empty line of
+// the output is a "Text | URL" pair; lines whose URL renders empty are
+// dropped, so buttons can be made conditional on the event.
+func Buttons(data *events.TemplateData, tplStr string) ([]notify.Button, error) {
+	tpl, err := template.New("buttons").Funcs(funcMap).Parse(tplStr)
+	if err != nil {
+		return nil, fmt.Errorf("parsing buttons template: %w", err)
+	}
+	return executeButtons(tpl, data)
+}
+
+// Text renders a template against data as plain text, such as a topic
+// name: html/template escaping is undone and surrounding space trimmed.
+func Text(data *events.TemplateData, tplStr string) (string, error) {
+	tpl, err := template.New("text").Funcs(funcMap).Parse(tplStr)
+	if err != nil {
+		return "", fmt.Errorf("parsing template: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := tpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("executing template: %w", err)
+	}
+	return strings.TrimSpace(html.UnescapeString(buf.String())), nil
+}
+
+// Condition renders a template against data and reports whether it
+// produced "true". Empty output is false; anything else that is not a
+// boolean is an error.
+func Condition(data *events.TemplateData, tplStr string) (bool, error) {
+	out, err := Text(data, tplStr)
+	if err != nil {
+		return false, fmt.Errorf("condition: %w", err)
+	}
+	if out == "" {
+		return false, nil
+	}
+	ok, err := strconv.ParseBool(out)
+	if err != nil {
+		return false, fmt.Errorf("condition template rendered %q, want true or false", out)
+	}
+	return ok, nil
+}
+
+func executeButtons(tpl *template.Template, data *events.TemplateData) ([]notify.Button, error) {
+	var buf bytes.Buffer
+	if err := tpl.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("executing buttons template: %w", err)
+	}
+
+	var result []notify.Button
+	for _, line := range strings.Split(buf.String(), "\n") {
+		// Button text and URLs are not HTML, so undo html/template escaping.
+		line = strings.TrimSpace(html.UnescapeString(line))
+		if line == "" {
+			continue
+		}
+		sep := strings.LastIndex(line, "|")
+		if sep < 0 {
+			return nil, fmt.Errorf("invalid button %q (want Text | URL)", line)
+		}
+		text, url := strings.TrimSpace(line[:sep]), strings.TrimSpace(line[sep+1:])
+		if url == "" {
+			continue
+		}
+		if text == "" {
+			return nil, fmt.Errorf("invalid button %q: text is empty", line)
+		}
+		result = append(result, notify.Button{Text: text, URL: url})
+	}
+	return result, nil
+}
+
+// executeFields renders a fields template. Each non-empty line is a
+// "Name | Value" pair; the value keeps its HTML, and a line whose value
+// renders empty is dropped.
+func executeFields(tpl *template.Template, data *events.TemplateData) ([]notify.Field, error) {
+	var buf bytes.Buffer
+	if err := tpl.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("executing fields template: %w", err)
+	}
+
+	var result []notify.Field
+	for _, line := range strings.Split(buf.String(), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(line, "|")
+		if !ok {
+			return nil, fmt.Errorf("invalid field %q (want Name | Value)", line)
+		}
+		name, value = strings.TrimSpace(html.UnescapeString(name)), strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		if name == "" {
+			return nil, fmt.Errorf("invalid field %q: name is empty", line)
+		}
+		result = append(result, notify.Field{Name: name, Value: value})
+	}
+	return result, nil
+}
+
+func buttons(data *events.TemplateData) []notify.Button {
+	row := []notify.Button{{Text: data.ButtonText(), URL: data.RelevantURL()}}
+	for _, link := range data.SecondaryLinks() {
+		row = append(row, notify.Button{Text: link.Text, URL: link.URL})
+	}
+	for _, issue := range data.LinkedIssues() {
+		row = append(row, notify.Button{Text: issue.Text, URL: issue.URL})
+	}
+	for _, ticket := range data.TicketLinks() {
+		row = append(row, notify.Button{Text: ticket.Text, URL: ticket.URL})
+	}
+	return row
+}
+
+// priority flags failures so backends can highlight them, and routine
+// updates, new commits on a PR and passing CI, so they can tone them down.
+// A CI recovery is not routine.
+func priority(data *events.TemplateData) notify.Priority {
+	switch {
+	case data.IsFailure():
+		return notify.PriorityHigh
+	case data.CI.Kind != "" && !data.CI.Recovered(),
+		data.EventName == "pull_request" && data.Action == "synchronize":
+		return notify.PriorityLow
+	}
+	return notify.PriorityNormal
+}
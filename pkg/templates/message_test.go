@@ -0,0 +1,233 @@
+package templates
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/andoniaf/telegram-pr-notify/pkg/events"
+	"github.com/andoniaf/telegram-pr-notify/pkg/notify"
+)
+
+func TestBuildDefault(t *testing.T) {
+	data := samplePRData()
+	data.PR.Body = "Fixes #7"
+
+	msg, err := Build(data, "")
+	if err != nil {
+		t.Fatalf("Build() error: %v", err)
+	}
+
+	body, _ := Render(data, "")
+	if len(msg.Blocks) != 1 || msg.Blocks[0] != body {
+		t.Errorf("Blocks = %q, want the rendered template", msg.Blocks)
+	}
+	if msg.Title != "" {
+		t.Errorf("Title = %q, want empty for default templates", msg.Title)
+	}
+	if len(msg.Buttons) != 1 || len(msg.Buttons[0]) != 2 {
+		t.Fatalf("Buttons = %+v, want one row with the PR and linked issue", msg.Buttons)
+	}
+	if msg.Buttons[0][0].URL != data.PR.HTMLURL {
+		t.Errorf("first button URL = %q", msg.Buttons[0][0].URL)
+	}
+	if msg.Priority != notify.PriorityNormal {
+		t.Errorf("Priority = %v, want normal", msg.Priority)
+	}
+}
+
+func TestBuildTitleDefine(t *testing.T) {
+	tpl := `{{define "title"}} PR #{{.PR.Number}} {{end}}{{.PR.Title}} by {{.Actor.Login}}`
+
+	msg, err := Build(samplePRData(), tpl)
+	if err != nil {
+		t.Fatalf("Build() error: %v", err)
+	}
+	if msg.Title != "PR #42" {
+		t.Errorf("Title = %q, want %q", msg.Title, "PR #42")
+	}
+	if got := msg.HTML(); got != "<b>PR #42</b>\nAdd new feature by octocat" {
+		t.Errorf("HTML() = %q", got)
+	}
+}
+
+func TestBuildTitleError(t *testing.T) {
+	tpl := `{{define "title"}}{{.Nope}}{{end}}body`
+	if _, err := Build(samplePRData(), tpl); err == nil || !strings.Contains(err.Error(), "title") {
+		t.Errorf("Build() error = %v, want title template error", err)
+	}
+}
+
+func TestBuildPriority(t *testing.T) {
+	tests := []struct {
+		name string
+		data *events.TemplateData
+		want notify.Priority
+	}{
+		{"ci failure", &events.TemplateData{EventName: "workflow_run", Action: "completed", CI: events.CIRun{Kind: "workflow_run", Conclusion: "failure"}}, notify.PriorityHigh},
+		{"ci success", &events.TemplateData{EventName: "workflow_run", Action: "completed", CI: events.CIRun{Kind: "workflow_run", Conclusion: "success"}}, notify.PriorityLow},
+		{"ci recovery", &events.TemplateData{EventName: "workflow_run", Action: "completed", CI: events.CIRun{Kind: "workflow_run", Conclusion: "success", PreviousConclusion: "failure"}}, notify.PriorityNormal},
+		{"pr synchronize", &events.TemplateData{EventName: "pull_request", Action: "synchronize"}, notify.PriorityLow},
+		{"pr opened", &events.TemplateData{EventName: "pull_request", Action: "opened"}, notify.PriorityNormal},
+		{"deployment error", &events.TemplateData{EventName: "deployment_status", Action: "error"}, notify.PriorityHigh},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := priority(tt.data); got != tt.want {
+				t.Errorf("priority() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildFieldsDefine(t *testing.T) {
+	tpl := `{{define "fields"}}
+Branch | <code>{{.PR.Head.Ref}}</code>
+Q&A | {{.PR.Title}}
+Reviewer | {{.Review.User.Login}}
+{{end}}{{.PR.Title}}`
+
+	msg, err := Build(samplePRData(), tpl)
+	if err != nil {
+		t.Fatalf("Build() error: %v", err)
+	}
+	want := []notify.Field{{Name: "Branch", Value: "<code>feature-branch</code>"}, {Name: "Q&A", Value: "Add new feature"}}
+	if len(msg.Fields) != len(want) {
+		t.Fatalf("Fields = %+v, want %+v", msg.Fields, want)
+	}
+	for i := range want {
+		if msg.Fields[i] != want[i] {
+			t.Errorf("field %d = %+v, want %+v", i, msg.Fields[i], want[i])
+		}
+	}
+
+	if _, err := Build(samplePRData(), `{{define "fields"}}Branch{{end}}body`); err == nil {
+		t.Error("Build() expected error for a field without a value separator")
+	}
+}
+
+func TestBuildButtonsDefine(t *testing.T) {
+	tpl := `{{define "buttons"}}
+Files changed | {{.PR.HTMLURL}}/files
+{{if .Review.State}}Review | {{.Review.HTMLURL}}{{end}}
+{{end}}{{.PR.Title}}`
+
+	msg, err := Build(samplePRData(), tpl)
+	if err != nil {
+		t.Fatalf("Build() error: %v", err)
+	}
+	row := msg.Buttons[0]
+	if len(row) != 2 {
+		t.Fatalf("buttons = %+v, want the default and Files changed", row)
+	}
+	if row[1].Text != "Files changed" || row[1].URL != "https://github.com/octocat/Hello-World/pull/42/files" {
+		t.Errorf("template button = %+v", row[1])
+	}
+}
+
+func TestButtons(t *testing.T) {
+	data := samplePRData()
+	data.PR.Title = "A & B"
+
+	tests := []struct {
+		name    string
+		tpl     string
+		want    []notify.Button
+		wantErr bool
+	}{
+		{
+			name: "templated text and url",
+			tpl:  "Diff: {{.PR.Title}} | {{.PR.HTMLURL}}.diff?a=1&b=2",
+			want: []notify.Button{{Text: "Diff: A & B", URL: "https://github.com/octocat/Hello-World/pull/42.diff?a=1&b=2"}},
+		},
+		{
+			name: "empty url dropped",
+			tpl:  "Checks | {{.CI.HTMLURL}}\nJira | https://jira.example.com/browse/ABC-1",
+			want: []notify.Button{{Text: "Jira", URL: "https://jira.example.com/browse/ABC-1"}},
+		},
+		{name: "missing separator", tpl: "Checks", wantErr: true},
+		{name: "empty text", tpl: " | https://example.com", wantErr: true},
+		{name: "parse error", tpl: "{{.PR", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Buttons(data, tt.tpl)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Buttons() = %+v, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Buttons() error: %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("Buttons() = %+v, want %+v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("button %d = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestBuildTicketButtons(t *testing.T) {
+	data := samplePRData()
+	data.ExternalTickets = []events.ExternalTicket{{Tracker: "Jira", Key: "PROJ-1", URL: "https://jira.example.com/browse/PROJ-1"}}
+
+	msg, err := Build(data, "")
+	if err != nil {
+		t.Fatalf("Build() error: %v", err)
+	}
+	row := msg.Buttons[0]
+	if last := row[len(row)-1]; last.Text != "Jira PROJ-1" || last.URL != "https://jira.example.com/browse/PROJ-1" {
+		t.Errorf("last button = %+v, want the Jira ticket", last)
+	}
+}
+
+func TestText(t *testing.T) {
+	data := samplePRData()
+	data.PR.Title = "Fix <parser> & lexer"
+
+	got, err := Text(data, "  #{{.PR.Number}} {{.PR.Title}}\n")
+	if err != nil {
+		t.Fatalf("Text() error: %v", err)
+	}
+	if want := "#42 Fix <parser> & lexer"; got != want {
+		t.Errorf("Text() = %q, want %q", got, want)
+	}
+	if _, err := Text(data, "{{.Nope}}"); err == nil {
+		t.Error("Text() with unknown field expected error")
+	}
+}
+
+func TestCondition(t *testing.T) {
+	data := samplePRData()
+	data.PR.Labels = []events.Label{{Name: "urgent"}}
+
+	tests := []struct {
+		tpl     string
+		want    bool
+		wantErr bool
+	}{
+		{tpl: `{{.PR.HasLabel "Urgent"}}`, want: true},
+		{tpl: `{{.PR.HasLabel "release"}}`, want: false},
+		{tpl: `{{if .PR.Draft}}true{{end}}`, want: false},
+		{tpl: `maybe`, wantErr: true},
+		{tpl: `{{.PR`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := Condition(data, tt.tpl)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Condition(%q) error = %v, wantErr %v", tt.tpl, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Condition(%q) = %v, want %v", tt.tpl, got, tt.want)
+		}
+	}
+}
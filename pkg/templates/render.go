@@ -16,6 +16,7 @@ var funcMap = template.FuncMap{
 		}
 		return string(runes[:max]) + "..."
 	},
+	"markdown": markdownToHTML,
 }
 
 // Render executes a template against the given data.
@@ -26,32 +27,62 @@ var funcMap = template.FuncMap{
 // GitHub URLs are clean ASCII so this is safe for default templates.
 // Custom templates with arbitrary URLs containing query params may see URL mangling.
 func Render(data *events.TemplateData, customTpl string) (string, error) {
+	tpl, err := parse(data, customTpl)
+	if err != nil {
+		return "", err
+	}
+	return execute(tpl, data)
+}
+
+// parse compiles customTpl, or the default template for the event when it
+// is empty.
+func parse(data *events.TemplateData, customTpl string) (*template.Template, error) {
 	tplStr := customTpl
 	if tplStr == "" {
 		tplStr = selectDefault(data)
 		if tplStr == "" {
-			return "", fmt.Errorf("no template for event %s action %q", data.EventName, data.Action)
+			return nil, fmt.Errorf("no template for event %s action %q", data.EventName, data.Action)
 		}
 	}
 
 	tpl, err := template.New("msg").Funcs(funcMap).Parse(tplStr)
 	if err != nil {
-		return "", fmt.Errorf("parsing template: %w", err)
+		return nil, fmt.Errorf("parsing template: %w", err)
 	}
+	return tpl, nil
+}
 
+func execute(tpl *template.Template, data *events.TemplateData) (string, error) {
 	var buf bytes.Buffer
 	if err := tpl.Execute(&buf, data); err != nil {
 		return "", fmt.Errorf("executing template: %w", err)
 	}
-
 	return buf.String(), nil
 }
 
+// HasDefault reports whether data's event and action have a default
+// template. GitHub sends some actions, such as the pending and in_progress
+// deployment states, that are not announced.
+func HasDefault(data *events.TemplateData) bool {
+	return selectDefault(data) != ""
+}
+
 func selectDefault(data *events.TemplateData) string {
-	if data.IsMerged() {
-		return defaultTemplates["pull_request:merged"]
+	return defaultTemplates[defaultKey(data)]
+}
+
+func defaultKey(data *events.TemplateData) string {
+	if data.IsMerged() || data.EventName == "merge_group" && data.QueueMerged() {
+		return data.EventName + ":merged"
 	}
+	return data.EventName + ":" + data.Action
+}
 
-	key := data.EventName + ":" + data.Action
-	return defaultTemplates[key]
+// Key names the template Render uses for data: "custom" when customTpl is
+// set, otherwise the default template's key, such as "pull_request:opened".
+func Key(data *events.TemplateData, customTpl string) string {
+	if customTpl != "" {
+		return "custom"
+	}
+	return defaultKey(data)
 }
@@ -1,8 +1,10 @@
 package templates
 
 import (
+	"fmt"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/andoniaf/telegram-pr-notify/pkg/events"
 )
@@ -68,6 +70,21 @@ func TestRenderDefaultMerged(t *testing.T) {
 	}
 }
 
+func TestKey(t *testing.T) {
+	data := samplePRData()
+	if got := Key(data, ""); got != "pull_request:opened" {
+		t.Errorf("Key() = %q, want pull_request:opened", got)
+	}
+	data.Action = "closed"
+	data.PR.Merged = true
+	if got := Key(data, ""); got != "pull_request:merged" {
+		t.Errorf("Key() = %q, want pull_request:merged", got)
+	}
+	if got := Key(data, "{{.PR.Title}}"); got != "custom" {
+		t.Errorf("Key() = %q, want custom", got)
+	}
+}
+
 func TestRenderDefaultClosed(t *testing.T) {
 	data := samplePRData()
 	data.Action = "closed"
@@ -180,6 +197,43 @@ func TestRenderAllPRActions(t *testing.T) {
 	}
 }
 
+func TestRenderReviewRequested(t *testing.T) {
+	tests := []struct {
+		name    string
+		request events.ReviewRequest
+		want    string
+	}{
+		{
+			name:    "user",
+			request: events.ReviewRequest{Reviewer: events.User{Login: "hubot", HTMLURL: "https://github.com/hubot"}},
+			want:    `Reviewer: <a href="https://github.com/hubot">hubot</a>`,
+		},
+		{
+			name:    "team",
+			request: events.ReviewRequest{Team: events.Team{Name: "core", HTMLURL: "https://github.com/orgs/octocat/teams/core"}},
+			want:    `Reviewer: <a href="https://github.com/orgs/octocat/teams/core">core</a> (team)`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := samplePRData()
+			data.Action = "review_requested"
+			data.ReviewRequest = tt.request
+
+			result, err := Render(data, "")
+			if err != nil {
+				t.Fatalf("Render() error: %v", err)
+			}
+			for _, want := range []string{"Review Requested", "#42", tt.want} {
+				if !strings.Contains(result, want) {
+					t.Errorf("result missing %q:\n%s", want, result)
+				}
+			}
+		})
+	}
+}
+
 func TestRenderCustomTemplate(t *testing.T) {
 	data := samplePRData()
 	custom := "PR #{{.PR.Number}} by {{.Actor.Login}}"
@@ -274,3 +328,455 @@ func TestRenderReviewCommentCreated(t *testing.T) {
 		t.Errorf("result missing comment body:\n%s", result)
 	}
 }
+
+func sampleCIData() *events.TemplateData {
+	data := samplePRData()
+	data.EventName = "workflow_run"
+	data.Action = "completed"
+	data.PR.Title = ""
+	data.CI = events.CIRun{
+		Kind:        "workflow_run",
+		Name:        "CI",
+		Conclusion:  "failure",
+		HTMLURL:     "https://github.com/octocat/Hello-World/actions/runs/1",
+		HeadBranch:  "feature-branch",
+		HeadSHA:     "acb5820ced9479c074f688cc328bf03f341a511d",
+		StartedAt:   time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC),
+		CompletedAt: time.Date(2024, 5, 1, 10, 3, 25, 0, time.UTC),
+		FailedJobs:  []string{"lint", "test"},
+	}
+	return data
+}
+
+func TestRenderCIFailure(t *testing.T) {
+	result, err := Render(sampleCIData(), "")
+	if err != nil {
+		t.Fatalf("Render() error: %v", err)
+	}
+
+	expectations := []string{
+		"CI Failed",
+		"acb5820",
+		"<code>lint</code>, <code>test</code>",
+		"3m25s",
+		"#42",
+		"feature-branch",
+	}
+	for _, exp := range expectations {
+		if !strings.Contains(result, exp) {
+			t.Errorf("result missing %q:\n%s", exp, result)
+		}
+	}
+}
+
+func TestRenderCIFailureWithoutJobs(t *testing.T) {
+	// Without github_token, a workflow_run failure has no job names.
+	data := sampleCIData()
+	data.CI.FailedJobs = nil
+
+	result, err := Render(data, "")
+	if err != nil {
+		t.Fatalf("Render() error: %v", err)
+	}
+	if !strings.Contains(result, "CI Failed") || !strings.Contains(result, ">CI</a>") {
+		t.Errorf("result missing the failure and workflow name:\n%s", result)
+	}
+	if strings.Contains(result, "Failed:") {
+		t.Errorf("result should not list failed jobs:\n%s", result)
+	}
+}
+
+func TestRenderCIConclusions(t *testing.T) {
+	tests := []struct {
+		event      string
+		conclusion string
+		previous   string
+		contains   string
+	}{
+		{"workflow_run", "success", "", "CI Passed"},
+		{"workflow_run", "success", "failure", "CI Recovered"},
+		{"check_suite", "cancelled", "", "CI cancelled"},
+		{"check_run", "timed_out", "", "CI Failed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.event+"/"+tt.conclusion, func(t *testing.T) {
+			data := sampleCIData()
+			data.EventName = tt.event
+			data.CI.Kind = tt.event
+			data.CI.Conclusion = tt.conclusion
+			data.CI.PreviousConclusion = tt.previous
+			data.CI.FailedJobs = nil
+
+			result, err := Render(data, "")
+			if err != nil {
+				t.Fatalf("Render() error: %v", err)
+			}
+			if !strings.Contains(result, tt.contains) {
+				t.Errorf("result missing %q:\n%s", tt.contains, result)
+			}
+		})
+	}
+}
+
+func samplePushData() *events.TemplateData {
+	data := samplePRData()
+	data.EventName = "push"
+	data.Action = "pushed"
+	data.PR = events.PullRequest{}
+	data.Push = events.Push{
+		Ref:    "refs/heads/main",
+		Before: "6113728f27ae82c7b1a177c8d03f9e96e0adf246",
+		After:  "0d1a26e67d8f5eaf1f6ba5c57fc3c7d91ac0fd1c",
+	}
+	for i := 0; i < 7; i++ {
+		data.Push.Commits = append(data.Push.Commits, events.Commit{
+			ID:      fmt.Sprintf("%07d0000000000000000000000000000000", i),
+			Message: fmt.Sprintf("Commit %d\n\nbody", i),
+			Author:  events.CommitAuthor{Name: "The Octocat", Username: "octocat"},
+		})
+	}
+	return data
+}
+
+func TestRenderPushCommitList(t *testing.T) {
+	result, err := Render(samplePushData(), "")
+	if err != nil {
+		t.Fatalf("Render() error: %v", err)
+	}
+
+	expectations := []string{
+		"7 new commits pushed",
+		"<code>main</code>",
+		"<code>0000000</code></a> Commit 0",
+		"Commit 4",
+		"…and 2 more",
+	}
+	for _, exp := range expectations {
+		if !strings.Contains(result, exp) {
+			t.Errorf("result missing %q:\n%s", exp, result)
+		}
+	}
+	if strings.Contains(result, "Commit 5") {
+		t.Errorf("result should not list commits beyond the limit:\n%s", result)
+	}
+	if strings.Contains(result, "body") {
+		t.Errorf("result should only contain the first line of commit messages:\n%s", result)
+	}
+}
+
+func TestRenderPushActions(t *testing.T) {
+	tests := []struct {
+		action   string
+		contains string
+	}{
+		{"forced", "Force Push"},
+		{"created", "Branch Created"},
+		{"deleted", "Branch Deleted"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.action, func(t *testing.T) {
+			data := samplePushData()
+			data.Action = tt.action
+
+			result, err := Render(data, "")
+			if err != nil {
+				t.Fatalf("Render() error: %v", err)
+			}
+			if !strings.Contains(result, tt.contains) {
+				t.Errorf("result missing %q:\n%s", tt.contains, result)
+			}
+		})
+	}
+}
+
+func TestRenderPushCustomTemplateCommits(t *testing.T) {
+	custom := `{{range .Push.Commits}}{{.ShortID}} {{end}}`
+	result, err := Render(samplePushData(), custom)
+	if err != nil {
+		t.Fatalf("Render() error: %v", err)
+	}
+	if !strings.Contains(result, "0000006") {
+		t.Errorf("custom template should see every commit, got %q", result)
+	}
+}
+
+func TestRenderReleasePublished(t *testing.T) {
+	data := samplePRData()
+	data.EventName = "release"
+	data.Action = "published"
+	data.Release = events.Release{
+		TagName: "v1.1.0",
+		Name:    "Hello World 1.1",
+		Body:    "## What's Changed\n* Fix <bug> in **parser**",
+		HTMLURL: "https://github.com/octocat/Hello-World/releases/tag/v1.1.0",
+	}
+
+	result, err := Render(data, "")
+	if err != nil {
+		t.Fatalf("Render() error: %v", err)
+	}
+
+	expectations := []string{
+		"Release Published",
+		"Hello World 1.1",
+		"<code>v1.1.0</code>",
+		"<b>What&#39;s Changed</b>",
+		"• Fix &lt;bug&gt; in <b>parser</b>",
+	}
+	for _, exp := range expectations {
+		if !strings.Contains(result, exp) {
+			t.Errorf("result missing %q:\n%s", exp, result)
+		}
+	}
+}
+
+func TestRenderPrerelease(t *testing.T) {
+	data := samplePRData()
+	data.EventName = "release"
+	data.Action = "published"
+	data.Release = events.Release{TagName: "v2.0.0-rc.1", Prerelease: true}
+
+	result, err := Render(data, "")
+	if err != nil {
+		t.Fatalf("Render() error: %v", err)
+	}
+	if !strings.Contains(result, "Pre-release Published") {
+		t.Errorf("result missing 'Pre-release Published':\n%s", result)
+	}
+	if strings.Contains(result, "(<code>") {
+		t.Errorf("tag should not be repeated when the release has no name:\n%s", result)
+	}
+}
+
+func TestRenderPrereleasedNotAnnouncedTwice(t *testing.T) {
+	// GitHub sends published and prereleased for a new pre-release; only
+	// published has a default template.
+	data := samplePRData()
+	data.EventName = "release"
+	data.Action = "prereleased"
+	data.Release = events.Release{TagName: "v2.0.0-rc.1", Prerelease: true}
+
+	if _, err := Render(data, ""); err == nil {
+		t.Error("Render() expected no default template for release prereleased")
+	}
+}
+
+func TestHasDefault(t *testing.T) {
+	tests := []struct {
+		state string
+		want  bool
+	}{
+		{"success", true},
+		{"failure", true},
+		{"error", true},
+		{"pending", false},
+		{"queued", false},
+		{"in_progress", false},
+		{"inactive", false},
+	}
+	for _, tt := range tests {
+		data := samplePRData()
+		data.EventName, data.Action = "deployment_status", tt.state
+		if got := HasDefault(data); got != tt.want {
+			t.Errorf("HasDefault(deployment_status %s) = %v, want %v", tt.state, got, tt.want)
+		}
+	}
+}
+
+func TestRenderRefAndDeploymentEvents(t *testing.T) {
+	tests := []struct {
+		name     string
+		modify   func(d *events.TemplateData)
+		contains string
+	}{
+		{"create tag", func(d *events.TemplateData) {
+			d.EventName, d.Action, d.Ref = "create", "tag", events.Ref{Name: "v1.0.0", Type: "tag"}
+		}, "Tag Created"},
+		{"delete tag", func(d *events.TemplateData) {
+			d.EventName, d.Action, d.Ref = "delete", "tag", events.Ref{Name: "v1.0.0", Type: "tag"}
+		}, "Tag Deleted"},
+		{"deployment success", func(d *events.TemplateData) {
+			d.EventName, d.Action = "deployment_status", "success"
+			d.Deployment = events.Deployment{Environment: "production", State: "success", Ref: "main"}
+		}, "Deployed to production"},
+		{"deployment error", func(d *events.TemplateData) {
+			d.EventName, d.Action = "deployment_status", "error"
+			d.Deployment = events.Deployment{Environment: "staging", State: "error", Ref: "main"}
+		}, "Deployment to staging Failed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := samplePRData()
+			tt.modify(data)
+
+			result, err := Render(data, "")
+			if err != nil {
+				t.Fatalf("Render() error: %v", err)
+			}
+			if !strings.Contains(result, tt.contains) {
+				t.Errorf("result missing %q:\n%s", tt.contains, result)
+			}
+		})
+	}
+}
+
+func sampleDiscussionData() *events.TemplateData {
+	data := samplePRData()
+	data.EventName = "discussion"
+	data.Action = "created"
+	data.PR = events.PullRequest{}
+	data.Discussion = events.Discussion{
+		Number:   90,
+		Title:    "How do I configure topics?",
+		Body:     "I want Q&A in its own topic.",
+		HTMLURL:  "https://github.com/octocat/Hello-World/discussions/90",
+		Category: events.DiscussionCategory{Name: "Q&A"},
+	}
+	return data
+}
+
+func TestRenderDiscussionEvents(t *testing.T) {
+	tests := []struct {
+		name     string
+		modify   func(d *events.TemplateData)
+		contains []string
+	}{
+		{"created", func(d *events.TemplateData) {}, []string{"New Discussion", "Q&amp;A", "#90", "I want Q&amp;A"}},
+		{"answered", func(d *events.TemplateData) {
+			d.Action = "answered"
+			d.Comment = events.Comment{Body: "Use category_topics", User: events.User{Login: "maintainer"}}
+		}, []string{"Discussion Answered", "maintainer", "Use category_topics"}},
+		{"category_changed", func(d *events.TemplateData) {
+			d.Action = "category_changed"
+			d.Discussion.PreviousCategory = events.DiscussionCategory{Name: "General"}
+		}, []string{"Discussion Moved", "General → Q&amp;A"}},
+		{"comment", func(d *events.TemplateData) {
+			d.EventName = "discussion_comment"
+			d.Comment = events.Comment{Body: "Thanks!"}
+		}, []string{"Discussion Comment", "Thanks!"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := sampleDiscussionData()
+			tt.modify(data)
+
+			result, err := Render(data, "")
+			if err != nil {
+				t.Fatalf("Render() error: %v", err)
+			}
+			for _, exp := range tt.contains {
+				if !strings.Contains(result, exp) {
+					t.Errorf("result missing %q:\n%s", exp, result)
+				}
+			}
+		})
+	}
+}
+
+func TestRenderQueueMergeAnnouncedOnce(t *testing.T) {
+	// A queue merge closes the PR and dequeues it; only the closed event
+	// renders as the merge.
+	closed := samplePRData()
+	closed.Action, closed.PR.Merged = "closed", true
+	dequeued := samplePRData()
+	dequeued.Action, dequeued.MergeGroup.Reason = "dequeued", "MERGE"
+
+	if got := Key(closed, ""); got != "pull_request:merged" {
+		t.Errorf("Key(closed) = %q, want pull_request:merged", got)
+	}
+	if got := Key(dequeued, ""); got != "pull_request:dequeued" {
+		t.Errorf("Key(dequeued) = %q, want pull_request:dequeued", got)
+	}
+	result, err := Render(dequeued, "")
+	if err != nil {
+		t.Fatalf("Render() error: %v", err)
+	}
+	if strings.Contains(result, "Pull Request Merged") || strings.Contains(result, "Reason:") {
+		t.Errorf("dequeued after merge should not render as a merge:\n%s", result)
+	}
+}
+
+func TestRenderMergeQueue(t *testing.T) {
+	tests := []struct {
+		name     string
+		modify   func(d *events.TemplateData)
+		contains []string
+	}{
+		{"enqueued", func(d *events.TemplateData) {
+			d.Action = "enqueued"
+		}, []string{"Added to Merge Queue", "#42"}},
+		{"dequeued with reason", func(d *events.TemplateData) {
+			d.Action = "dequeued"
+			d.MergeGroup.Reason = "CHECKS_FAILED"
+		}, []string{"Removed from Merge Queue", "Reason: checks failed"}},
+		{"dequeued after merge", func(d *events.TemplateData) {
+			d.Action = "dequeued"
+			d.MergeGroup.Reason = "MERGE"
+		}, []string{"Landed via Merge Queue", "#42"}},
+		{"group checks requested", func(d *events.TemplateData) {
+			d.EventName, d.Action = "merge_group", "checks_requested"
+			d.MergeGroup = events.MergeGroup{BaseRef: "refs/heads/main", HeadCommit: events.Commit{ID: "ec26c3e57ca3a959"}}
+		}, []string{"Merge Queue Checks Started", "→ main", "ec26c3e"}},
+		{"group invalidated", func(d *events.TemplateData) {
+			d.EventName, d.Action = "merge_group", "destroyed"
+			d.MergeGroup = events.MergeGroup{BaseRef: "refs/heads/main", Reason: "invalidated"}
+		}, []string{"Merge Group Removed", "Reason: invalidated"}},
+		{"group merged", func(d *events.TemplateData) {
+			d.EventName, d.Action = "merge_group", "destroyed"
+			d.MergeGroup = events.MergeGroup{BaseRef: "refs/heads/main", Reason: "merged"}
+		}, []string{"Landed via Merge Queue", "#42"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := samplePRData()
+			tt.modify(data)
+
+			result, err := Render(data, "")
+			if err != nil {
+				t.Fatalf("Render() error: %v", err)
+			}
+			for _, exp := range tt.contains {
+				if !strings.Contains(result, exp) {
+					t.Errorf("result missing %q:\n%s", exp, result)
+				}
+			}
+		})
+	}
+}
+
+func TestRenderPREnrichment(t *testing.T) {
+	data := samplePRData()
+	plain, err := Render(data, "")
+	if err != nil {
+		t.Fatalf("Render() error: %v", err)
+	}
+
+	data.PR.ChangedFiles = 3
+	data.Files = []events.ChangedFile{{Filename: "main.go", Additions: 4, Deletions: 1}, {Filename: "go.mod", Additions: 1}}
+	data.ReviewSummary = events.ReviewSummary{Approved: 2, Pending: 1}
+	data.Checks.Add("completed", "success")
+
+	result, err := Render(data, "")
+	if err != nil {
+		t.Fatalf("Render() error: %v", err)
+	}
+	for _, want := range []string{
+		"✅ 2 approvals, ⏳ 1 pending",
+		"✅ 1/1 checks passed",
+		"📁 <b>Files changed</b> (3)",
+		"<code>main.go</code> +4 −1",
+		"…and 1 more",
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("result missing %q:\n%s", want, result)
+		}
+	}
+	if strings.Contains(plain, "📁") || strings.Contains(plain, "checks") {
+		t.Errorf("result without enrichment should not mention files or checks:\n%s", plain)
+	}
+}
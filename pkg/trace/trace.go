@@ -0,0 +1,260 @@
+// Package trace records spans and exports them to an OpenTelemetry
+// collector with OTLP over HTTP in JSON, without the OpenTelemetry SDK.
+package trace
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Tracer collects ended spans until Flush sends them. A nil *Tracer is
+// valid and records nothing, so tracing can stay off without checks.
+type Tracer struct {
+	endpoint   string
+	service    string
+	headers    map[string]string
+	httpClient *http.Client
+
+	mu    sync.Mutex
+	spans []*Span
+}
+
+// NewTracer returns a tracer exporting to the OTLP/HTTP traces endpoint,
+// such as http://localhost:4318/v1/traces.
+func NewTracer(endpoint, service string) *Tracer {
+	return &Tracer{
+		endpoint:   endpoint,
+		service:    service,
+		headers:    map[string]string{},
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// FromEnv returns a tracer configured by the standard OpenTelemetry
+// variables OTEL_EXPORTER_OTLP_TRACES_ENDPOINT, OTEL_EXPORTER_OTLP_ENDPOINT,
+// OTEL_EXPORTER_OTLP_HEADERS and OTEL_SERVICE_NAME, or nil when no endpoint
+// is set.
+func FromEnv(service string) *Tracer {
+	endpoint := os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT")
+	if endpoint == "" {
+		base := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+		if base == "" {
+			return nil
+		}
+		endpoint = strings.TrimSuffix(base, "/") + "/v1/traces"
+	}
+	if name := os.Getenv("OTEL_SERVICE_NAME"); name != "" {
+		service = name
+	}
+	t := NewTracer(endpoint, service)
+	for _, pair := range strings.Split(os.Getenv("OTEL_EXPORTER_OTLP_HEADERS"), ",") {
+		if k, v, ok := strings.Cut(pair, "="); ok {
+			t.headers[strings.TrimSpace(k)] = strings.TrimSpace(v)
+		}
+	}
+	return t
+}
+
+// WithHTTPClient sets a custom http.Client, useful for testing.
+func (t *Tracer) WithHTTPClient(hc *http.Client) *Tracer {
+	t.httpClient = hc
+	return t
+}
+
+// Start begins a span, as a child of parent when it is not nil.
+func (t *Tracer) Start(name string, parent *Span) *Span {
+	if t == nil {
+		return nil
+	}
+	s := &Span{tracer: t, name: name, spanID: randomHex(8), start: time.Now()}
+	if parent != nil {
+		s.traceID, s.parentID = parent.traceID, parent.spanID
+	} else {
+		s.traceID = randomHex(16)
+	}
+	return s
+}
+
+// Span is one timed operation, such as a delivery to one destination. A nil
+// *Span is valid and ignores every call.
+type Span struct {
+	tracer                    *Tracer
+	name                      string
+	traceID, spanID, parentID string
+	start, end                time.Time
+	attrs                     []attribute
+	err                       error
+}
+
+// SetAttr records a string, integer or boolean attribute on the span.
+func (s *Span) SetAttr(key string, value any) {
+	if s == nil {
+		return
+	}
+	s.attrs = append(s.attrs, attribute{Key: key, Value: attrValue(value)})
+}
+
+// End finishes the span, marking it failed when err is not nil.
+func (s *Span) End(err error) {
+	if s == nil {
+		return
+	}
+	s.end, s.err = time.Now(), err
+	s.tracer.mu.Lock()
+	s.tracer.spans = append(s.tracer.spans, s)
+	s.tracer.mu.Unlock()
+}
+
+// Flush exports the ended spans.
+func (t *Tracer) Flush() error {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	spans := t.spans
+	t.spans = nil
+	t.mu.Unlock()
+	if len(spans) == 0 {
+		return nil
+	}
+
+	body, err := json.Marshal(t.request(spans))
+	if err != nil {
+		return fmt.Errorf("marshaling spans: %w", err)
+	}
+	req, err := http.NewRequest(http.MethodPost, t.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range t.headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := t.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("sending spans: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("trace collector returned %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// OTLP/JSON request, with only the fields the collector needs.
+type exportRequest struct {
+	ResourceSpans []resourceSpans `json:"resourceSpans"`
+}
+
+type resourceSpans struct {
+	Resource   resource     `json:"resource"`
+	ScopeSpans []scopeSpans `json:"scopeSpans"`
+}
+
+type resource struct {
+	Attributes []attribute `json:"attributes"`
+}
+
+type scopeSpans struct {
+	Scope scope      `json:"scope"`
+	Spans []spanJSON `json:"spans"`
+}
+
+type scope struct {
+	Name string `json:"name"`
+}
+
+type spanJSON struct {
+	TraceID      string      `json:"traceId"`
+	SpanID       string      `json:"spanId"`
+	ParentSpanID string      `json:"parentSpanId,omitempty"`
+	Name         string      `json:"name"`
+	Kind         int         `json:"kind"`
+	Start        string      `json:"startTimeUnixNano"`
+	End          string      `json:"endTimeUnixNano"`
+	Attributes   []attribute `json:"attributes,omitempty"`
+	Status       status      `json:"status"`
+}
+
+type status struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type attribute struct {
+	Key   string `json:"key"`
+	Value value  `json:"value"`
+}
+
+// value is an OTLP AnyValue; 64-bit integers are strings in OTLP/JSON.
+type value struct {
+	StringValue *string `json:"stringValue,omitempty"`
+	IntValue    *string `json:"intValue,omitempty"`
+	BoolValue   *bool   `json:"boolValue,omitempty"`
+}
+
+// Span kinds and status codes from the OTLP protocol.
+const (
+	kindInternal = 1
+	statusOK     = 1
+	statusError  = 2
+)
+
+func attrValue(v any) value {
+	switch v := v.(type) {
+	case bool:
+		return value{BoolValue: &v}
+	case int:
+		s := strconv.Itoa(v)
+		return value{IntValue: &s}
+	case int64:
+		s := strconv.FormatInt(v, 10)
+		return value{IntValue: &s}
+	case string:
+		return value{StringValue: &v}
+	}
+	s := fmt.Sprint(v)
+	return value{StringValue: &s}
+}
+
+func (t *Tracer) request(spans []*Span) exportRequest {
+	out := make([]spanJSON, len(spans))
+	for i, s := range spans {
+		st := status{Code: statusOK}
+		if s.err != nil {
+			st = status{Code: statusError, Message: s.err.Error()}
+		}
+		out[i] = spanJSON{
+			TraceID:      s.traceID,
+			SpanID:       s.spanID,
+			ParentSpanID: s.parentID,
+			Name:         s.name,
+			Kind:         kindInternal,
+			Start:        strconv.FormatInt(s.start.UnixNano(), 10),
+			End:          strconv.FormatInt(s.end.UnixNano(), 10),
+			Attributes:   s.attrs,
+			Status:       st,
+		}
+	}
+	return exportRequest{ResourceSpans: []resourceSpans{{
+		Resource:   resource{Attributes: []attribute{{Key: "service.name", Value: attrValue(t.service)}}},
+		ScopeSpans: []scopeSpans{{Scope: scope{Name: t.service}, Spans: out}},
+	}}}
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,108 @@
+package trace
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFlush(t *testing.T) {
+	var received exportRequest
+	var auth string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &received)
+	}))
+	defer server.Close()
+
+	tracer := NewTracer(server.URL+"/v1/traces", "telegram-pr-notify").WithHTTPClient(server.Client())
+	tracer.headers["Authorization"] = "Bearer secret"
+
+	root := tracer.Start("notify", nil)
+	child := tracer.Start("deliver", root)
+	child.SetAttr("destination", "chat -100123")
+	child.SetAttr("attempts", 2)
+	child.End(errors.New("chat not found"))
+	root.End(nil)
+
+	if err := tracer.Flush(); err != nil {
+		t.Fatalf("Flush() error: %v", err)
+	}
+	if auth != "Bearer secret" {
+		t.Errorf("Authorization = %q", auth)
+	}
+
+	spans := received.ResourceSpans[0].ScopeSpans[0].Spans
+	if len(spans) != 2 {
+		t.Fatalf("len(spans) = %d, want 2", len(spans))
+	}
+	deliver, notify := spans[0], spans[1]
+	if deliver.TraceID != notify.TraceID || deliver.ParentSpanID != notify.SpanID || notify.ParentSpanID != "" {
+		t.Errorf("deliver should be a child of notify: %+v, %+v", deliver, notify)
+	}
+	if len(deliver.TraceID) != 32 || len(deliver.SpanID) != 16 {
+		t.Errorf("IDs = %q, %q, want 16 and 8 bytes of hex", deliver.TraceID, deliver.SpanID)
+	}
+	if deliver.Status.Code != statusError || deliver.Status.Message != "chat not found" {
+		t.Errorf("deliver status = %+v", deliver.Status)
+	}
+	if notify.Status.Code != statusOK {
+		t.Errorf("notify status = %+v", notify.Status)
+	}
+	attrs := deliver.Attributes
+	if len(attrs) != 2 || *attrs[0].Value.StringValue != "chat -100123" || *attrs[1].Value.IntValue != "2" {
+		t.Errorf("attributes = %+v", attrs)
+	}
+
+	received = exportRequest{}
+	if err := tracer.Flush(); err != nil || received.ResourceSpans != nil {
+		t.Errorf("second Flush() should send nothing: %v, %+v", err, received)
+	}
+}
+
+func TestFlushError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	tracer := NewTracer(server.URL, "svc").WithHTTPClient(server.Client())
+	tracer.Start("notify", nil).End(nil)
+	if err := tracer.Flush(); err == nil {
+		t.Error("Flush() expected error for a 401")
+	}
+}
+
+func TestNilTracer(t *testing.T) {
+	var tracer *Tracer
+	span := tracer.Start("notify", nil)
+	span.SetAttr("event", "push")
+	span.End(nil)
+	if err := tracer.Flush(); err != nil {
+		t.Errorf("Flush() error = %v", err)
+	}
+}
+
+func TestFromEnv(t *testing.T) {
+	t.Setenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "")
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "")
+	if FromEnv("svc") != nil {
+		t.Error("FromEnv() without an endpoint should be nil")
+	}
+
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "http://collector:4318/")
+	t.Setenv("OTEL_EXPORTER_OTLP_HEADERS", "x-api-key=abc, x-team = ci")
+	t.Setenv("OTEL_SERVICE_NAME", "notify-prod")
+	tracer := FromEnv("svc")
+	if tracer.endpoint != "http://collector:4318/v1/traces" || tracer.service != "notify-prod" {
+		t.Errorf("tracer = %+v", tracer)
+	}
+	if tracer.headers["x-api-key"] != "abc" || tracer.headers["x-team"] != "ci" {
+		t.Errorf("headers = %v", tracer.headers)
+	}
+}
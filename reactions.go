@@ -0,0 +1,101 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/andoniaf/telegram-pr-notify/pkg/events"
+	"github.com/andoniaf/telegram-pr-notify/pkg/notify"
+	"github.com/andoniaf/telegram-pr-notify/pkg/state"
+)
+
+// announceActions are the pull_request actions whose notification is the
+// PR's announcement, which pins and reactions refer to.
+var announceActions = map[string]bool{
+	"opened":           true,
+	"reopened":         true,
+	"ready_for_review": true,
+}
+
+// defaultReactions is used when the reactions input is "true".
+var defaultReactions = eventRules{
+	"pull_request_review.approved":          "👍",
+	"pull_request_review.changes_requested": "👎",
+	"merged":                                "🎉",
+	"failure":                               "🔥",
+}
+
+// reactor is implemented by notifiers that can react to messages.
+type reactor interface {
+	SetReaction(id, emoji string) error
+}
+
+// parseReactions parses the reactions input: "true" for the default
+// mapping, or event rules mapping selectors to an emoji. An empty emoji
+// removes the reaction. Reactions are disabled when the input is empty or
+// "false".
+func parseReactions(input string) (eventRules, error) {
+	input = strings.TrimSpace(input)
+	if b, err := strconv.ParseBool(input); err == nil {
+		if b {
+			return defaultReactions, nil
+		}
+		return nil, nil
+	}
+	if input == "" {
+		return nil, nil
+	}
+	return parseEventRules(input, func(string) error { return nil })
+}
+
+// announcementKey identifies a PR's announcement message in the state file.
+func announcementKey(data *events.TemplateData) string {
+	return fmt.Sprintf("pr:%s#%d", data.Repo.FullName, data.PR.Number)
+}
+
+// react sets the reaction selected by rules on the announcement of the
+// event's PR. It reports whether a reaction was set; events without a
+// matching rule or a known announcement are left alone.
+func react(r reactor, store *state.Store, rules eventRules, data *events.TemplateData) (bool, error) {
+	if data.PR.Number == 0 || data.EventName == "pull_request" && announceActions[data.Action] {
+		return false, nil
+	}
+	emoji, ok := rules.lookup(data)
+	if !ok {
+		return false, nil
+	}
+	id := store.Message(announcementKey(data))
+	if id == "" {
+		return false, nil
+	}
+	if err := r.SetReaction(id, emoji); err != nil {
+		return false, fmt.Errorf("reacting to message %s: %w", id, err)
+	}
+	return true, nil
+}
+
+// applyReaction reacts to the PR's announcement recorded in stateFile.
+func applyReaction(notifier notify.Notifier, stateFile string, rules eventRules, data *events.TemplateData) (bool, error) {
+	r, ok := notifier.(reactor)
+	if !ok {
+		return false, errors.New("the notifier does not support reactions")
+	}
+	store, err := state.Open(stateFile)
+	if err != nil {
+		return false, err
+	}
+	return react(r, store, rules, data)
+}
+
+// rememberAnnouncement records messageID as the PR's announcement so later
+// events can react to it.
+func rememberAnnouncement(stateFile string, data *events.TemplateData, messageID string) error {
+	store, err := state.Open(stateFile)
+	if err != nil {
+		return err
+	}
+	store.SetMessage(announcementKey(data), messageID)
+	return store.Save()
+}
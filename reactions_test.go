@@ -0,0 +1,86 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/andoniaf/telegram-pr-notify/pkg/events"
+	"github.com/andoniaf/telegram-pr-notify/pkg/state"
+)
+
+type fakeReactor struct {
+	id, emoji string
+}
+
+func (f *fakeReactor) SetReaction(id, emoji string) error {
+	f.id, f.emoji = id, emoji
+	return nil
+}
+
+func TestParseReactions(t *testing.T) {
+	for _, input := range []string{"", "false"} {
+		if rules, err := parseReactions(input); err != nil || rules != nil {
+			t.Errorf("parseReactions(%q) = %v, %v, want disabled", input, rules, err)
+		}
+	}
+	rules, err := parseReactions("true")
+	if err != nil || rules["merged"] != "🎉" {
+		t.Errorf("parseReactions(\"true\") = %v, %v, want defaults", rules, err)
+	}
+	rules, err = parseReactions("pull_request_review.commented=👀, workflow_run=")
+	if err != nil {
+		t.Fatalf("parseReactions() error: %v", err)
+	}
+	if rules["pull_request_review.commented"] != "👀" || rules["merged"] != "" {
+		t.Errorf("parseReactions() = %v, want only the given rules", rules)
+	}
+}
+
+func TestReact(t *testing.T) {
+	store, err := state.Open(filepath.Join(t.TempDir(), "state.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.SetMessage(announcementKey(prEvent("opened")), "10")
+
+	review := prEvent("approved")
+	review.EventName = "pull_request_review"
+	merged := prEvent("closed")
+	merged.PR.Merged = true
+	ci := prEvent("completed")
+	ci.EventName = "workflow_run"
+	ci.CI = events.CIRun{Kind: "workflow_run", Conclusion: "failure"}
+	other := prEvent("approved")
+	other.EventName = "pull_request_review"
+	other.PR.Number = 8
+
+	tests := []struct {
+		name      string
+		data      *events.TemplateData
+		wantEmoji string
+		want      bool
+	}{
+		{"approved", review, "👍", true},
+		{"merged", merged, "🎉", true},
+		{"ci failure", ci, "🔥", true},
+		{"announcement itself", prEvent("opened"), "", false},
+		{"no mapping", prEvent("synchronize"), "", false},
+		{"unknown announcement", other, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &fakeReactor{}
+			got, err := react(r, store, defaultReactions, tt.data)
+			if err != nil {
+				t.Fatalf("react() error: %v", err)
+			}
+			if got != tt.want || r.emoji != tt.wantEmoji {
+				t.Errorf("react() = %v with %q, want %v with %q", got, r.emoji, tt.want, tt.wantEmoji)
+			}
+			if got && r.id != "10" {
+				t.Errorf("reacted to %q, want the announcement 10", r.id)
+			}
+		})
+	}
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/andoniaf/telegram-pr-notify/pkg/events"
+	"github.com/andoniaf/telegram-pr-notify/pkg/notify"
+	"github.com/andoniaf/telegram-pr-notify/pkg/state"
+	"github.com/andoniaf/telegram-pr-notify/pkg/telegram"
+	"github.com/andoniaf/telegram-pr-notify/pkg/templates"
+)
+
+// auto_topic modes.
+const (
+	autoTopicRepo = "repo"
+	autoTopicPR   = "pr"
+)
+
+const (
+	defaultRepoTopicName = "{{.Repo.FullName}}"
+	defaultPRTopicName   = "#{{.PR.Number}} {{.PR.Title}}"
+)
+
+// topicManager is implemented by notifiers that can manage forum topics.
+type topicManager interface {
+	CreateForumTopic(name string, iconColor int) (string, error)
+	CloseForumTopic(topicID string) error
+	ReopenForumTopic(topicID string) error
+}
+
+// autoTopic creates a forum topic per repository or per PR and routes
+// events into it.
+type autoTopic struct {
+	mode string
+
+	// minLines is the smallest PR, in changed lines, that gets its own
+	// topic in pr mode.
+	minLines int
+
+	// name and color are templates for new topics.
+	name, color string
+}
+
+func parseAutoTopic(mode, minLines, name, color string) (autoTopic, error) {
+	a := autoTopic{mode: strings.ToLower(strings.TrimSpace(mode)), name: name, color: color}
+	switch a.mode {
+	case "", "off", "false":
+		a.mode = ""
+		return a, nil
+	case autoTopicRepo:
+		if a.name == "" {
+			a.name = defaultRepoTopicName
+		}
+	case autoTopicPR:
+		if a.name == "" {
+			a.name = defaultPRTopicName
+		}
+	default:
+		return autoTopic{}, fmt.Errorf("invalid auto_topic %q (want repo or pr)", mode)
+	}
+	if minLines != "" {
+		n, err := strconv.Atoi(minLines)
+		if err != nil || n < 0 {
+			return autoTopic{}, fmt.Errorf("auto_topic_min_lines must be a non-negative integer")
+		}
+		a.minLines = n
+	}
+	return a, nil
+}
+
+func (a autoTopic) enabled() bool {
+	return a.mode != ""
+}
+
+// key identifies the event's topic in the state file.
+func (a autoTopic) key(data *events.TemplateData) string {
+	if a.mode == autoTopicPR {
+		return fmt.Sprintf("pr:%s#%d", data.Repo.FullName, data.PR.Number)
+	}
+	return "repo:" + data.Repo.FullName
+}
+
+// resolve returns the topic for the event, creating it on first use and
+// reopening a PR topic when the PR is reopened. It returns "" for events
+// that stay in the default topic: events without a PR in pr mode, and PRs
+// below minLines.
+func (a autoTopic) resolve(m topicManager, store *state.Store, data *events.TemplateData) (string, error) {
+	if a.mode == autoTopicPR && data.PR.Number == 0 {
+		return "", nil
+	}
+	key := a.key(data)
+	if id := store.Topic(key); id != "" {
+		if a.mode == autoTopicPR && data.EventName == "pull_request" && data.Action == "reopened" {
+			if err := m.ReopenForumTopic(id); err != nil {
+				return "", fmt.Errorf("reopening topic %s: %w", id, err)
+			}
+		}
+		return id, nil
+	}
+	if a.mode == autoTopicPR {
+		if data.EventName == "pull_request" && data.Action == "closed" {
+			return "", nil
+		}
+		if data.PR.Additions+data.PR.Deletions < a.minLines {
+			return "", nil
+		}
+	}
+
+	name, err := templates.Text(data, a.name)
+	if err != nil {
+		return "", fmt.Errorf("rendering auto_topic_name: %w", err)
+	}
+	if name == "" {
+		return "", errors.New("auto_topic_name rendered empty")
+	}
+	color, err := a.iconColor(data)
+	if err != nil {
+		return "", err
+	}
+	id, err := m.CreateForumTopic(name, color)
+	if err != nil {
+		return "", fmt.Errorf("creating topic %q: %w", name, err)
+	}
+	store.SetTopic(key, id)
+	return id, nil
+}
+
+func (a autoTopic) iconColor(data *events.TemplateData) (int, error) {
+	if a.color == "" {
+		return 0, nil
+	}
+	name, err := templates.Text(data, a.color)
+	if err != nil {
+		return 0, fmt.Errorf("rendering auto_topic_color: %w", err)
+	}
+	if name == "" {
+		return 0, nil
+	}
+	color, ok := telegram.TopicColors[strings.ToLower(name)]
+	if !ok {
+		return 0, fmt.Errorf("invalid topic color %q (want blue, yellow, violet, green, rose or red)", name)
+	}
+	return color, nil
+}
+
+// finish closes a PR's topic once the PR is merged or closed.
+func (a autoTopic) finish(m topicManager, store *state.Store, data *events.TemplateData) error {
+	if a.mode != autoTopicPR || data.EventName != "pull_request" || data.Action != "closed" {
+		return nil
+	}
+	id := store.Topic(a.key(data))
+	if id == "" {
+		return nil
+	}
+	if err := m.CloseForumTopic(id); err != nil {
+		return fmt.Errorf("closing topic %s: %w", id, err)
+	}
+	return nil
+}
+
+// routeAutoTopic resolves the event's automatic topic, recording new topics
+// in stateFile.
+func routeAutoTopic(notifier notify.Notifier, stateFile string, a autoTopic, data *events.TemplateData) (string, error) {
+	m, ok := notifier.(topicManager)
+	if !ok {
+		return "", errors.New("the notifier does not support forum topics")
+	}
+	store, err := state.Open(stateFile)
+	if err != nil {
+		return "", err
+	}
+	id, err := a.resolve(m, store, data)
+	if err != nil {
+		return "", err
+	}
+	return id, store.Save()
+}
+
+// closeAutoTopic closes the PR topic after the closing notification.
+func closeAutoTopic(notifier notify.Notifier, stateFile string, a autoTopic, data *events.TemplateData) error {
+	m, ok := notifier.(topicManager)
+	if !ok {
+		return nil
+	}
+	store, err := state.Open(stateFile)
+	if err != nil {
+		return err
+	}
+	return a.finish(m, store, data)
+}
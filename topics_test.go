@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/andoniaf/telegram-pr-notify/pkg/events"
+	"github.com/andoniaf/telegram-pr-notify/pkg/state"
+)
+
+type fakeTopics struct {
+	calls []string
+	next  int
+}
+
+func (f *fakeTopics) CreateForumTopic(name string, iconColor int) (string, error) {
+	f.next++
+	f.calls = append(f.calls, fmt.Sprintf("create:%s:%x", name, iconColor))
+	return fmt.Sprint(100 + f.next), nil
+}
+
+func (f *fakeTopics) CloseForumTopic(topicID string) error {
+	f.calls = append(f.calls, "close:"+topicID)
+	return nil
+}
+
+func (f *fakeTopics) ReopenForumTopic(topicID string) error {
+	f.calls = append(f.calls, "reopen:"+topicID)
+	return nil
+}
+
+func TestAutoTopicPR(t *testing.T) {
+	store, err := state.Open(filepath.Join(t.TempDir(), "state.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	topics, err := parseAutoTopic("pr", "50", "", `{{if .PR.Draft}}yellow{{else}}blue{{end}}`)
+	if err != nil {
+		t.Fatalf("parseAutoTopic() error: %v", err)
+	}
+	m := &fakeTopics{}
+
+	opened := prEvent("opened")
+	opened.PR.Title = "Fix <parser> & lexer"
+	opened.PR.Additions, opened.PR.Deletions = 40, 20
+	small := prEvent("opened")
+	small.PR.Number = 8
+	small.PR.Additions = 3
+	review := prEvent("approved")
+	review.EventName = "pull_request_review"
+
+	steps := []struct {
+		data *events.TemplateData
+		want string
+	}{
+		{opened, "101"},
+		{small, ""},
+		{review, "101"},
+		{prEvent("closed"), "101"},
+		{prEvent("reopened"), "101"},
+	}
+	for _, s := range steps {
+		got, err := topics.resolve(m, store, s.data)
+		if err != nil {
+			t.Fatalf("resolve(%s) error: %v", s.data.Action, err)
+		}
+		if got != s.want {
+			t.Errorf("resolve(%s #%d) = %q, want %q", s.data.Action, s.data.PR.Number, got, s.want)
+		}
+		if err := topics.finish(m, store, s.data); err != nil {
+			t.Fatalf("finish(%s) error: %v", s.data.Action, err)
+		}
+	}
+
+	want := "create:#7 Fix <parser> & lexer:6fb9f0,close:101,reopen:101"
+	if got := strings.Join(m.calls, ","); got != want {
+		t.Errorf("calls = %s, want %s", got, want)
+	}
+}
+
+func TestAutoTopicRepo(t *testing.T) {
+	store, err := state.Open(filepath.Join(t.TempDir(), "state.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	topics, err := parseAutoTopic("repo", "", "", "")
+	if err != nil {
+		t.Fatalf("parseAutoTopic() error: %v", err)
+	}
+	m := &fakeTopics{}
+
+	push := &events.TemplateData{EventName: "push", Repo: events.Repository{FullName: "octocat/Hello-World"}}
+	for _, data := range []*events.TemplateData{push, prEvent("opened"), prEvent("closed")} {
+		got, err := topics.resolve(m, store, data)
+		if err != nil || got != "101" {
+			t.Errorf("resolve(%s) = %q, %v, want 101", data.EventName, got, err)
+		}
+		if err := topics.finish(m, store, data); err != nil {
+			t.Fatalf("finish() error: %v", err)
+		}
+	}
+	if got := strings.Join(m.calls, ","); got != "create:octocat/Hello-World:0" {
+		t.Errorf("calls = %s, want a single repository topic", got)
+	}
+}
+
+func TestParseAutoTopic(t *testing.T) {
+	if a, err := parseAutoTopic("", "", "", ""); err != nil || a.enabled() {
+		t.Errorf("parseAutoTopic(\"\") = %+v, %v, want disabled", a, err)
+	}
+	for _, tt := range [][2]string{{"channel", ""}, {"pr", "-1"}, {"pr", "many"}} {
+		if _, err := parseAutoTopic(tt[0], tt[1], "", ""); err == nil {
+			t.Errorf("parseAutoTopic(%q, %q) expected error", tt[0], tt[1])
+		}
+	}
+
+	a, _ := parseAutoTopic("repo", "", "", "purple")
+	if _, err := a.iconColor(prEvent("opened")); err == nil {
+		t.Error("iconColor() accepted an unknown color")
+	}
+}